@@ -0,0 +1,107 @@
+// Command aieval checks which tool calls the AI assistant's model makes for a suite
+// of questions and reports its accuracy. It runs against the configured model, or
+// offline against a golden file:
+//
+//	aieval -suite suite.json -record golden.json   # live model, saving each exchange
+//	aieval -suite suite.json -replay golden.json   # recorded responses only
+//
+// A replay fails when any recorded exchange was made with a different system prompt,
+// tool schema or tool config than the current code sends, since its answers no
+// longer say how the model behaves; record again to measure the new model output.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/GregMSThompson/finance-backend/internal/bootstrap"
+	replayclient "github.com/GregMSThompson/finance-backend/internal/client/replay"
+	"github.com/GregMSThompson/finance-backend/internal/config"
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/retry"
+	"github.com/GregMSThompson/finance-backend/internal/services"
+	"github.com/GregMSThompson/finance-backend/pkg/logger"
+)
+
+func exitOnError(message string, err error, log *slog.Logger) {
+	if err != nil {
+		log.Error(message, "error", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	suitePath := flag.String("suite", "", "JSON file of eval cases")
+	recordPath := flag.String("record", "", "write model exchanges to this golden file")
+	replayPath := flag.String("replay", "", "serve model responses from this golden file instead of calling the model")
+	minAccuracy := flag.Float64("min", 0, "exit non-zero when accuracy falls below this fraction")
+	flag.Parse()
+
+	cfg := config.New()
+	log := logger.New(cfg.LogLevel, logger.NewCloudRunHandler)
+	ctx := logger.ToContext(context.Background(), log)
+
+	if *suitePath == "" {
+		exitOnError("invalid flags", fmt.Errorf("-suite is required"), log)
+	}
+	if *recordPath != "" && *replayPath != "" {
+		exitOnError("invalid flags", fmt.Errorf("-record and -replay are mutually exclusive"), log)
+	}
+	suite, err := loadSuite(*suitePath)
+	exitOnError("failed to load suite", err, log)
+
+	// model
+	var llm bootstrap.LLMClient
+	var replay *replayclient.Client
+	if *replayPath != "" {
+		replay, err = replayclient.Load(*replayPath)
+		exitOnError("failed to load golden file", err, log)
+		llm = replay
+	} else {
+		llm, err = bootstrap.NewLLM(ctx, cfg, log, retry.Default())
+		exitOnError("failed to create model client", err, log)
+		if *recordPath != "" {
+			llm = replayclient.NewRecorder(llm, *recordPath)
+		}
+	}
+
+	// services
+	evserv := services.NewAIEvalService(llm)
+
+	report, err := evserv.RunSuite(ctx, suite)
+	exitOnError("ai eval failed", err, log)
+
+	for _, r := range report.Results {
+		if r.Passed {
+			fmt.Printf("PASS  %s\n", r.Name)
+		} else {
+			fmt.Printf("FAIL  %s: %s\n", r.Name, r.Reason)
+		}
+	}
+	fmt.Printf("accuracy %d/%d (%.1f%%)\n", report.Passed, report.Total, report.Accuracy*100)
+	failed := report.Accuracy < *minAccuracy
+	if replay != nil && replay.Stale() > 0 {
+		fmt.Printf("stale: %d replayed responses were recorded with a different prompt or tool schema; record the golden file again\n", replay.Stale())
+		failed = true
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func loadSuite(path string) (dto.AIEvalSuite, error) {
+	var suite dto.AIEvalSuite
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return suite, fmt.Errorf("read suite: %w", err)
+	}
+	if err := json.Unmarshal(raw, &suite); err != nil {
+		return suite, fmt.Errorf("parse suite %s: %w", path, err)
+	}
+	return suite, nil
+}
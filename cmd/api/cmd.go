@@ -37,13 +37,24 @@ func main() {
 	tstore := store.NewTransactionStore(bs.Firestore)
 	bstore := store.NewBankStore(bs.Firestore, kmsHelper)
 	astore := store.NewAIStore(bs.Firestore)
+	acstore := store.NewAccountStore(bs.Firestore)
+	srstore := store.NewSyncRunStore(bs.Firestore)
+	bgstore := store.NewBudgetStore(bs.Firestore)
+	alstore := store.NewAlertStore(bs.Firestore)
+	dvstore := store.NewDeviceStore(bs.Firestore)
 
 	// services
-	userv := services.NewUserService(ustore)
-	bserv := services.NewBankService(bstore, tstore)
-	plserv := services.NewPlaidService(bs.PlaidAdapter, bstore, tstore)
-	anserv := services.NewAnalyticsService(tstore)
-	aiserv := services.NewAIService(bs.VertexAdapter, anserv, astore, cfg.AITTL)
+	userv := services.NewUserService(ustore, bstore, bs.PlaidAdapter)
+	bserv := services.NewBankService(bstore, tstore, acstore, srstore)
+	ntserv := services.NewNotificationService(dvstore, bs.FCMAdapter)
+	alserv := services.NewAlertService(alstore, tstore, ntserv)
+	plserv := services.NewPlaidService(bs.PlaidAdapter, bstore, tstore, acstore, srstore, alserv, ntserv)
+	acserv := services.NewAccountService(acstore)
+	txserv := services.NewTransactionService(tstore)
+	anserv := services.NewAnalyticsService(tstore, bs.FXProvider)
+	bgserv := services.NewBudgetService(bgstore, anserv)
+	aiserv := services.NewAIService(bs.LLM, anserv, astore, cfg.AITTL)
+	whserv := services.NewWebhookService(bs.PlaidAdapter, bstore, plserv, ntserv)
 
 	// response handler
 	rh := response.New(bs.Log)
@@ -55,8 +66,15 @@ func main() {
 	deps.Firebase = bs.Firebase
 	deps.UserSvc = userv
 	deps.BankSvc = bserv
+	deps.AccountSvc = acserv
+	deps.TransactionSvc = txserv
+	deps.AnalyticsSvc = anserv
+	deps.BudgetSvc = bgserv
+	deps.AlertSvc = alserv
+	deps.NotificationSvc = ntserv
 	deps.PlaidSvc = plserv
 	deps.AISvc = aiserv
+	deps.WebhookSvc = whserv
 
 	// router
 	r := router.NewRouter(deps)
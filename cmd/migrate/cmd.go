@@ -0,0 +1,41 @@
+// Command migrate applies one-off Firestore data migrations. It currently converts
+// transaction amounts and account balances written as floats to integer minor units,
+// and is safe to re-run.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/GregMSThompson/finance-backend/internal/bootstrap"
+	"github.com/GregMSThompson/finance-backend/internal/config"
+	"github.com/GregMSThompson/finance-backend/internal/store"
+)
+
+func exitOnError(message string, err error, log *slog.Logger) {
+	if err != nil {
+		log.Error(message, "error", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	// bootstrap
+	cfg := config.New()
+	bs, err := bootstrap.Run(cfg)
+	exitOnError("bootstrap failed", err, bs.Log)
+	defer bs.Close()
+
+	// stores
+	tstore := store.NewTransactionStore(bs.Firestore)
+	astore := store.NewAccountStore(bs.Firestore)
+
+	migrated, err := tstore.MigrateLegacyAmounts(context.Background())
+	exitOnError("transaction amount migration failed", err, bs.Log)
+	bs.Log.Info("transaction amount migration complete", "transactions_migrated", migrated)
+
+	migrated, err = astore.MigrateLegacyBalances(context.Background())
+	exitOnError("account balance migration failed", err, bs.Log)
+	bs.Log.Info("account balance migration complete", "accounts_migrated", migrated)
+}
@@ -0,0 +1,41 @@
+// Command sweeper deletes expired AI messages and the sessions they leave empty. It
+// backs up Firestore's TTL policy and stands in for it where TTL isn't available,
+// such as the emulator. Run it on a schedule; each run is a single pass.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/GregMSThompson/finance-backend/internal/bootstrap"
+	"github.com/GregMSThompson/finance-backend/internal/config"
+	"github.com/GregMSThompson/finance-backend/internal/services"
+	"github.com/GregMSThompson/finance-backend/internal/store"
+	"github.com/GregMSThompson/finance-backend/pkg/logger"
+)
+
+func exitOnError(message string, err error, log *slog.Logger) {
+	if err != nil {
+		log.Error(message, "error", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	// bootstrap
+	cfg := config.New()
+	bs, err := bootstrap.Run(cfg)
+	exitOnError("bootstrap failed", err, bs.Log)
+	defer bs.Close()
+
+	// stores
+	astore := store.NewAIStore(bs.Firestore)
+
+	// services
+	swserv := services.NewAISweeperService(astore)
+
+	ctx := logger.ToContext(context.Background(), bs.Log)
+	_, err = swserv.SweepExpired(ctx)
+	exitOnError("ai sweep failed", err, bs.Log)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/GregMSThompson/finance-backend/internal/bootstrap"
+	"github.com/GregMSThompson/finance-backend/internal/config"
+	"github.com/GregMSThompson/finance-backend/internal/crypto"
+	"github.com/GregMSThompson/finance-backend/internal/services"
+	"github.com/GregMSThompson/finance-backend/internal/store"
+	"github.com/GregMSThompson/finance-backend/pkg/logger"
+)
+
+func exitOnError(message string, err error, log *slog.Logger) {
+	if err != nil {
+		log.Error(message, "error", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	// bootstrap
+	cfg := config.New()
+	bs, err := bootstrap.Run(cfg)
+	exitOnError("bootstrap failed", err, bs.Log)
+	defer bs.Close()
+
+	// helpers
+	kmsHelper := crypto.NewKMS(bs.KMS, cfg.KMSKeyName)
+
+	// stores
+	tstore := store.NewTransactionStore(bs.Firestore)
+	bstore := store.NewBankStore(bs.Firestore, kmsHelper)
+	acstore := store.NewAccountStore(bs.Firestore)
+	srstore := store.NewSyncRunStore(bs.Firestore)
+	alstore := store.NewAlertStore(bs.Firestore)
+	dvstore := store.NewDeviceStore(bs.Firestore)
+
+	// services
+	ntserv := services.NewNotificationService(dvstore, bs.FCMAdapter)
+	alserv := services.NewAlertService(alstore, tstore, ntserv)
+	plserv := services.NewPlaidService(bs.PlaidAdapter, bstore, tstore, acstore, srstore, alserv, ntserv)
+	scserv := services.NewSchedulerService(bstore, plserv, cfg.SyncConcurrency)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = logger.ToContext(ctx, bs.Log)
+
+	// A zero interval runs a single pass, suited to a scheduled Cloud Run job.
+	if cfg.SyncInterval <= 0 {
+		_, err = scserv.SyncAll(ctx)
+		exitOnError("scheduled sync failed", err, bs.Log)
+		return
+	}
+	if err := scserv.Run(ctx, cfg.SyncInterval); err != nil && ctx.Err() == nil {
+		exitOnError("scheduler stopped", err, bs.Log)
+	}
+}
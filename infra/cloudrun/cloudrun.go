@@ -150,6 +150,7 @@ func createCloudRunService(ctx *pulumi.Context,
 	logLevel := crCfg.Require("logLevel")
 	timeout, _ := strconv.Atoi(crCfg.Require("timeout"))
 	plaidEnv := plaidCfg.Require("environment")
+	plaidWebhookURL := plaidCfg.Get("webhookUrl")
 	vertexModel := vertexCfg.Require("model")
 	aiTTL := appCfg.Require("aiTtl")
 
@@ -173,8 +174,9 @@ func createCloudRunService(ctx *pulumi.Context,
 					"run.googleapis.com/cpu":    pulumi.String(cpu),
 					"run.googleapis.com/memory": pulumi.String(memory),
 
-					// Allow throttling when idle (reduces cost)
-					"run.googleapis.com/cpu-throttling": pulumi.String("true"),
+					// Keep CPU allocated after a response is sent: Plaid webhooks are
+					// answered first and their transaction sync finishes in the background.
+					"run.googleapis.com/cpu-throttling": pulumi.String("false"),
 
 					// Set the number of concurrent requests per container
 					"run.googleapis.com/container-concurrency": pulumi.String(concurrency),
@@ -214,6 +216,10 @@ func createCloudRunService(ctx *pulumi.Context,
 								Name:  pulumi.String("PLAIDENVIRONMENT"),
 								Value: pulumi.String(plaidEnv),
 							},
+							&cloudrun.ServiceTemplateSpecContainerEnvArgs{
+								Name:  pulumi.String("PLAIDWEBHOOKURL"),
+								Value: pulumi.String(plaidWebhookURL),
+							},
 							&cloudrun.ServiceTemplateSpecContainerEnvArgs{
 								Name:  pulumi.String("VERTEXMODEL"),
 								Value: pulumi.String(vertexModel),
@@ -55,6 +55,109 @@ func setupIndexes(ctx *pulumi.Context, prov *gcp.Provider, db *firestore.Databas
 	if err := setupTransactionIndexes(ctx, prov, db, res...); err != nil {
 		return err
 	}
+	if err := setupBankIndexes(ctx, prov, db, res...); err != nil {
+		return err
+	}
+	if err := setupAlertIndexes(ctx, prov, db, res...); err != nil {
+		return err
+	}
+	if err := setupAITTL(ctx, prov, db, res...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// setupBankIndexes enables a collection-group index on bankId so Plaid webhooks
+// can resolve an item_id back to its owning user.
+func setupBankIndexes(ctx *pulumi.Context, prov *gcp.Provider, db *firestore.Database, res ...pulumi.Resource) error {
+	gcpCfg := config.New(ctx, "gcp")
+	projectID := gcpCfg.Require("project")
+
+	_, err := firestore.NewField(ctx, "banksBankIdField", &firestore.FieldArgs{
+		Project:    pulumi.String(projectID),
+		Database:   db.Name,
+		Collection: pulumi.String("banks"),
+		Field:      pulumi.String("bankId"),
+		IndexConfig: &firestore.FieldIndexConfigArgs{
+			Indexes: firestore.FieldIndexConfigIndexArray{
+				&firestore.FieldIndexConfigIndexArgs{
+					Order:      pulumi.String("ASCENDING"),
+					QueryScope: pulumi.String("COLLECTION"),
+				},
+				&firestore.FieldIndexConfigIndexArgs{
+					Order:      pulumi.String("ASCENDING"),
+					QueryScope: pulumi.String("COLLECTION_GROUP"),
+				},
+			},
+		},
+	},
+		pulumi.Provider(prov),
+		pulumi.DependsOn(res),
+	)
+	return err
+}
+
+// setupAlertIndexes backs the unacknowledged-only alert listing, newest first.
+func setupAlertIndexes(ctx *pulumi.Context, prov *gcp.Provider, db *firestore.Database, res ...pulumi.Resource) error {
+	gcpCfg := config.New(ctx, "gcp")
+	projectID := gcpCfg.Require("project")
+
+	_, err := firestore.NewIndex(ctx, "alertAcknowledgedCreatedAtDesc", &firestore.IndexArgs{
+		Project:    pulumi.String(projectID),
+		Database:   db.Name,
+		Collection: pulumi.String("alerts"),
+		QueryScope: pulumi.String("COLLECTION"),
+		Fields:     indexFieldsWithNameOrder("DESCENDING", "acknowledged", "ASCENDING", "createdAt", "DESCENDING"),
+	},
+		pulumi.Provider(prov),
+		pulumi.DependsOn(res),
+	)
+	return err
+}
+
+// setupAITTL turns on Firestore TTL for AI messages and sessions, which carry an
+// expiresAt stamped from AITTL. The collection-group index backs the sweeper's
+// expiry query in environments without native TTL.
+func setupAITTL(ctx *pulumi.Context, prov *gcp.Provider, db *firestore.Database, res ...pulumi.Resource) error {
+	gcpCfg := config.New(ctx, "gcp")
+	projectID := gcpCfg.Require("project")
+
+	fields := []struct {
+		name       string
+		collection string
+	}{
+		{name: "aiMessagesExpiresAtField", collection: "messages"},
+		{name: "aiSessionsExpiresAtField", collection: "ai_sessions"},
+	}
+
+	for _, f := range fields {
+		_, err := firestore.NewField(ctx, f.name, &firestore.FieldArgs{
+			Project:    pulumi.String(projectID),
+			Database:   db.Name,
+			Collection: pulumi.String(f.collection),
+			Field:      pulumi.String("expiresAt"),
+			TtlConfig:  &firestore.FieldTtlConfigArgs{},
+			IndexConfig: &firestore.FieldIndexConfigArgs{
+				Indexes: firestore.FieldIndexConfigIndexArray{
+					&firestore.FieldIndexConfigIndexArgs{
+						Order:      pulumi.String("ASCENDING"),
+						QueryScope: pulumi.String("COLLECTION"),
+					},
+					&firestore.FieldIndexConfigIndexArgs{
+						Order:      pulumi.String("ASCENDING"),
+						QueryScope: pulumi.String("COLLECTION_GROUP"),
+					},
+				},
+			},
+		},
+			pulumi.Provider(prov),
+			pulumi.DependsOn(res),
+		)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -82,6 +185,8 @@ func setupTransactionIndexes(ctx *pulumi.Context, prov *gcp.Provider, db *firest
 		{name: "txPendingBankIdDateAsc", fields: indexFields("pending", "ASCENDING", "bankId", "ASCENDING", "date", "ASCENDING")},
 		{name: "txPendingBankIdDateDesc", fields: indexFields("pending", "ASCENDING", "bankId", "ASCENDING", "date", "DESCENDING")},
 		{name: "txPendingBankIdDateDescNameDesc", fields: indexFieldsWithNameOrder("DESCENDING", "pending", "ASCENDING", "bankId", "ASCENDING", "date", "DESCENDING")},
+		// merchant history for the unusually-large-transaction alert
+		{name: "txNameDateDescNameDesc", fields: indexFieldsWithNameOrder("DESCENDING", "name", "ASCENDING", "date", "DESCENDING")},
 	}
 
 	for _, idx := range indexes {
@@ -2,25 +2,41 @@ package bootstrap
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 
 	"cloud.google.com/go/firestore"
 	kms "cloud.google.com/go/kms/apiv1"
 	"firebase.google.com/go/v4/auth"
 
+	fcmclient "github.com/GregMSThompson/finance-backend/internal/client/fcm"
+	fxclient "github.com/GregMSThompson/finance-backend/internal/client/fx"
+	openaiclient "github.com/GregMSThompson/finance-backend/internal/client/openai"
 	plaidclient "github.com/GregMSThompson/finance-backend/internal/client/plaid"
 	vertexclient "github.com/GregMSThompson/finance-backend/internal/client/vertex"
 	"github.com/GregMSThompson/finance-backend/internal/config"
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/retry"
 	"github.com/GregMSThompson/finance-backend/pkg/logger"
 )
 
+// LLMClient is the model behind the AI assistant, either Vertex AI or an
+// OpenAI-compatible server depending on config.AIProvider.
+type LLMClient interface {
+	GenerateContent(ctx context.Context, req dto.VertexGenerateRequest) (dto.VertexGenerateResponse, error)
+	GenerateContentStream(ctx context.Context, req dto.VertexGenerateRequest, onText func(string) error) (dto.VertexGenerateResponse, error)
+}
+
 type Bootstrap struct {
 	Log           *slog.Logger
 	Firestore     *firestore.Client
 	Firebase      *auth.Client
 	KMS           *kms.KeyManagementClient
 	PlaidAdapter  *plaidclient.Adapter
-	VertexAdapter *vertexclient.Adapter
+	VertexAdapter *vertexclient.Adapter // nil unless the AI provider is vertex
+	LLM           LLMClient
+	FXProvider    *fxclient.StaticProvider
+	FCMAdapter    *fcmclient.Adapter
 }
 
 func Run(cfg *config.Config) (*Bootstrap, error) {
@@ -36,7 +52,11 @@ func Run(cfg *config.Config) (*Bootstrap, error) {
 	if err != nil {
 		return bs, err
 	}
-	bs.Firebase, err = InitFirebase(applicationCtx)
+	firebaseApp, err := InitFirebase(applicationCtx)
+	if err != nil {
+		return bs, err
+	}
+	bs.Firebase, err = firebaseApp.Auth(applicationCtx)
 	if err != nil {
 		return bs, err
 	}
@@ -45,16 +65,49 @@ func Run(cfg *config.Config) (*Bootstrap, error) {
 		return bs, err
 	}
 
-	// Adapters wrap external APIs for the service layer.
-	bs.PlaidAdapter = plaidclient.NewAdapter(cfg.PlaidClientID, cfg.PlaidSecret, cfg.PlaidEnvironment)
-	bs.VertexAdapter, err = vertexclient.NewAdapter(applicationCtx, bs.Log, cfg.ProjectID, cfg.Region, cfg.VertexModel)
+	// Adapters wrap external APIs for the service layer and retry transient failures.
+	policy := retry.Default()
+	bs.PlaidAdapter = plaidclient.NewAdapter(cfg.PlaidClientID, cfg.PlaidSecret, cfg.PlaidEnvironment, cfg.PlaidWebhookURL, policy)
+	bs.LLM, err = NewLLM(applicationCtx, cfg, bs.Log, policy)
+	if err != nil {
+		return bs, err
+	}
+	bs.VertexAdapter, _ = bs.LLM.(*vertexclient.Adapter)
+	messagingClient, err := firebaseApp.Messaging(applicationCtx)
 	if err != nil {
 		return bs, err
 	}
+	bs.FCMAdapter = fcmclient.NewAdapter(messagingClient, policy)
+
+	// Exchange rates for base-currency analytics. Without a rates file only
+	// same-currency conversions succeed.
+	bs.FXProvider = fxclient.NewStaticProvider("", nil)
+	if cfg.FXRatesFile != "" {
+		bs.FXProvider, err = fxclient.LoadFile(cfg.FXRatesFile)
+		if err != nil {
+			return bs, err
+		}
+	}
 
 	return bs, nil
 }
 
+// NewLLM returns the model client for cfg.AIProvider: Vertex AI by default, or an
+// OpenAI-compatible server so the assistant can run locally in dev and CI.
+func NewLLM(ctx context.Context, cfg *config.Config, log *slog.Logger, policy *retry.Policy) (LLMClient, error) {
+	switch cfg.AIProvider {
+	case "", "vertex":
+		return vertexclient.NewAdapter(ctx, log, cfg.ProjectID, cfg.Region, cfg.VertexModel, policy)
+	case "openai":
+		if cfg.OpenAIBaseURL == "" {
+			return nil, fmt.Errorf("OPENAIBASEURL is required for the openai AI provider")
+		}
+		return openaiclient.NewAdapter(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, cfg.OpenAIModel, policy), nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider %q", cfg.AIProvider)
+	}
+}
+
 func (bs *Bootstrap) Close() {
 	if bs == nil {
 		return
@@ -4,13 +4,8 @@ import (
 	"context"
 
 	firebase "firebase.google.com/go/v4"
-	"firebase.google.com/go/v4/auth"
 )
 
-func InitFirebase(ctx context.Context) (*auth.Client, error) {
-	app, err := firebase.NewApp(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-	return app.Auth(ctx)
+func InitFirebase(ctx context.Context) (*firebase.App, error) {
+	return firebase.NewApp(ctx, nil)
 }
@@ -0,0 +1,71 @@
+package fcmclient
+
+import (
+	"context"
+
+	"firebase.google.com/go/v4/messaging"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/retry"
+)
+
+// FCM accepts at most this many tokens in one multicast request.
+const maxTokensPerRequest = 500
+
+type Adapter struct {
+	client *messaging.Client
+	retry  *retry.Policy
+}
+
+func NewAdapter(client *messaging.Client, policy *retry.Policy) *Adapter {
+	return &Adapter{client: client, retry: policy}
+}
+
+// Send delivers n to every token and returns the tokens FCM reported as no longer
+// valid, so callers can forget them. Other per-token failures are ignored; the
+// device will get the next notification.
+func (a *Adapter) Send(ctx context.Context, tokens []string, n dto.Notification) ([]string, error) {
+	var invalid []string
+	for start := 0; start < len(tokens); start += maxTokensPerRequest {
+		end := min(start+maxTokensPerRequest, len(tokens))
+		batch := tokens[start:end]
+
+		msg := &messaging.MulticastMessage{
+			Tokens: batch,
+			Notification: &messaging.Notification{
+				Title: n.Title,
+				Body:  n.Body,
+			},
+			Data: n.Data,
+		}
+
+		var resp *messaging.BatchResponse
+		err := a.retry.Do(ctx, "fcm.send", func(ctx context.Context) error {
+			var err error
+			resp, err = a.client.SendEachForMulticast(ctx, msg)
+			if err != nil {
+				return errs.NewExternalServiceError("fcm", "failed to send notification", IsTransientError(err), err)
+			}
+			return nil
+		})
+		if err != nil {
+			return invalid, err
+		}
+
+		for i, r := range resp.Responses {
+			if r.Success || r.Error == nil {
+				continue
+			}
+			if messaging.IsUnregistered(r.Error) || messaging.IsInvalidArgument(r.Error) || messaging.IsSenderIDMismatch(r.Error) {
+				invalid = append(invalid, batch[i])
+			}
+		}
+	}
+	return invalid, nil
+}
+
+// IsTransientError reports whether an FCM failure may succeed on retry.
+func IsTransientError(err error) bool {
+	return messaging.IsUnavailable(err) || messaging.IsInternal(err) || messaging.IsQuotaExceeded(err)
+}
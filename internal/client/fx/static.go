@@ -0,0 +1,73 @@
+package fxclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+)
+
+// StaticProvider serves fixed exchange rates quoted against a single base currency,
+// e.g. base USD with {"EUR": 0.92} meaning 1 USD buys 0.92 EUR.
+type StaticProvider struct {
+	base  string
+	rates map[string]float64
+}
+
+// ratesFile is the on-disk format read by LoadFile.
+type ratesFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func NewStaticProvider(base string, rates map[string]float64) *StaticProvider {
+	p := &StaticProvider{
+		base:  strings.ToUpper(base),
+		rates: make(map[string]float64, len(rates)+1),
+	}
+	for currency, rate := range rates {
+		p.rates[strings.ToUpper(currency)] = rate
+	}
+	if p.base != "" {
+		p.rates[p.base] = 1
+	}
+	return p
+}
+
+// LoadFile reads rates from a JSON file of the form {"base": "USD", "rates": {"EUR": 0.92}}.
+func LoadFile(path string) (*StaticProvider, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fx rates file: %w", err)
+	}
+	var f ratesFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("parse fx rates file: %w", err)
+	}
+	if f.Base == "" {
+		return nil, fmt.Errorf("fx rates file %s has no base currency", path)
+	}
+	for currency, rate := range f.Rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("fx rates file %s has a non-positive rate for %s", path, currency)
+		}
+	}
+	return NewStaticProvider(f.Base, f.Rates), nil
+}
+
+// Rate returns the multiplier that converts an amount in from into to.
+func (p *StaticProvider) Rate(ctx context.Context, from, to string) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, nil
+	}
+	fromRate, okFrom := p.rates[from]
+	toRate, okTo := p.rates[to]
+	if !okFrom || !okTo {
+		return 0, errs.NewMissingExchangeRateError(from, to)
+	}
+	return toRate / fromRate, nil
+}
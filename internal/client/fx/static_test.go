@@ -0,0 +1,60 @@
+package fxclient
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+func TestStaticProviderRate(t *testing.T) {
+	p := NewStaticProvider("USD", map[string]float64{"EUR": 0.8, "gbp": 0.5})
+	ctx := helpers.TestCtx()
+
+	cases := []struct {
+		from, to string
+		want     float64
+	}{
+		{"USD", "EUR", 0.8},
+		{"EUR", "USD", 1.25},
+		{"EUR", "GBP", 0.625},
+		{"gbp", "GBP", 1},
+	}
+	for _, tc := range cases {
+		got, err := p.Rate(ctx, tc.from, tc.to)
+		if err != nil {
+			t.Fatalf("%s->%s: unexpected error: %v", tc.from, tc.to, err)
+		}
+		if math.Abs(got-tc.want) > 1e-9 {
+			t.Fatalf("%s->%s = %v, want %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+
+	if _, err := p.Rate(ctx, "USD", "JPY"); err == nil {
+		t.Fatalf("expected error for unknown currency")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(`{"base":"EUR","rates":{"USD":1.1}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := p.Rate(helpers.TestCtx(), "EUR", "USD"); got != 1.1 {
+		t.Fatalf("EUR->USD = %v, want 1.1", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"rates":{"USD":1.1}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Fatalf("expected error for missing base currency")
+	}
+}
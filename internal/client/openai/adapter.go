@@ -0,0 +1,446 @@
+package openaiclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/retry"
+	"github.com/GregMSThompson/finance-backend/pkg/logger"
+)
+
+// Adapter talks to any server implementing the OpenAI chat completions API, such as
+// llama.cpp or Ollama, and serves the same requests as the Vertex adapter.
+type Adapter struct {
+	http    *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+	retry   *retry.Policy
+}
+
+// NewAdapter returns an adapter for the API rooted at baseURL, e.g.
+// "http://localhost:11434/v1". apiKey may be empty for local servers.
+func NewAdapter(baseURL, apiKey, model string, policy *retry.Policy) *Adapter {
+	return &Adapter{
+		http:    &http.Client{},
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		retry:   policy,
+	}
+}
+
+func (a *Adapter) GenerateContent(ctx context.Context, req dto.VertexGenerateRequest) (dto.VertexGenerateResponse, error) {
+	out := dto.VertexGenerateResponse{}
+
+	body, err := a.newChatRequest(ctx, req, false)
+	if err != nil {
+		return out, err
+	}
+
+	var resp chatResponse
+	err = a.retry.Do(ctx, "openai.chat_completion", func(ctx context.Context) error {
+		httpResp, err := a.post(ctx, body)
+		if err != nil {
+			return err
+		}
+		defer httpResp.Body.Close()
+
+		resp = chatResponse{}
+		if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+			return errs.NewExternalServiceError("openai", "failed to decode chat completion", false, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return out, err
+	}
+
+	out.Raw = resp
+	if len(resp.Choices) == 0 {
+		return out, fmt.Errorf("openai response contained no choices")
+	}
+	msg := resp.Choices[0].Message
+	out.Text = msg.Content
+	out.ToolCalls, err = parseToolCalls(msg.ToolCalls)
+	if err != nil {
+		return out, err
+	}
+
+	if logger.IsDebugEnabled(ctx) {
+		log := logger.FromContext(ctx)
+		log.Debug(
+			"openai chat completion response",
+			"finishReason", resp.Choices[0].FinishReason,
+			"toolCalls", len(out.ToolCalls),
+			"textLen", len(out.Text),
+		)
+	}
+
+	if len(out.Text) == 0 && len(out.ToolCalls) == 0 {
+		return out, fmt.Errorf("openai response contained no text or tool calls")
+	}
+	return out, nil
+}
+
+// GenerateContentStream is GenerateContent over a streamed completion. onText
+// receives each piece of answer text as it arrives; the returned response holds the
+// complete text and any tool calls. A failed stream is only retried if nothing was
+// passed to onText yet.
+func (a *Adapter) GenerateContentStream(ctx context.Context, req dto.VertexGenerateRequest, onText func(string) error) (dto.VertexGenerateResponse, error) {
+	out := dto.VertexGenerateResponse{}
+
+	body, err := a.newChatRequest(ctx, req, true)
+	if err != nil {
+		return out, err
+	}
+
+	var (
+		text  strings.Builder
+		calls []toolCall
+	)
+	err = a.retry.Do(ctx, "openai.stream_chat_completion", func(ctx context.Context) error {
+		text.Reset()
+		calls = nil
+
+		httpResp, err := a.post(ctx, body)
+		if err != nil {
+			return err
+		}
+		defer httpResp.Body.Close()
+
+		streamErr := readStream(httpResp.Body, func(chunk chatChunk) error {
+			if len(chunk.Choices) == 0 {
+				return nil
+			}
+			delta := chunk.Choices[0].Delta
+			calls = mergeToolCallDeltas(calls, delta.ToolCalls)
+			if delta.Content == "" {
+				return nil
+			}
+			text.WriteString(delta.Content)
+			return onText(delta.Content)
+		})
+		var extErr *errs.ExternalServiceError
+		if streamErr == nil || !errors.As(streamErr, &extErr) {
+			// Either done, or onText failed and the caller's error goes back as is.
+			return streamErr
+		}
+		if text.Len() > 0 {
+			// Text already reached the caller, so a retry would repeat it.
+			return errs.NewExternalServiceError("openai", fmt.Sprintf("content stream interrupted: %v", streamErr), false, nil)
+		}
+		return streamErr
+	})
+	if err != nil {
+		return out, err
+	}
+
+	out.Text = text.String()
+	out.ToolCalls, err = parseToolCalls(calls)
+	if err != nil {
+		return out, err
+	}
+	if logger.IsDebugEnabled(ctx) {
+		log := logger.FromContext(ctx)
+		log.Debug("openai stream chat completion response", "toolCalls", len(out.ToolCalls), "textLen", len(out.Text))
+	}
+
+	if len(out.Text) == 0 && len(out.ToolCalls) == 0 {
+		return out, fmt.Errorf("openai response contained no text or tool calls")
+	}
+	return out, nil
+}
+
+// newChatRequest converts req to a chat completions request body.
+func (a *Adapter) newChatRequest(ctx context.Context, req dto.VertexGenerateRequest, stream bool) ([]byte, error) {
+	model := req.Model
+	if model == "" {
+		model = a.model
+	}
+	if model == "" {
+		return nil, fmt.Errorf("openai model is required")
+	}
+	if len(req.Contents) == 0 {
+		return nil, fmt.Errorf("openai generate request has no content")
+	}
+
+	body := chatRequest{
+		Model:       model,
+		Messages:    toMessages(req.System, req.Contents),
+		Tools:       toTools(req.Tools),
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxOutputTokens,
+		Stream:      stream,
+	}
+	if len(body.Tools) > 0 && req.ToolConfig != nil {
+		body.ToolChoice = toToolChoice(req.ToolConfig.Mode)
+	}
+
+	if logger.IsDebugEnabled(ctx) {
+		log := logger.FromContext(ctx)
+		log.Debug(
+			"openai chat completion request",
+			"model", model,
+			"systemLen", len(req.System),
+			"messages", len(body.Messages),
+			"tools", len(body.Tools),
+			"stream", stream,
+		)
+	}
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("encode openai request: %w", err)
+	}
+	return raw, nil
+}
+
+// post sends a chat completions request and returns the response if it succeeded.
+// The caller closes the body.
+func (a *Adapter) post(ctx context.Context, body []byte) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build openai request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if a.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	resp, err := a.http.Do(httpReq)
+	if err != nil {
+		// Connection failures are worth retrying; a cancelled request is not.
+		return nil, errs.NewExternalServiceError("openai", "failed to call chat completions", ctx.Err() == nil, err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	extErr := errs.NewExternalServiceError("openai", fmt.Sprintf("chat completions returned %d: %s", resp.StatusCode, readErrorMessage(resp.Body)), IsTransientStatus(resp.StatusCode), nil)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		extErr.Code = "RATE_LIMIT_EXCEEDED"
+	}
+	return nil, extErr
+}
+
+// readErrorMessage pulls the message out of an error body, falling back to the raw
+// text for servers that don't use the OpenAI error shape.
+func readErrorMessage(r io.Reader) string {
+	raw, _ := io.ReadAll(io.LimitReader(r, 4096))
+	var body errorResponse
+	if err := json.Unmarshal(raw, &body); err == nil && body.Error.Message != "" {
+		return body.Error.Message
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// readStream calls handle for each chunk of a server-sent event stream until the
+// [DONE] marker.
+func readStream(r io.Reader, handle func(chatChunk) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return errs.NewExternalServiceError("openai", "failed to decode stream chunk", false, err)
+		}
+		if err := handle(chunk); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errs.NewExternalServiceError("openai", "failed to read stream", true, err)
+	}
+	return errs.NewExternalServiceError("openai", "stream ended without [DONE]", true, nil)
+}
+
+// mergeToolCallDeltas folds streamed tool call fragments into calls. Fragments
+// with the same index belong to one call; arguments arrive in pieces.
+func mergeToolCallDeltas(calls []toolCall, deltas []toolCall) []toolCall {
+	for _, d := range deltas {
+		i := len(calls)
+		if d.Index != nil {
+			i = *d.Index
+		}
+		for len(calls) <= i {
+			calls = append(calls, toolCall{Type: "function"})
+		}
+		if d.ID != "" {
+			calls[i].ID = d.ID
+		}
+		if d.Function.Name != "" {
+			calls[i].Function.Name = d.Function.Name
+		}
+		calls[i].Function.Arguments += d.Function.Arguments
+	}
+	return calls
+}
+
+// parseToolCalls decodes the JSON arguments of each call. Arguments that aren't a
+// JSON object are reported as a malformed function call, like Vertex does.
+func parseToolCalls(calls []toolCall) ([]dto.VertexToolCall, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	out := make([]dto.VertexToolCall, 0, len(calls))
+	for _, call := range calls {
+		if call.Function.Name == "" {
+			return nil, errs.NewMalformedFunctionCallError()
+		}
+		args := map[string]any{}
+		if raw := strings.TrimSpace(call.Function.Arguments); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &args); err != nil {
+				return nil, errs.NewMalformedFunctionCallError()
+			}
+		}
+		out = append(out, dto.VertexToolCall{Name: call.Function.Name, Args: args})
+	}
+	return out, nil
+}
+
+// toMessages flattens the system prompt and contents into chat messages. Vertex
+// matches function responses to calls by name, while chat completions needs ids,
+// so each call gets a generated id that the next response with its name reuses.
+func toMessages(system string, contents []dto.VertexContent) []chatMessage {
+	messages := make([]chatMessage, 0, len(contents)+1)
+	if system != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: system})
+	}
+
+	pending := map[string][]string{}
+	nextID := 0
+	newID := func() string {
+		nextID++
+		return fmt.Sprintf("call_%d", nextID)
+	}
+
+	for _, content := range contents {
+		var text strings.Builder
+		var calls []toolCall
+		for _, part := range content.Parts {
+			if part.Text != nil {
+				text.WriteString(*part.Text)
+			}
+			if part.FunctionCall != nil {
+				id := newID()
+				pending[part.FunctionCall.Name] = append(pending[part.FunctionCall.Name], id)
+				args, _ := json.Marshal(part.FunctionCall.Args)
+				calls = append(calls, toolCall{
+					ID:       id,
+					Type:     "function",
+					Function: functionCall{Name: part.FunctionCall.Name, Arguments: string(args)},
+				})
+			}
+			if part.FunctionResponse != nil {
+				id := newID()
+				if ids := pending[part.FunctionResponse.Name]; len(ids) > 0 {
+					id, pending[part.FunctionResponse.Name] = ids[0], ids[1:]
+				}
+				result, _ := json.Marshal(part.FunctionResponse.Response)
+				messages = append(messages, chatMessage{Role: "tool", Content: string(result), ToolCallID: id})
+			}
+		}
+
+		if text.Len() == 0 && len(calls) == 0 {
+			continue
+		}
+		messages = append(messages, chatMessage{
+			Role:      toRole(content.Role),
+			Content:   text.String(),
+			ToolCalls: calls,
+		})
+	}
+	return messages
+}
+
+func toRole(role string) string {
+	if role == "model" {
+		return "assistant"
+	}
+	return role
+}
+
+func toTools(tools []dto.VertexTool) []chatTool {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	out := make([]chatTool, 0, len(tools))
+	for _, tool := range tools {
+		out = append(out, chatTool{
+			Type: "function",
+			Function: functionDef{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  toSchema(tool.Parameters),
+			},
+		})
+	}
+	return out
+}
+
+func toSchema(schema *dto.VertexSchema) *jsonSchema {
+	if schema == nil {
+		return nil
+	}
+
+	out := &jsonSchema{
+		Type:        schema.Type,
+		Description: schema.Description,
+		Enum:        schema.Enum,
+		Required:    schema.Required,
+		Items:       toSchema(schema.Items),
+	}
+	if len(schema.Properties) > 0 {
+		out.Properties = make(map[string]*jsonSchema, len(schema.Properties))
+		for key, value := range schema.Properties {
+			out.Properties[key] = toSchema(value)
+		}
+	}
+	return out
+}
+
+func toToolChoice(mode dto.FunctionCallingMode) string {
+	switch mode {
+	case dto.FunctionCallingModeAny:
+		return "required"
+	case dto.FunctionCallingModeNone:
+		return "none"
+	default:
+		return "auto"
+	}
+}
+
+// IsTransientStatus reports whether an HTTP status from the API is worth retrying:
+// rate limiting, timeouts and server errors.
+func IsTransientStatus(code int) bool {
+	switch {
+	case code == http.StatusTooManyRequests,
+		code == http.StatusRequestTimeout,
+		code >= 500:
+		return true
+	default:
+		return false
+	}
+}
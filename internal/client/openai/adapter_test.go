@@ -0,0 +1,166 @@
+package openaiclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/retry"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+func newTestAdapter(t *testing.T, handler http.HandlerFunc) *Adapter {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewAdapter(srv.URL+"/v1/", "key-1", "local-model", retry.NewPolicy(2, time.Millisecond, time.Millisecond, nil))
+}
+
+func toolTurnRequest() dto.VertexGenerateRequest {
+	call := dto.VertexToolCall{Name: "get_spend_total", Args: map[string]any{"pending": false}}
+	result := dto.VertexToolResult{Name: "get_spend_total", Response: map[string]any{"total": 5}}
+	return dto.VertexGenerateRequest{
+		System: "be brief",
+		Contents: []dto.VertexContent{
+			{Role: "user", Parts: []dto.VertexPart{{Text: helpers.Ptr("How much did I spend?")}}},
+			{Role: "model", Parts: []dto.VertexPart{{FunctionCall: &call}}},
+			{Role: "user", Parts: []dto.VertexPart{{FunctionResponse: &result}}},
+		},
+		Tools: []dto.VertexTool{{
+			Name: "get_spend_total",
+			Parameters: &dto.VertexSchema{
+				Type:       "object",
+				Properties: map[string]*dto.VertexSchema{"pending": {Type: "boolean"}},
+			},
+		}},
+		ToolConfig: &dto.VertexToolConfig{Mode: dto.FunctionCallingModeAny},
+	}
+}
+
+func TestGenerateContentTranslatesToolTurns(t *testing.T) {
+	var got chatRequest
+	a := newTestAdapter(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer key-1" {
+			t.Errorf("unexpected request: %s %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"","tool_calls":[
+			{"id":"x","type":"function","function":{"name":"get_spend_breakdown","arguments":"{\"groupBy\":\"category\"}"}}]},
+			"finish_reason":"tool_calls"}]}`)
+	})
+
+	resp, err := a.GenerateContent(helpers.TestCtx(), toolTurnRequest())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Model != "local-model" || got.ToolChoice != "required" || got.Stream {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Function.Parameters.Properties["pending"].Type != "boolean" {
+		t.Fatalf("unexpected tools: %+v", got.Tools)
+	}
+	roles := make([]string, 0, len(got.Messages))
+	for _, m := range got.Messages {
+		roles = append(roles, m.Role)
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,tool" {
+		t.Fatalf("unexpected roles: %v", roles)
+	}
+	call, result := got.Messages[2].ToolCalls[0], got.Messages[3]
+	if call.ID == "" || call.ID != result.ToolCallID || call.Function.Arguments != `{"pending":false}` || result.Content != `{"total":5}` {
+		t.Fatalf("tool call and result not paired: %+v / %+v", call, result)
+	}
+
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "get_spend_breakdown" || resp.ToolCalls[0].Args["groupBy"] != "category" {
+		t.Fatalf("unexpected tool calls: %+v", resp.ToolCalls)
+	}
+}
+
+func TestGenerateContentMalformedArguments(t *testing.T) {
+	a := newTestAdapter(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices":[{"message":{"tool_calls":[{"id":"x","function":{"name":"get_spend_total","arguments":"{pending:"}}]}}]}`)
+	})
+
+	_, err := a.GenerateContent(helpers.TestCtx(), toolTurnRequest())
+	var malformed *errs.MalformedFunctionCallError
+	if !errors.As(err, &malformed) {
+		t.Fatalf("expected malformed function call error, got %v", err)
+	}
+}
+
+func TestGenerateContentRetriesRateLimit(t *testing.T) {
+	calls := 0
+	a := newTestAdapter(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"message":"slow down"}}`)
+	})
+
+	_, err := a.GenerateContent(helpers.TestCtx(), toolTurnRequest())
+	var extErr *errs.ExternalServiceError
+	if !errors.As(err, &extErr) || extErr.Code != "RATE_LIMIT_EXCEEDED" || !strings.Contains(extErr.Message, "slow down") {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected a retry, got %d calls", calls)
+	}
+}
+
+func TestGenerateContentStreamAssemblesChunks(t *testing.T) {
+	a := newTestAdapter(t, func(w http.ResponseWriter, r *http.Request) {
+		var body chatRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if !body.Stream {
+			t.Errorf("expected a stream request")
+		}
+		for _, chunk := range []string{
+			`{"choices":[{"delta":{"role":"assistant","content":"You "}}]}`,
+			`{"choices":[{"delta":{"content":"spent $5."}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"c1","function":{"name":"get_transactions","arguments":"{\"lim"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"it\":5}"}}]}}]}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+	})
+
+	var pieces []string
+	resp, err := a.GenerateContentStream(helpers.TestCtx(), toolTurnRequest(), func(text string) error {
+		pieces = append(pieces, text)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(pieces, "|") != "You |spent $5." || resp.Text != "You spent $5." {
+		t.Fatalf("unexpected text: %v / %q", pieces, resp.Text)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "get_transactions" || resp.ToolCalls[0].Args["limit"] != float64(5) {
+		t.Fatalf("unexpected tool calls: %+v", resp.ToolCalls)
+	}
+}
+
+func TestGenerateContentStreamInterruptedAfterTextIsNotRetried(t *testing.T) {
+	calls := 0
+	a := newTestAdapter(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"You \"}}]}\n\n")
+	})
+
+	_, err := a.GenerateContentStream(helpers.TestCtx(), toolTurnRequest(), func(string) error { return nil })
+	var extErr *errs.ExternalServiceError
+	if !errors.As(err, &extErr) || extErr.Transient {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected no retry once text was sent, got %d calls", calls)
+	}
+}
@@ -2,7 +2,12 @@ package plaidclient
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/plaid/plaid-go/v24/plaid"
@@ -10,48 +15,109 @@ import (
 	"github.com/GregMSThompson/finance-backend/internal/dto"
 	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/money"
+	"github.com/GregMSThompson/finance-backend/internal/retry"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
 )
 
 type Adapter struct {
-	client *plaid.APIClient
+	client     *plaid.APIClient
+	webhookURL string
+	retry      *retry.Policy
 }
 
-func NewAdapter(clientID, secret string, env dto.PlaidEnvironment) *Adapter {
+func NewAdapter(clientID, secret string, env dto.PlaidEnvironment, webhookURL string, policy *retry.Policy) *Adapter {
 	cfg := plaid.NewConfiguration()
 	cfg.AddDefaultHeader("PLAID-CLIENT-ID", clientID)
 	cfg.AddDefaultHeader("PLAID-SECRET", secret)
 	cfg.UseEnvironment(toPlaidEnv(env))
 
 	return &Adapter{
-		client: plaid.NewAPIClient(cfg),
+		client:     plaid.NewAPIClient(cfg),
+		webhookURL: webhookURL,
+		retry:      policy,
 	}
 }
 
+// call runs a Plaid request under the retry policy. Failures are wrapped with
+// plaidError so the policy can see the error code and transient flag.
+func (a *Adapter) call(ctx context.Context, op, message string, fn func(ctx context.Context) error) error {
+	return a.retry.Do(ctx, "plaid."+op, func(ctx context.Context) error {
+		if err := fn(ctx); err != nil {
+			return plaidError(message, err)
+		}
+		return nil
+	})
+}
+
 func (a *Adapter) CreateLinkToken(ctx context.Context, uid string) (string, error) {
+	req := a.newLinkTokenRequest(uid)
+	req.SetProducts([]plaid.Products{plaid.PRODUCTS_TRANSACTIONS})
+
+	var resp plaid.LinkTokenCreateResponse
+	err := a.call(ctx, "link_token_create", "failed to create link token", func(ctx context.Context) (err error) {
+		resp, _, err = a.client.PlaidApi.LinkTokenCreate(ctx).LinkTokenCreateRequest(*req).Execute()
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetLinkToken(), nil
+}
+
+// CreateUpdateLinkToken creates a Link token in update mode so the user can
+// repair an existing item (e.g. after ITEM_LOGIN_REQUIRED) without re-linking.
+func (a *Adapter) CreateUpdateLinkToken(ctx context.Context, uid, accessToken string) (string, error) {
+	req := a.newLinkTokenRequest(uid)
+	// Update mode is selected by passing the access token; products must be omitted.
+	req.SetAccessToken(accessToken)
+
+	var resp plaid.LinkTokenCreateResponse
+	err := a.call(ctx, "link_token_create", "failed to create update link token", func(ctx context.Context) (err error) {
+		resp, _, err = a.client.PlaidApi.LinkTokenCreate(ctx).LinkTokenCreateRequest(*req).Execute()
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetLinkToken(), nil
+}
+
+func (a *Adapter) newLinkTokenRequest(uid string) *plaid.LinkTokenCreateRequest {
 	req := plaid.NewLinkTokenCreateRequest(
 		"Finance App",
 		"en",
 		[]plaid.CountryCode{plaid.CountryCode("US")},
 		plaid.LinkTokenCreateRequestUser{ClientUserId: uid},
 	)
-	req.SetProducts([]plaid.Products{plaid.PRODUCTS_TRANSACTIONS})
-
-	resp, _, err := a.client.PlaidApi.LinkTokenCreate(ctx).LinkTokenCreateRequest(*req).Execute()
-	if err != nil {
-		return "", errs.NewExternalServiceError("plaid", "failed to create link token", IsTransientError(err), err)
+	if a.webhookURL != "" {
+		req.SetWebhook(a.webhookURL)
 	}
-	return resp.GetLinkToken(), nil
+	return req
 }
 
+// ExchangePublicToken is not retried: a public token can be exchanged only once, so
+// a retry after a lost response would fail and strand the item Plaid created.
 func (a *Adapter) ExchangePublicToken(ctx context.Context, publicToken string) (itemID, accessToken string, err error) {
 	req := plaid.NewItemPublicTokenExchangeRequest(publicToken)
 	resp, _, err := a.client.PlaidApi.ItemPublicTokenExchange(ctx).ItemPublicTokenExchangeRequest(*req).Execute()
 	if err != nil {
-		return "", "", errs.NewExternalServiceError("plaid", "failed to exchange public token", IsTransientError(err), err)
+		return "", "", plaidError("failed to exchange public token", err)
 	}
 	return resp.GetItemId(), resp.GetAccessToken(), nil
 }
 
+// RemoveItem invalidates the access token and ends Plaid billing for the item. It
+// is not retried: once the first attempt lands, a retry fails on the dead token and
+// would report a successful removal as an error.
+func (a *Adapter) RemoveItem(ctx context.Context, accessToken string) error {
+	req := plaid.NewItemRemoveRequest(accessToken)
+	if _, _, err := a.client.PlaidApi.ItemRemove(ctx).ItemRemoveRequest(*req).Execute(); err != nil {
+		return plaidError("failed to remove item", err)
+	}
+	return nil
+}
+
 func (a *Adapter) SyncTransactions(ctx context.Context, bankID string, accessToken string, cursor *string) (dto.PlaidSyncPage, error) {
 	req := plaid.NewTransactionsSyncRequest(accessToken)
 	if cursor != nil {
@@ -64,9 +130,13 @@ func (a *Adapter) SyncTransactions(ctx context.Context, bankID string, accessTok
 
 	var page dto.PlaidSyncPage
 
-	resp, _, err := a.client.PlaidApi.TransactionsSync(ctx).TransactionsSyncRequest(*req).Execute()
+	var resp plaid.TransactionsSyncResponse
+	err := a.call(ctx, "transactions_sync", "failed to sync transactions", func(ctx context.Context) (err error) {
+		resp, _, err = a.client.PlaidApi.TransactionsSync(ctx).TransactionsSyncRequest(*req).Execute()
+		return err
+	})
 	if err != nil {
-		return page, errs.NewExternalServiceError("plaid", "failed to sync transactions", IsTransientError(err), err)
+		return page, err
 	}
 
 	txs := make([]models.Transaction, 0, len(resp.GetAdded())+len(resp.GetModified()))
@@ -74,12 +144,14 @@ func (a *Adapter) SyncTransactions(ctx context.Context, bankID string, accessTok
 
 	convert := func(plaidTx plaid.Transaction) models.Transaction {
 		pfc := plaidTx.GetPersonalFinanceCategory()
+		currency := plaidTx.GetIsoCurrencyCode()
 		return models.Transaction{
 			TransactionID:  plaidTx.GetTransactionId(),
 			BankID:         bankID,
+			AccountID:      plaidTx.GetAccountId(),
 			Name:           plaidTx.GetName(),
-			Amount:         plaidTx.GetAmount(),
-			Currency:       plaidTx.GetIsoCurrencyCode(),
+			AmountMinor:    money.FromFloat(plaidTx.GetAmount(), currency).Minor,
+			Currency:       currency,
 			Pending:        plaidTx.GetPending(),
 			Date:           plaidTx.GetDate(),
 			AuthorizedDate: plaidTx.GetAuthorizedDate(),
@@ -100,13 +172,120 @@ func (a *Adapter) SyncTransactions(ctx context.Context, bankID string, accessTok
 		txs = append(txs, convert(t))
 	}
 
+	removed := make([]string, 0, len(resp.GetRemoved()))
+	for _, r := range resp.GetRemoved() {
+		if id := r.GetTransactionId(); id != "" {
+			removed = append(removed, id)
+		}
+	}
+
 	page.Transactions = txs
+	page.RemovedTransactionIDs = removed
 	page.Cursor = resp.GetNextCursor()
 	page.HasMore = resp.GetHasMore()
 
 	return page, nil
 }
 
+// GetAccounts returns the item's accounts with the balances Plaid has cached.
+func (a *Adapter) GetAccounts(ctx context.Context, bankID, accessToken string) ([]models.Account, error) {
+	req := plaid.NewAccountsGetRequest(accessToken)
+	var resp plaid.AccountsGetResponse
+	err := a.call(ctx, "accounts_get", "failed to get accounts", func(ctx context.Context) (err error) {
+		resp, _, err = a.client.PlaidApi.AccountsGet(ctx).AccountsGetRequest(*req).Execute()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toAccounts(bankID, resp.GetAccounts()), nil
+}
+
+// GetAccountBalances returns the item's accounts with real-time balances.
+func (a *Adapter) GetAccountBalances(ctx context.Context, bankID, accessToken string) ([]models.Account, error) {
+	req := plaid.NewAccountsBalanceGetRequest(accessToken)
+	var resp plaid.AccountsGetResponse
+	err := a.call(ctx, "accounts_balance_get", "failed to get account balances", func(ctx context.Context) (err error) {
+		resp, _, err = a.client.PlaidApi.AccountsBalanceGet(ctx).AccountsBalanceGetRequest(*req).Execute()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toAccounts(bankID, resp.GetAccounts()), nil
+}
+
+func toAccounts(bankID string, plaidAccounts []plaid.AccountBase) []models.Account {
+	accounts := make([]models.Account, 0, len(plaidAccounts))
+	now := time.Now()
+
+	for _, acct := range plaidAccounts {
+		balances := acct.GetBalances()
+		account := models.Account{
+			AccountID:    acct.GetAccountId(),
+			BankID:       bankID,
+			Name:         acct.GetName(),
+			OfficialName: acct.GetOfficialName(),
+			Mask:         acct.GetMask(),
+			Type:         string(acct.GetType()),
+			Subtype:      string(acct.GetSubtype()),
+			Currency:     balances.GetIsoCurrencyCode(),
+			UpdatedAt:    now,
+		}
+		if v, ok := balances.GetCurrentOk(); ok && v != nil {
+			account.CurrentBalanceMinor = helpers.Ptr(money.FromFloat(*v, account.Currency).Minor)
+		}
+		if v, ok := balances.GetAvailableOk(); ok && v != nil {
+			account.AvailableBalanceMinor = helpers.Ptr(money.FromFloat(*v, account.Currency).Minor)
+		}
+		if v, ok := balances.GetLimitOk(); ok && v != nil {
+			account.CreditLimitMinor = helpers.Ptr(money.FromFloat(*v, account.Currency).Minor)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
+
+// GetWebhookVerificationKey fetches the JWK Plaid used to sign a webhook and
+// converts it to an ECDSA public key, along with when Plaid expired it.
+func (a *Adapter) GetWebhookVerificationKey(ctx context.Context, keyID string) (dto.PlaidWebhookKey, error) {
+	req := plaid.NewWebhookVerificationKeyGetRequest(keyID)
+	var resp plaid.WebhookVerificationKeyGetResponse
+	err := a.call(ctx, "webhook_verification_key_get", "failed to get webhook verification key", func(ctx context.Context) (err error) {
+		resp, _, err = a.client.PlaidApi.WebhookVerificationKeyGet(ctx).WebhookVerificationKeyGetRequest(*req).Execute()
+		return err
+	})
+	if err != nil {
+		return dto.PlaidWebhookKey{}, err
+	}
+
+	key := resp.GetKey()
+	if key.GetKty() != "EC" || key.GetCrv() != "P-256" {
+		return dto.PlaidWebhookKey{}, fmt.Errorf("unsupported webhook verification key type %s/%s", key.GetKty(), key.GetCrv())
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(key.GetX())
+	if err != nil {
+		return dto.PlaidWebhookKey{}, fmt.Errorf("decode webhook key x: %w", err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(key.GetY())
+	if err != nil {
+		return dto.PlaidWebhookKey{}, fmt.Errorf("decode webhook key y: %w", err)
+	}
+
+	out := dto.PlaidWebhookKey{
+		Key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		},
+	}
+	if expiredAt := key.GetExpiredAt(); expiredAt != 0 {
+		out.ExpiredAt = time.Unix(int64(expiredAt), 0)
+	}
+	return out, nil
+}
+
 func toPlaidEnv(env dto.PlaidEnvironment) plaid.Environment {
 	switch env {
 	case dto.PlaidSandbox:
@@ -118,6 +297,25 @@ func toPlaidEnv(env dto.PlaidEnvironment) plaid.Environment {
 	}
 }
 
+// plaidError wraps a Plaid SDK error with its error code and transient flag.
+func plaidError(message string, err error) *errs.ExternalServiceError {
+	e := errs.NewExternalServiceError("plaid", message, IsTransientError(err), err)
+	e.Code = ErrorCode(err)
+	return e
+}
+
+// ErrorCode extracts the Plaid error_code from an SDK error, or "" if unavailable.
+func ErrorCode(err error) string {
+	var apiErr plaid.GenericOpenAPIError
+	if errors.As(err, &apiErr) {
+		// Extract the PlaidError from the response body
+		if plaidErr, ok := apiErr.Model().(plaid.PlaidError); ok {
+			return plaidErr.GetErrorCode()
+		}
+	}
+	return ""
+}
+
 // IsTransientError checks if a Plaid error is transient (retryable).
 // Transient errors include rate limits, maintenance, and temporary service issues.
 // Non-transient errors include authentication failures, invalid credentials, etc.
@@ -126,37 +324,28 @@ func IsTransientError(err error) bool {
 		return false
 	}
 
-	// Check for Plaid API errors
-	var apiErr plaid.GenericOpenAPIError
-	if errors.As(err, &apiErr) {
-		// Extract the PlaidError from the response body
-		if plaidErr, ok := apiErr.Model().(plaid.PlaidError); ok {
-			code := plaidErr.GetErrorCode()
-
-			// Transient error codes that may succeed on retry
-			switch code {
-			case "RATE_LIMIT_EXCEEDED",
-				"PLANNED_MAINTENANCE",
-				"INTERNAL_SERVER_ERROR",
-				"PRODUCT_NOT_READY":
-				return true
-
-			// Non-transient errors that won't succeed on retry
-			case "INVALID_API_KEYS",
-				"INVALID_SECRET",
-				"INVALID_ACCESS_TOKEN",
-				"INVALID_PUBLIC_TOKEN",
-				"ITEM_LOGIN_REQUIRED",
-				"ITEM_LOCKED",
-				"ITEM_NOT_FOUND",
-				"INSUFFICIENT_CREDENTIALS",
-				"INVALID_CREDENTIALS",
-				"INVALID_MFA",
-				"INVALID_REQUEST",
-				"INVALID_RESULT":
-				return false
-			}
-		}
+	switch ErrorCode(err) {
+	// Transient error codes that may succeed on retry
+	case "RATE_LIMIT_EXCEEDED",
+		"PLANNED_MAINTENANCE",
+		"INTERNAL_SERVER_ERROR",
+		"PRODUCT_NOT_READY":
+		return true
+
+	// Non-transient errors that won't succeed on retry
+	case "INVALID_API_KEYS",
+		"INVALID_SECRET",
+		"INVALID_ACCESS_TOKEN",
+		"INVALID_PUBLIC_TOKEN",
+		"ITEM_LOGIN_REQUIRED",
+		"ITEM_LOCKED",
+		"ITEM_NOT_FOUND",
+		"INSUFFICIENT_CREDENTIALS",
+		"INVALID_CREDENTIALS",
+		"INVALID_MFA",
+		"INVALID_REQUEST",
+		"INVALID_RESULT":
+		return false
 	}
 
 	// For unknown errors, assume non-transient to avoid infinite retries
@@ -0,0 +1,186 @@
+// Package replayclient records model exchanges to golden files and serves them back,
+// so AI assistant runs can be repeated without a live model.
+package replayclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+)
+
+type llmClient interface {
+	GenerateContent(ctx context.Context, req dto.VertexGenerateRequest) (dto.VertexGenerateResponse, error)
+	GenerateContentStream(ctx context.Context, req dto.VertexGenerateRequest, onText func(string) error) (dto.VertexGenerateResponse, error)
+}
+
+// Exchange is one recorded model call. Raw provider responses are not kept.
+type Exchange struct {
+	Request  dto.VertexGenerateRequest `json:"request"`
+	Response Response                  `json:"response"`
+}
+
+type Response struct {
+	Text      string               `json:"text,omitempty"`
+	ToolCalls []dto.VertexToolCall `json:"toolCalls,omitempty"`
+}
+
+// goldenFile is the on-disk format shared by Recorder and Client.
+type goldenFile struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// Recorder passes calls through to a live client and writes every successful
+// exchange to a golden file. The file is rewritten after each call, so a run that
+// stops early still leaves what it recorded.
+type Recorder struct {
+	next llmClient
+	path string
+
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+func NewRecorder(next llmClient, path string) *Recorder {
+	return &Recorder{next: next, path: path}
+}
+
+func (r *Recorder) GenerateContent(ctx context.Context, req dto.VertexGenerateRequest) (dto.VertexGenerateResponse, error) {
+	resp, err := r.next.GenerateContent(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	return resp, r.record(req, resp)
+}
+
+func (r *Recorder) GenerateContentStream(ctx context.Context, req dto.VertexGenerateRequest, onText func(string) error) (dto.VertexGenerateResponse, error) {
+	resp, err := r.next.GenerateContentStream(ctx, req, onText)
+	if err != nil {
+		return resp, err
+	}
+	return resp, r.record(req, resp)
+}
+
+func (r *Recorder) record(req dto.VertexGenerateRequest, resp dto.VertexGenerateResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.exchanges = append(r.exchanges, Exchange{
+		Request:  req,
+		Response: Response{Text: resp.Text, ToolCalls: resp.ToolCalls},
+	})
+	raw, err := json.MarshalIndent(goldenFile{Exchanges: r.exchanges}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode golden file: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(raw, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write golden file: %w", err)
+	}
+	return nil
+}
+
+// Client serves recorded responses. A request matches an exchange with the same
+// conversation contents; repeats of one conversation are served in recorded order.
+// The system prompt and tools are not part of the match, so prompt and schema
+// changes still replay, and Stale counts the exchanges they would have changed.
+type Client struct {
+	mu      sync.Mutex
+	pending map[string][]Exchange
+	stale   int
+}
+
+// Load reads a golden file written by a Recorder.
+func Load(path string) (*Client, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read golden file: %w", err)
+	}
+	var f goldenFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("parse golden file %s: %w", path, err)
+	}
+
+	c := &Client{pending: map[string][]Exchange{}}
+	for _, ex := range f.Exchanges {
+		key, err := contentsKey(ex.Request.Contents)
+		if err != nil {
+			return nil, err
+		}
+		c.pending[key] = append(c.pending[key], ex)
+	}
+	return c, nil
+}
+
+func (c *Client) GenerateContent(ctx context.Context, req dto.VertexGenerateRequest) (dto.VertexGenerateResponse, error) {
+	key, err := contentsKey(req.Contents)
+	if err != nil {
+		return dto.VertexGenerateResponse{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	queue := c.pending[key]
+	if len(queue) == 0 {
+		return dto.VertexGenerateResponse{}, errs.NewExternalServiceError("replay", "no recorded response for request", false, nil)
+	}
+	ex := queue[0]
+	c.pending[key] = queue[1:]
+
+	if isStale(ex.Request, req) {
+		c.stale++
+	}
+	return dto.VertexGenerateResponse{Text: ex.Response.Text, ToolCalls: ex.Response.ToolCalls, Raw: ex}, nil
+}
+
+// GenerateContentStream serves the recorded response, passing its text to onText
+// in one piece.
+func (c *Client) GenerateContentStream(ctx context.Context, req dto.VertexGenerateRequest, onText func(string) error) (dto.VertexGenerateResponse, error) {
+	resp, err := c.GenerateContent(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.Text != "" {
+		if err := onText(resp.Text); err != nil {
+			return dto.VertexGenerateResponse{}, err
+		}
+	}
+	return resp, nil
+}
+
+// Stale reports how many served exchanges were recorded with a different system
+// prompt, tool set or tool config than the request that replayed them.
+func (c *Client) Stale() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stale
+}
+
+// contentsKey is the JSON form of contents. Recorded requests went through the
+// same encoding, so equal conversations give equal keys.
+func contentsKey(contents []dto.VertexContent) (string, error) {
+	raw, err := json.Marshal(contents)
+	if err != nil {
+		return "", fmt.Errorf("encode replay key: %w", err)
+	}
+	return string(raw), nil
+}
+
+func isStale(recorded, req dto.VertexGenerateRequest) bool {
+	if recorded.System != req.System {
+		return true
+	}
+	// Compare through JSON, the form the recording was loaded from.
+	for _, pair := range [][2]any{{recorded.Tools, req.Tools}, {recorded.ToolConfig, req.ToolConfig}} {
+		a, errA := json.Marshal(pair[0])
+		b, errB := json.Marshal(pair[1])
+		if errA != nil || errB != nil || !bytes.Equal(a, b) {
+			return true
+		}
+	}
+	return false
+}
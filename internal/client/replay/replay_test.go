@@ -0,0 +1,109 @@
+package replayclient
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+// fakeLLM answers every request with the next of its responses.
+type fakeLLM struct {
+	responses []dto.VertexGenerateResponse
+}
+
+func (f *fakeLLM) GenerateContent(ctx context.Context, req dto.VertexGenerateRequest) (dto.VertexGenerateResponse, error) {
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	return resp, nil
+}
+
+func (f *fakeLLM) GenerateContentStream(ctx context.Context, req dto.VertexGenerateRequest, onText func(string) error) (dto.VertexGenerateResponse, error) {
+	resp, err := f.GenerateContent(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	return resp, onText(resp.Text)
+}
+
+func question(text string) dto.VertexGenerateRequest {
+	return dto.VertexGenerateRequest{
+		System:   "prompt v1",
+		Contents: []dto.VertexContent{{Role: "user", Parts: []dto.VertexPart{{Text: helpers.Ptr(text)}}}},
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden.json")
+	live := &fakeLLM{responses: []dto.VertexGenerateResponse{
+		{ToolCalls: []dto.VertexToolCall{{Name: "get_spend_total", Args: map[string]any{"limit": 5}}}, Raw: "raw"},
+		{Text: "first"},
+		{Text: "second"},
+	}}
+	rec := NewRecorder(live, path)
+	ctx := helpers.TestCtx()
+
+	if _, err := rec.GenerateContent(ctx, question("total?")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range 2 {
+		if _, err := rec.GenerateContentStream(ctx, question("hi"), func(string) error { return nil }); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	replay, err := Load(path)
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+
+	resp, err := replay.GenerateContent(ctx, question("total?"))
+	if err != nil || len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Args["limit"] != float64(5) {
+		t.Fatalf("unexpected replay: %+v, %v", resp, err)
+	}
+	// Repeats of a conversation come back in recorded order.
+	for _, want := range []string{"first", "second"} {
+		var streamed string
+		resp, err := replay.GenerateContentStream(ctx, question("hi"), func(text string) error {
+			streamed += text
+			return nil
+		})
+		if err != nil || resp.Text != want || streamed != want {
+			t.Fatalf("expected %q, got %+v (streamed %q), %v", want, resp, streamed, err)
+		}
+	}
+
+	_, err = replay.GenerateContent(ctx, question("hi"))
+	var extErr *errs.ExternalServiceError
+	if !errors.As(err, &extErr) || extErr.Transient {
+		t.Fatalf("expected a permanent error once recordings run out, got %v", err)
+	}
+	if replay.Stale() != 0 {
+		t.Fatalf("expected no stale exchanges, got %d", replay.Stale())
+	}
+}
+
+func TestReplayCountsStaleExchanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden.json")
+	rec := NewRecorder(&fakeLLM{responses: []dto.VertexGenerateResponse{{Text: "ok"}}}, path)
+	if _, err := rec.GenerateContent(helpers.TestCtx(), question("total?")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	replay, err := Load(path)
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	req := question("total?")
+	req.System = "prompt v2"
+	if resp, err := replay.GenerateContent(helpers.TestCtx(), req); err != nil || resp.Text != "ok" {
+		t.Fatalf("expected a prompt change to still replay, got %+v, %v", resp, err)
+	}
+	if replay.Stale() != 1 {
+		t.Fatalf("expected one stale exchange, got %d", replay.Stale())
+	}
+}
@@ -2,15 +2,19 @@ package vertexclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"cloud.google.com/go/vertexai/genai"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/GregMSThompson/finance-backend/internal/dto"
 	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/retry"
 	"github.com/GregMSThompson/finance-backend/pkg/logger"
 )
 
@@ -18,9 +22,10 @@ type Adapter struct {
 	client *genai.Client
 	model  string
 	log    *slog.Logger
+	retry  *retry.Policy
 }
 
-func NewAdapter(ctx context.Context, log *slog.Logger, projectID, region, model string) (*Adapter, error) {
+func NewAdapter(ctx context.Context, log *slog.Logger, projectID, region, model string, policy *retry.Policy) (*Adapter, error) {
 	client, err := genai.NewClient(ctx, projectID, region)
 	if err != nil {
 		return nil, errs.NewExternalServiceError("vertex", "failed to create Vertex AI client", IsTransientError(err), err)
@@ -30,6 +35,7 @@ func NewAdapter(ctx context.Context, log *slog.Logger, projectID, region, model
 		client: client,
 		model:  model,
 		log:    log,
+		retry:  policy,
 	}, nil
 }
 
@@ -41,15 +47,22 @@ func (a *Adapter) Close() error {
 	return err
 }
 
-func (a *Adapter) GenerateContent(ctx context.Context, req dto.VertexGenerateRequest) (dto.VertexGenerateResponse, error) {
-	out := dto.VertexGenerateResponse{}
+// chatRequest is a generate request converted to genai types.
+type chatRequest struct {
+	model   *genai.GenerativeModel
+	history []*genai.Content
+	parts   []genai.Part
+}
 
+// newChatRequest configures the model and splits the request contents into chat
+// history and the current message.
+func (a *Adapter) newChatRequest(ctx context.Context, req dto.VertexGenerateRequest) (*chatRequest, error) {
 	modelName := req.Model
 	if modelName == "" {
 		modelName = a.model
 	}
 	if modelName == "" {
-		return out, fmt.Errorf("vertex model is required")
+		return nil, fmt.Errorf("vertex model is required")
 	}
 
 	model := a.client.GenerativeModel(modelName)
@@ -110,28 +123,46 @@ func (a *Adapter) GenerateContent(ctx context.Context, req dto.VertexGenerateReq
 	}
 
 	if len(req.Contents) == 0 {
-		return out, fmt.Errorf("vertex generate request has no content")
+		return nil, fmt.Errorf("vertex generate request has no content")
 	}
 
-	// Split contents into history and current message
-	var history []*genai.Content
-	var currentParts []genai.Part
-
+	cr := &chatRequest{model: model}
 	if len(req.Contents) > 1 {
 		// Convert all but last to history
-		history = toGenaiContents(req.Contents[:len(req.Contents)-1])
+		cr.history = toGenaiContents(req.Contents[:len(req.Contents)-1])
 	}
-
 	// Last content is the current message
-	currentParts = toGenaiParts(req.Contents[len(req.Contents)-1].Parts)
+	cr.parts = toGenaiParts(req.Contents[len(req.Contents)-1].Parts)
+	return cr, nil
+}
 
-	// Use chat session with history
-	chat := model.StartChat()
-	chat.History = history
+// startChat opens a chat session holding the request history. Sending a message
+// appends to the session, so every attempt needs a fresh one.
+func (cr *chatRequest) startChat() *genai.ChatSession {
+	chat := cr.model.StartChat()
+	chat.History = append([]*genai.Content(nil), cr.history...)
+	return chat
+}
+
+func (a *Adapter) GenerateContent(ctx context.Context, req dto.VertexGenerateRequest) (dto.VertexGenerateResponse, error) {
+	out := dto.VertexGenerateResponse{}
 
-	resp, err := chat.SendMessage(ctx, currentParts...)
+	cr, err := a.newChatRequest(ctx, req)
 	if err != nil {
-		return out, errs.NewExternalServiceError("vertex", "failed to generate content", IsTransientError(err), err)
+		return out, err
+	}
+
+	var resp *genai.GenerateContentResponse
+	err = a.retry.Do(ctx, "vertex.generate_content", func(ctx context.Context) error {
+		var err error
+		resp, err = cr.startChat().SendMessage(ctx, cr.parts...)
+		if err != nil {
+			return errs.NewExternalServiceError("vertex", "failed to generate content", IsTransientError(err), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return out, err
 	}
 
 	out.Raw = resp
@@ -223,6 +254,81 @@ func (a *Adapter) GenerateContent(ctx context.Context, req dto.VertexGenerateReq
 	return out, nil
 }
 
+// GenerateContentStream is GenerateContent over a streaming call. onText receives
+// each piece of answer text as it arrives; the returned response holds the complete
+// text and any tool calls. A failed stream is only retried if nothing was passed
+// to onText yet.
+func (a *Adapter) GenerateContentStream(ctx context.Context, req dto.VertexGenerateRequest, onText func(string) error) (dto.VertexGenerateResponse, error) {
+	out := dto.VertexGenerateResponse{}
+
+	cr, err := a.newChatRequest(ctx, req)
+	if err != nil {
+		return out, err
+	}
+
+	var (
+		text      strings.Builder
+		calls     []dto.VertexToolCall
+		malformed bool
+	)
+	err = a.retry.Do(ctx, "vertex.stream_generate_content", func(ctx context.Context) error {
+		text.Reset()
+		calls, malformed = nil, false
+
+		iter := cr.startChat().SendMessageStream(ctx, cr.parts...)
+		for {
+			resp, err := iter.Next()
+			if err == iterator.Done {
+				return nil
+			}
+			if err != nil {
+				var blocked *genai.BlockedError
+				if errors.As(err, &blocked) {
+					return errs.NewExternalServiceError("vertex", "response blocked by safety filters", false, err)
+				}
+				if text.Len() > 0 {
+					// Text already reached the caller, so a retry would repeat it. The cause
+					// is left off so the retry policy can't classify it as rate limited.
+					return errs.NewExternalServiceError("vertex", fmt.Sprintf("content stream interrupted: %v", err), false, nil)
+				}
+				return errs.NewExternalServiceError("vertex", "failed to stream content", IsTransientError(err), err)
+			}
+
+			chunk, chunkCalls := parseContentResponse(resp)
+			calls = append(calls, chunkCalls...)
+			for _, candidate := range resp.Candidates {
+				if candidate.FinishReason == genai.FinishReasonMalformedFunctionCall {
+					malformed = true
+				}
+			}
+			if chunk == "" {
+				continue
+			}
+			text.WriteString(chunk)
+			if err := onText(chunk); err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		return out, err
+	}
+
+	out.Text, out.ToolCalls = text.String(), calls
+	if logger.IsDebugEnabled(ctx) {
+		log := logger.FromContext(ctx)
+		log.Debug("vertex stream content response", "toolCalls", len(out.ToolCalls), "textLen", len(out.Text))
+	}
+
+	if len(out.Text) == 0 && len(out.ToolCalls) == 0 {
+		if malformed {
+			return out, errs.NewMalformedFunctionCallError()
+		}
+		return out, fmt.Errorf("vertex response contained no text or tool calls")
+	}
+	return out, nil
+}
+
 func parseContentResponse(resp *genai.GenerateContentResponse) (string, []dto.VertexToolCall) {
 	if resp == nil || len(resp.Candidates) == 0 {
 		return "", nil
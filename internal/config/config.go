@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/GregMSThompson/finance-backend/internal/dto"
@@ -14,9 +15,17 @@ type Config struct {
 	PlaidClientID    string
 	PlaidSecret      string
 	PlaidEnvironment dto.PlaidEnvironment
+	PlaidWebhookURL  string
 	KMSKeyName       string
 	VertexModel      string
+	AIProvider       string // "vertex" (default) or "openai" for an OpenAI-compatible server
+	OpenAIBaseURL    string // e.g. http://localhost:11434/v1; openai provider only
+	OpenAIAPIKey     string
+	OpenAIModel      string
 	AITTL            time.Duration
+	SyncInterval     time.Duration // worker only; zero runs a single pass and exits
+	SyncConcurrency  int
+	FXRatesFile      string // JSON exchange rates for base-currency analytics; optional
 }
 
 func New() *Config {
@@ -27,9 +36,17 @@ func New() *Config {
 		PlaidClientID:    os.Getenv("PLAIDCLIENTID"),
 		PlaidSecret:      os.Getenv("PLAIDSECRET"),
 		PlaidEnvironment: getPlaidEnvironment(os.Getenv("PLAIDENVIRONMENT")),
+		PlaidWebhookURL:  os.Getenv("PLAIDWEBHOOKURL"),
 		KMSKeyName:       os.Getenv("KMSKEYNAME"),
 		VertexModel:      os.Getenv("VERTEXMODEL"),
+		AIProvider:       os.Getenv("AIPROVIDER"),
+		OpenAIBaseURL:    os.Getenv("OPENAIBASEURL"),
+		OpenAIAPIKey:     os.Getenv("OPENAIAPIKEY"),
+		OpenAIModel:      os.Getenv("OPENAIMODEL"),
 		AITTL:            parseDuration(os.Getenv("AITTL")),
+		SyncInterval:     parseDuration(os.Getenv("SYNCINTERVAL")),
+		SyncConcurrency:  parseInt(os.Getenv("SYNCCONCURRENCY")),
+		FXRatesFile:      os.Getenv("FXRATESFILE"),
 	}
 }
 
@@ -54,3 +71,14 @@ func parseDuration(value string) time.Duration {
 	}
 	return d
 }
+
+func parseInt(value string) int {
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return n
+}
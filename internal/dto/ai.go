@@ -1,5 +1,11 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/models"
+)
+
 type AIQueryRequest struct {
 	SessionID string `json:"sessionId"`
 	Message   string `json:"message"`
@@ -10,7 +16,118 @@ type AIQueryResponse struct {
 	Debug  *AIDebugInfo `json:"debug,omitempty"`
 }
 
+// AIDebugInfo describes the tools a query ran. Tool and Args are the first call;
+// Calls lists every call in the order it was made.
 type AIDebugInfo struct {
+	Tool  string            `json:"tool"`
+	Args  map[string]any    `json:"args"`
+	Calls []AIDebugToolCall `json:"calls"`
+}
+
+type AIDebugToolCall struct {
+	Step int            `json:"step"`
+	Tool string         `json:"tool"`
+	Args map[string]any `json:"args"`
+}
+
+// Server-sent event types of a streamed AI query
+const (
+	AIEventToolCall   = "tool_call"
+	AIEventToolResult = "tool_result"
+	AIEventText       = "text"
+	AIEventDone       = "done"
+	AIEventError      = "error"
+)
+
+// AIStreamEvent is one server-sent event of a streamed AI query. Data is the JSON payload: an
+// AIStreamToolCall, AIStreamToolResult, AIStreamText, AIQueryResponse (done) or
+// an error.
+type AIStreamEvent struct {
+	Type string
+	Data any
+}
+
+type AIStreamToolCall struct {
+	Step int            `json:"step"`
 	Tool string         `json:"tool"`
 	Args map[string]any `json:"args"`
 }
+
+type AIStreamToolResult struct {
+	Step    int    `json:"step"`
+	Tool    string `json:"tool"`
+	Summary string `json:"summary"`
+}
+
+type AIStreamText struct {
+	Delta string `json:"delta"`
+}
+
+type AISessionRequest struct {
+	Title string `json:"title"`
+}
+
+// Latest turn of a session, recorded after each query
+type AISessionActivity struct {
+	Title       string // used only if the session has no title yet
+	LastMessage string
+	At          time.Time
+	ExpiresAt   time.Time // zero when messages don't expire
+}
+
+// A session together with the uid of the user who owns it
+type UserSession struct {
+	UID       string
+	SessionID string
+}
+
+// Outcome of one pass of the AI retention sweeper
+type AISweepResult struct {
+	MessagesDeleted int
+	SessionsDeleted int
+}
+
+// Position of a message within a session, newest first
+type AIMessageCursor struct {
+	CreatedAt time.Time
+	MessageID string
+}
+
+// One page of a session's messages, oldest first within the page. NextPageToken
+// fetches the page of older messages.
+type AIMessagePage struct {
+	Messages      []models.AIMessage `json:"messages"`
+	NextPageToken string             `json:"nextPageToken,omitempty"`
+}
+
+// A set of assistant eval cases. Now is the YYYY-MM-DD date the prompt treats as
+// today, so relative dates in questions resolve the same way on every run.
+type AIEvalSuite struct {
+	Now   string       `json:"now"`
+	Cases []AIEvalCase `json:"cases"`
+}
+
+// One eval question and the tool call the model should answer it with. An empty
+// Tool expects no tool call. Args lists only the arguments that must match; the
+// call may set others.
+type AIEvalCase struct {
+	Name     string         `json:"name"`
+	Question string         `json:"question"`
+	Tool     string         `json:"tool,omitempty"`
+	Args     map[string]any `json:"args,omitempty"`
+}
+
+type AIEvalResult struct {
+	Name   string         `json:"name"`
+	Passed bool           `json:"passed"`
+	Tool   string         `json:"tool,omitempty"` // first tool the model called
+	Args   map[string]any `json:"args,omitempty"`
+	Reason string         `json:"reason,omitempty"` // why the case failed
+}
+
+type AIEvalReport struct {
+	Total    int            `json:"total"`
+	Passed   int            `json:"passed"`
+	Accuracy float64        `json:"accuracy"`
+	Results  []AIEvalResult `json:"results"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+// Fields a client sets when creating an alert rule. MinAmount is in major units of
+// Currency, which is required when MinAmount is set.
+type AlertRuleInput struct {
+	Name        string   `json:"name"`
+	MinAmount   *float64 `json:"minAmount,omitempty"`
+	Currency    string   `json:"currency,omitempty"`
+	PFCPrimary  string   `json:"pfcPrimary,omitempty"`
+	Merchant    string   `json:"merchant,omitempty"`
+	BankID      string   `json:"bankId,omitempty"`
+	NewMerchant bool     `json:"newMerchant,omitempty"`
+}
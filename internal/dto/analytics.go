@@ -1,44 +1,95 @@
 package dto
 
-import "github.com/GregMSThompson/finance-backend/internal/models"
-
+import (
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/money"
+)
+
+// Flow directions accepted by the analytics args. Plaid amounts are positive for money
+// leaving an account, so outflows are debits and inflows are credits such as refunds.
+const (
+	FlowOutflow = "outflow"
+	FlowInflow  = "inflow"
+	FlowNet     = "net"
+)
+
+// Analytics args share Direction and IncludeTransfers: Direction picks which
+// transactions count toward Total and Count (defaulting to FlowOutflow), and
+// TRANSFER_IN/TRANSFER_OUT transactions are skipped unless IncludeTransfers is set
+// or the transfer category is requested explicitly.
 type AnalyticsSpendTotalArgs struct {
-	Pending    *bool
-	PFCPrimary *string
-	BankID     *string
-	Merchant   *string
-	DateFrom   *string
-	DateTo     *string
+	Pending          *bool
+	PFCPrimary       *string
+	BankID           *string
+	Merchant         *string
+	DateFrom         *string
+	DateTo           *string
+	BaseCurrency     *string
+	Direction        string
+	IncludeTransfers bool
 }
 
+// FlowTotals splits amounts by direction. Spend and Income are both positive; Net is
+// Income minus Spend, so it is negative when more money went out than came in.
+type FlowTotals struct {
+	Spend  money.Money `json:"spend"`
+	Income money.Money `json:"income"`
+	Net    money.Money `json:"net"`
+}
+
+// CurrencyTotal is the unconverted sum of the transactions in one currency.
+type CurrencyTotal struct {
+	Currency string      `json:"currency"`
+	Total    money.Money `json:"total"`
+	Count    int         `json:"count"`
+	FlowTotals
+}
+
+// AnalyticsSpendTotalResult reports Total in Currency only when every transaction shares
+// a currency or a base currency was requested; mixed currencies leave both empty and
+// callers should read Totals.
 type AnalyticsSpendTotalResult struct {
-	Total    float64 `json:"total"`
-	Currency string  `json:"currency"`
-	From     string  `json:"from,omitempty"`
-	To       string  `json:"to,omitempty"`
+	Direction string          `json:"direction"`
+	Total     money.Money     `json:"total"`
+	Currency  string          `json:"currency"`
+	Totals    []CurrencyTotal `json:"totals"`
+	From      string          `json:"from,omitempty"`
+	To        string          `json:"to,omitempty"`
+	FlowTotals
 }
 
 type AnalyticsSpendBreakdownArgs struct {
-	Pending    *bool
-	PFCPrimary *string
-	BankID     *string
-	DateFrom   *string
-	DateTo     *string
-	GroupBy    string
+	Pending          *bool
+	PFCPrimary       *string
+	BankID           *string
+	Merchant         *string
+	DateFrom         *string
+	DateTo           *string
+	GroupBy          string
+	BaseCurrency     *string
+	Direction        string
+	IncludeTransfers bool
 }
 
+// AnalyticsBreakdownItem totals one group in one currency. Without a base currency a
+// group spanning several currencies appears once per currency. Groups with no
+// transactions in the requested direction are omitted.
 type AnalyticsBreakdownItem struct {
-	Key   string  `json:"key"`
-	Total float64 `json:"total"`
-	Count int     `json:"count"`
+	Key      string      `json:"key"`
+	Currency string      `json:"currency,omitempty"`
+	Total    money.Money `json:"total"`
+	Count    int         `json:"count"`
+	FlowTotals
 }
 
 type AnalyticsSpendBreakdownResult struct {
-	GroupBy  string                   `json:"groupBy"`
-	Items    []AnalyticsBreakdownItem `json:"items"`
-	Currency string                   `json:"currency"`
-	From     string                   `json:"from,omitempty"`
-	To       string                   `json:"to,omitempty"`
+	GroupBy   string                   `json:"groupBy"`
+	Direction string                   `json:"direction"`
+	Items     []AnalyticsBreakdownItem `json:"items"`
+	Currency  string                   `json:"currency"`
+	Totals    []CurrencyTotal          `json:"totals"`
+	From      string                   `json:"from,omitempty"`
+	To        string                   `json:"to,omitempty"`
 }
 
 type AnalyticsTransactionsArgs struct {
@@ -58,68 +109,81 @@ type AnalyticsTransactionsResult struct {
 }
 
 type AnalyticsPeriodComparisonArgs struct {
-	Pending      *bool
-	PFCPrimary   *string
-	BankID       *string
-	Merchant     *string
-	CurrentFrom  string
-	CurrentTo    string
-	PreviousFrom string
-	PreviousTo   string
-	GroupBy      string
+	Pending          *bool
+	PFCPrimary       *string
+	BankID           *string
+	Merchant         *string
+	CurrentFrom      string
+	CurrentTo        string
+	PreviousFrom     string
+	PreviousTo       string
+	GroupBy          string
+	BaseCurrency     *string
+	Direction        string
+	IncludeTransfers bool
 }
 
 type PeriodSummary struct {
-	Total    float64                  `json:"total"`
+	Total    money.Money              `json:"total"`
 	Count    int                      `json:"count"`
 	Currency string                   `json:"currency"`
+	Totals   []CurrencyTotal          `json:"totals"`
 	From     string                   `json:"from"`
 	To       string                   `json:"to"`
 	Items    []AnalyticsBreakdownItem `json:"items,omitempty"`
+	FlowTotals
 }
 
 type BreakdownItemChange struct {
-	Key              string   `json:"key"`
-	AbsoluteChange   float64  `json:"absoluteChange"`
-	PercentageChange *float64 `json:"percentageChange,omitempty"`
-	CountChange      int      `json:"countChange"`
+	Key              string      `json:"key"`
+	Currency         string      `json:"currency,omitempty"`
+	AbsoluteChange   money.Money `json:"absoluteChange"`
+	PercentageChange *float64    `json:"percentageChange,omitempty"`
+	CountChange      int         `json:"countChange"`
 }
 
+// Change between two periods. AbsoluteChange and PercentageChange are omitted when
+// the periods' headline totals are in different currencies; compare their Totals.
 type PeriodChange struct {
-	AbsoluteChange   float64              `json:"absoluteChange"`
-	PercentageChange *float64             `json:"percentageChange,omitempty"`
-	CountChange      int                  `json:"countChange"`
+	AbsoluteChange   *money.Money          `json:"absoluteChange,omitempty"`
+	PercentageChange *float64              `json:"percentageChange,omitempty"`
+	CountChange      int                   `json:"countChange"`
 	Items            []BreakdownItemChange `json:"items,omitempty"`
 }
 
 type AnalyticsPeriodComparisonResult struct {
-	GroupBy  string        `json:"groupBy,omitempty"`
-	Current  PeriodSummary `json:"current"`
-	Previous PeriodSummary `json:"previous"`
-	Change   PeriodChange  `json:"change"`
+	GroupBy   string        `json:"groupBy,omitempty"`
+	Direction string        `json:"direction"`
+	Current   PeriodSummary `json:"current"`
+	Previous  PeriodSummary `json:"previous"`
+	Change    PeriodChange  `json:"change"`
 }
 
 type AnalyticsRecurringArgs struct {
-	BankID   *string
-	DateFrom string
-	DateTo   string
+	BankID       *string
+	DateFrom     string
+	DateTo       string
+	BaseCurrency *string
 }
 
 type RecurringItem struct {
-	Merchant          string  `json:"merchant"`
-	Frequency         string  `json:"frequency"`
-	TypicalAmount     float64 `json:"typicalAmount"`
-	AmountIsVariable  bool    `json:"amountIsVariable"`
-	Currency          string  `json:"currency"`
-	OccurrenceCount   int     `json:"occurrenceCount"`
-	LastDate          string  `json:"lastDate"`
-	MonthlyEquivalent float64 `json:"monthlyEquivalent"`
+	Merchant          string      `json:"merchant"`
+	Frequency         string      `json:"frequency"`
+	TypicalAmount     money.Money `json:"typicalAmount"`
+	AmountIsVariable  bool        `json:"amountIsVariable"`
+	Currency          string      `json:"currency"`
+	OccurrenceCount   int         `json:"occurrenceCount"`
+	LastDate          string      `json:"lastDate"`
+	MonthlyEquivalent money.Money `json:"monthlyEquivalent"`
 }
 
+// RecurringTransactionsResult follows the same currency rules as AnalyticsSpendTotalResult;
+// Totals holds the monthly equivalent per currency.
 type RecurringTransactionsResult struct {
 	Items                  []RecurringItem `json:"items"`
-	TotalMonthlyEquivalent float64         `json:"totalMonthlyEquivalent"`
+	TotalMonthlyEquivalent money.Money     `json:"totalMonthlyEquivalent"`
 	Currency               string          `json:"currency"`
+	Totals                 []CurrencyTotal `json:"totals"`
 	From                   string          `json:"from"`
 	To                     string          `json:"to"`
 }
@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/money"
+)
+
+// Fields a client sets when creating or replacing a budget
+type BudgetInput struct {
+	Name       string  `json:"name"`
+	PFCPrimary string  `json:"pfcPrimary,omitempty"`
+	Merchant   string  `json:"merchant,omitempty"`
+	Period     string  `json:"period"`
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency"`
+}
+
+// Progress of one budget through its current period
+type BudgetStatus struct {
+	Budget      *models.Budget `json:"budget"`
+	PeriodStart string         `json:"periodStart"`
+	PeriodEnd   string         `json:"periodEnd"`
+	Spent       money.Money    `json:"spent"`
+	Remaining   money.Money    `json:"remaining"` // negative once the budget is exceeded
+	Projected   money.Money    `json:"projected"` // end-of-period spend at the current daily rate
+	OverBudget  bool           `json:"overBudget"`
+	// ProjectedOverBudget is true when the current pace would exceed the budget by period end.
+	ProjectedOverBudget bool `json:"projectedOverBudget"`
+	// ExcludedCurrencies lists currencies left out of Spent for lack of an exchange rate.
+	ExcludedCurrencies []string `json:"excludedCurrencies,omitempty"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+const (
+	NotificationSyncCompleted = "sync_completed"
+	NotificationAlert         = "alert"
+	NotificationLoginRequired = "login_required"
+)
+
+// A push notification for every device a user has registered. Data carries the
+// notification type under "type" plus ids the app needs to deep link.
+type Notification struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
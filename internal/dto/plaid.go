@@ -1,22 +1,70 @@
 package dto
 
 import (
+	"crypto/ecdsa"
+	"time"
+
 	"github.com/GregMSThompson/finance-backend/internal/models"
 )
 
 // Metadata from the transaction sync process
 type PlaidServiceSyncResult struct {
 	BanksSynced          int
+	BanksFailed          int
 	TransactionsInserted int
 	TransactionsUpdated  int
+	TransactionsRemoved  int
 	Cursor               string // latest cursor if syncing one bank; empty when multiple
+	Banks                []PlaidBankSyncResult
+	AlertsCreated        int
+
+	NewTransactions []models.Transaction `json:"-"` // inserted by this sync, for alert evaluation
+}
+
+// Per-bank outcome of a transaction sync
+type PlaidBankSyncResult struct {
+	BankID               string
+	Success              bool
+	TransactionsInserted int
+	TransactionsUpdated  int
+	TransactionsRemoved  int
+	ErrorCode            string `json:",omitempty"` // Plaid error_code when the failure came from Plaid
+	Error                string `json:",omitempty"`
+	Transient            bool   // whether retrying later may succeed
 }
 
 // Paid adapter result - represents one page from /transactions/sync
 type PlaidSyncPage struct {
-	Transactions []models.Transaction
-	Cursor       string
-	HasMore      bool
+	Transactions          []models.Transaction
+	RemovedTransactionIDs []string
+	Cursor                string
+	HasMore               bool
+}
+
+// Stored /transactions/sync position of one bank. PaginationStart is the cursor the
+// unfinished pagination loop began from, or nil when no loop is in progress.
+type PlaidSyncCursor struct {
+	Cursor          string
+	PaginationStart *string
+}
+
+// Public key Plaid signs webhooks with. ExpiredAt is zero while the key is current.
+type PlaidWebhookKey struct {
+	Key       *ecdsa.PublicKey
+	ExpiredAt time.Time
+}
+
+// Plaid webhook payload - only the fields the service acts on
+type PlaidWebhook struct {
+	WebhookType string             `json:"webhook_type"`
+	WebhookCode string             `json:"webhook_code"`
+	ItemID      string             `json:"item_id"`
+	Error       *PlaidWebhookError `json:"error,omitempty"`
+}
+
+type PlaidWebhookError struct {
+	ErrorCode    string `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
 }
 
 type PlaidEnvironment string
@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/models"
+)
+
+// A bank together with the uid of the user who linked it
+type UserBank struct {
+	UID  string
+	Bank *models.Bank
+}
+
+// Outcome of a scheduled sync recorded on the bank document
+type BankSyncState struct {
+	LastSyncAt    time.Time
+	LastSyncError string // empty when the sync succeeded
+	SyncFailures  int    // consecutive failures, reset on success
+	NextSyncAt    time.Time
+}
+
+// Metadata from one scheduled pass over every linked bank
+type ScheduledSyncResult struct {
+	BanksDue     int
+	BanksSkipped int
+	BanksSynced  int
+	BanksFailed  int
+}
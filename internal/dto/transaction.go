@@ -1,5 +1,9 @@
 package dto
 
+import (
+	"github.com/GregMSThompson/finance-backend/internal/models"
+)
+
 type TransactionQuery struct {
 	Pending    *bool
 	PFCPrimary *string
@@ -10,4 +14,25 @@ type TransactionQuery struct {
 	OrderBy    string
 	Desc       bool
 	Limit      int
+	StartAfter *TransactionCursor // resume after this position in the ordering
+}
+
+// Position of a transaction within a query ordering
+type TransactionCursor struct {
+	OrderValue    any // value of the OrderBy field
+	TransactionID string
+}
+
+// One page of the transaction listing API
+type TransactionPage struct {
+	Transactions  []*models.Transaction `json:"transactions"`
+	NextPageToken string                `json:"nextPageToken,omitempty"`
+}
+
+// Outcome of a transaction batch upsert
+type TransactionUpsertResult struct {
+	Inserted    int
+	Updated     int
+	Unchanged   int
+	InsertedIDs []string
 }
@@ -0,0 +1,11 @@
+package dto
+
+// UserUpdate is a partial profile update; nil fields are left unchanged. A non-nil
+// Preferences replaces the stored preferences as a whole.
+type UserUpdate struct {
+	FirstName    *string           `json:"firstName"`
+	LastName     *string           `json:"lastName"`
+	Timezone     *string           `json:"timezone"`
+	BaseCurrency *string           `json:"baseCurrency"`
+	Preferences  map[string]string `json:"preferences"`
+}
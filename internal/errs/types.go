@@ -21,6 +21,19 @@ type ValidationError struct {
 	ErrorMessage
 }
 
+// MissingExchangeRateError reports that no rate is known for converting From into
+// To. It is a client error, like ValidationError, but lets callers tell a missing
+// rate apart from other invalid input.
+type MissingExchangeRateError struct {
+	ErrorMessage
+	From string
+	To   string
+}
+
+type UnauthorizedError struct {
+	ErrorMessage
+}
+
 type UnsupportedGroupByError struct {
 	ErrorMessage
 }
@@ -37,6 +50,7 @@ type DatabaseError struct {
 type ExternalServiceError struct {
 	ErrorMessage
 	Service   string // "plaid", "vertex", "firestore", "kms"
+	Code      string // provider error code when known, e.g. "ITEM_LOGIN_REQUIRED"
 	Transient bool   // true if retry might succeed
 }
 
@@ -62,6 +76,23 @@ func NewValidationError(message string) *ValidationError {
 	}
 }
 
+func NewMissingExchangeRateError(from, to string) *MissingExchangeRateError {
+	return &MissingExchangeRateError{
+		ErrorMessage: ErrorMessage{Message: "no exchange rate from " + from + " to " + to},
+		From:         from,
+		To:           to,
+	}
+}
+
+func NewUnauthorizedError(message string, cause error) *UnauthorizedError {
+	return &UnauthorizedError{
+		ErrorMessage: ErrorMessage{
+			Message: message,
+			Cause:   cause,
+		},
+	}
+}
+
 func NewUnsupportedGroupByError() *UnsupportedGroupByError {
 	return &UnsupportedGroupByError{
 		ErrorMessage: ErrorMessage{Message: "unsupported groupBy"},
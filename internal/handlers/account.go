@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/GregMSThompson/finance-backend/internal/middleware"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/response"
+)
+
+type accountService interface {
+	ListAccounts(ctx context.Context, uid string) ([]*models.Account, error)
+}
+
+type accountHandlers struct {
+	ResponseHandler response.ResponseHandler
+	AccountSvc      accountService
+}
+
+func NewAccountHandlers(deps *Deps) *accountHandlers {
+	return &accountHandlers{
+		ResponseHandler: deps.ResponseHandler,
+		AccountSvc:      deps.AccountSvc,
+	}
+}
+
+func (h *accountHandlers) AccountRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", h.ListAccounts)
+	return r
+}
+
+func (h *accountHandlers) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+
+	accounts, err := h.AccountSvc.ListAccounts(r.Context(), uid)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, accounts)
+}
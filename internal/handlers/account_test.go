@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GregMSThompson/finance-backend/internal/models"
+)
+
+type stubAccountService struct {
+	uid      string
+	accounts []*models.Account
+	err      error
+}
+
+func (s *stubAccountService) ListAccounts(ctx context.Context, uid string) ([]*models.Account, error) {
+	s.uid = uid
+	return s.accounts, s.err
+}
+
+func TestListAccountsHandler(t *testing.T) {
+	svc := &stubAccountService{accounts: []*models.Account{{AccountID: "acc-1"}}}
+	resp := &stubResponseHandler{}
+	h := NewAccountHandlers(&Deps{ResponseHandler: resp, AccountSvc: svc})
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts", nil).WithContext(ctxWithUID(context.Background()))
+	rr := httptest.NewRecorder()
+
+	h.ListAccounts(rr, req)
+
+	if svc.uid != "uid-123" {
+		t.Fatalf("service called with uid %q", svc.uid)
+	}
+	if !resp.writeSuccessCalled || resp.writeSuccessStatus != http.StatusOK {
+		t.Fatalf("WriteSuccess not called with status 200")
+	}
+	if accounts, ok := resp.writeSuccessData.([]*models.Account); !ok || len(accounts) != 1 {
+		t.Fatalf("unexpected response data: %#v", resp.writeSuccessData)
+	}
+}
+
+func TestListAccountsHandlerServiceError(t *testing.T) {
+	svc := &stubAccountService{err: errors.New("boom")}
+	resp := &stubResponseHandler{}
+	h := NewAccountHandlers(&Deps{ResponseHandler: resp, AccountSvc: svc})
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts", nil).WithContext(ctxWithUID(context.Background()))
+	rr := httptest.NewRecorder()
+
+	h.ListAccounts(rr, req)
+
+	if !resp.handleErrorCalled {
+		t.Fatalf("expected HandleError to be called")
+	}
+}
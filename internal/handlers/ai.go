@@ -3,6 +3,9 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -10,11 +13,19 @@ import (
 	"github.com/GregMSThompson/finance-backend/internal/dto"
 	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/middleware"
+	"github.com/GregMSThompson/finance-backend/internal/models"
 	"github.com/GregMSThompson/finance-backend/internal/response"
+	"github.com/GregMSThompson/finance-backend/pkg/logger"
 )
 
 type aiService interface {
 	Query(ctx context.Context, uid, sessionID, message string) (dto.AIQueryResponse, error)
+	QueryStream(ctx context.Context, uid, sessionID, message string, emit func(dto.AIStreamEvent) error) error
+	CreateSession(ctx context.Context, uid, title string) (*models.AISession, error)
+	ListSessions(ctx context.Context, uid string, limit int) ([]*models.AISession, error)
+	RenameSession(ctx context.Context, uid, sessionID, title string) (*models.AISession, error)
+	ListSessionMessages(ctx context.Context, uid, sessionID string, limit int, pageToken string) (dto.AIMessagePage, error)
+	DeleteSession(ctx context.Context, uid, sessionID string) error
 }
 
 type aiHandlers struct {
@@ -32,31 +43,207 @@ func NewAIHandlers(deps *Deps) *aiHandlers {
 func (h *aiHandlers) AIRoutes() chi.Router {
 	r := chi.NewRouter()
 	r.Post("/query", h.Query)
+	r.Post("/query/stream", h.QueryStream)
+	r.Route("/sessions", func(r chi.Router) {
+		r.Post("/", h.CreateSession)
+		r.Get("/", h.ListSessions)
+		r.Patch("/{sessionId}", h.RenameSession)
+		r.Delete("/{sessionId}", h.DeleteSession)
+		r.Get("/{sessionId}/messages", h.ListSessionMessages)
+	})
 	return r
 }
 
 func (h *aiHandlers) Query(w http.ResponseWriter, r *http.Request) {
 	// TODO: Add request-scoped timeouts and per-resource timeouts once latency budgets are defined.
+	body, err := decodeAIQueryRequest(r)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	uid := middleware.UID(r.Context())
+	resp, err := h.AISvc.Query(r.Context(), uid, body.SessionID, body.Message)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, resp)
+}
+
+// QueryStream answers like Query but streams progress as server-sent events:
+// tool_call and tool_result for each tool the model runs, text as the answer is
+// written, then done with the full response. Failures before the first event get
+// a normal error response; later ones are sent as an error event.
+func (h *aiHandlers) QueryStream(w http.ResponseWriter, r *http.Request) {
+	body, err := decodeAIQueryRequest(r)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.ResponseHandler.HandleError(w, r, errors.New("response writer does not support streaming"))
+		return
+	}
+
+	started := false
+	emit := func(ev dto.AIStreamEvent) error {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		return writeEvent(w, flusher, ev)
+	}
+
+	uid := middleware.UID(r.Context())
+	err = h.AISvc.QueryStream(r.Context(), uid, body.SessionID, body.Message, emit)
+	if err == nil {
+		return
+	}
+	if !started {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	log := logger.FromContext(r.Context())
+	log.Error("ai query stream failed", "error", err)
+	if err := writeEvent(w, flusher, dto.AIStreamEvent{Type: dto.AIEventError, Data: streamError(err)}); err != nil {
+		log.Warn("failed to write stream error event", "error", err)
+	}
+}
+
+func decodeAIQueryRequest(r *http.Request) (dto.AIQueryRequest, error) {
 	var body dto.AIQueryRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return body, err
+	}
+	if body.Message == "" {
+		return body, errs.NewValidationError("message is required")
+	}
+	if body.SessionID == "" {
+		return body, errs.NewValidationError("sessionId is required")
+	}
+	return body, nil
+}
+
+// writeEvent writes one server-sent event and flushes it to the client.
+func writeEvent(w http.ResponseWriter, flusher http.Flusher, ev dto.AIStreamEvent) error {
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
+// streamError is the client-facing body of an error event. Like HandleError, it
+// only passes validation messages through.
+func streamError(err error) response.ErrorResponse {
+	var validationErr *errs.ValidationError
+	if errors.As(err, &validationErr) {
+		return response.ErrorResponse{Code: "invalid_input", Message: validationErr.Message}
+	}
+	var rateErr *errs.MissingExchangeRateError
+	if errors.As(err, &rateErr) {
+		return response.ErrorResponse{Code: "invalid_input", Message: rateErr.Message}
+	}
+	var extErr *errs.ExternalServiceError
+	if errors.As(err, &extErr) {
+		return response.ErrorResponse{Code: "service_unavailable", Message: "Service temporarily unavailable"}
+	}
+	return response.ErrorResponse{Code: "internal_error", Message: "An error occurred"}
+}
+
+// CreateSession starts a conversation. The title is optional; untitled sessions take
+// their title from the first message.
+func (h *aiHandlers) CreateSession(w http.ResponseWriter, r *http.Request) {
+	var body dto.AISessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		h.ResponseHandler.HandleError(w, r, errs.NewValidationError("invalid request body"))
+		return
+	}
+
+	uid := middleware.UID(r.Context())
+	session, err := h.AISvc.CreateSession(r.Context(), uid, body.Title)
+	if err != nil {
 		h.ResponseHandler.HandleError(w, r, err)
 		return
 	}
-	if body.Message == "" {
-		h.ResponseHandler.HandleError(w, r, errs.NewValidationError("message is required"))
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, session)
+}
+
+// ListSessions returns sessions with their latest message preview, most recently
+// active first.
+func (h *aiHandlers) ListSessions(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r.URL.Query(), "limit")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
 		return
 	}
-	if body.SessionID == "" {
-		h.ResponseHandler.HandleError(w, r, errs.NewValidationError("sessionId is required"))
+
+	uid := middleware.UID(r.Context())
+	sessions, err := h.AISvc.ListSessions(r.Context(), uid, limit)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, sessions)
+}
+
+func (h *aiHandlers) RenameSession(w http.ResponseWriter, r *http.Request) {
+	var body dto.AISessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.ResponseHandler.HandleError(w, r, errs.NewValidationError("invalid request body"))
 		return
 	}
 
 	uid := middleware.UID(r.Context())
-	resp, err := h.AISvc.Query(r.Context(), uid, body.SessionID, body.Message)
+	session, err := h.AISvc.RenameSession(r.Context(), uid, chi.URLParam(r, "sessionId"), body.Title)
 	if err != nil {
 		h.ResponseHandler.HandleError(w, r, err)
 		return
 	}
 
-	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, resp)
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, session)
+}
+
+// ListSessionMessages returns the newest messages of a session; nextPageToken pages
+// back through older ones.
+func (h *aiHandlers) ListSessionMessages(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	limit, err := queryInt(params, "limit")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	uid := middleware.UID(r.Context())
+	page, err := h.AISvc.ListSessionMessages(r.Context(), uid, chi.URLParam(r, "sessionId"), limit, params.Get("pageToken"))
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, page)
+}
+
+func (h *aiHandlers) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+
+	if err := h.AISvc.DeleteSession(r.Context(), uid, chi.URLParam(r, "sessionId")); err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, nil)
 }
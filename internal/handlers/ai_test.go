@@ -9,9 +9,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
+
 	"github.com/GregMSThompson/finance-backend/internal/dto"
 	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/middleware"
+	"github.com/GregMSThompson/finance-backend/internal/models"
 	"github.com/GregMSThompson/finance-backend/pkg/helpers"
 )
 
@@ -22,6 +25,15 @@ type stubAIService struct {
 	message   string
 	resp      dto.AIQueryResponse
 	err       error
+
+	title     string
+	limit     int
+	pageToken string
+	session   *models.AISession
+	page      dto.AIMessagePage
+	deleted   bool
+
+	events []dto.AIStreamEvent
 }
 
 func (s *stubAIService) Query(ctx context.Context, uid, sessionID, message string) (dto.AIQueryResponse, error) {
@@ -32,6 +44,49 @@ func (s *stubAIService) Query(ctx context.Context, uid, sessionID, message strin
 	return s.resp, s.err
 }
 
+// QueryStream emits the configured events, then returns err.
+func (s *stubAIService) QueryStream(ctx context.Context, uid, sessionID, message string, emit func(dto.AIStreamEvent) error) error {
+	s.called = true
+	s.uid, s.sessionID, s.message = uid, sessionID, message
+	for _, ev := range s.events {
+		if err := emit(ev); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func (s *stubAIService) CreateSession(ctx context.Context, uid, title string) (*models.AISession, error) {
+	s.called = true
+	s.uid, s.title = uid, title
+	return s.session, s.err
+}
+
+func (s *stubAIService) ListSessions(ctx context.Context, uid string, limit int) ([]*models.AISession, error) {
+	s.called = true
+	s.uid, s.limit = uid, limit
+	return []*models.AISession{s.session}, s.err
+}
+
+func (s *stubAIService) RenameSession(ctx context.Context, uid, sessionID, title string) (*models.AISession, error) {
+	s.called = true
+	s.uid, s.sessionID, s.title = uid, sessionID, title
+	return s.session, s.err
+}
+
+func (s *stubAIService) ListSessionMessages(ctx context.Context, uid, sessionID string, limit int, pageToken string) (dto.AIMessagePage, error) {
+	s.called = true
+	s.uid, s.sessionID, s.limit, s.pageToken = uid, sessionID, limit, pageToken
+	return s.page, s.err
+}
+
+func (s *stubAIService) DeleteSession(ctx context.Context, uid, sessionID string) error {
+	s.called = true
+	s.deleted = true
+	s.uid, s.sessionID = uid, sessionID
+	return s.err
+}
+
 type aiStubResponseHandler struct {
 	writeSuccessCalled bool
 	writeSuccessStatus int
@@ -166,3 +221,157 @@ func TestAIQueryHandlerServiceError(t *testing.T) {
 		t.Fatalf("expected HandleError to be called")
 	}
 }
+
+func withSessionID(r *http.Request, sessionID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("sessionId", sessionID)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	return r.WithContext(context.WithValue(ctx, middleware.UIDKey, "uid-123"))
+}
+
+func TestCreateSessionAllowsEmptyBody(t *testing.T) {
+	aiSvc := &stubAIService{session: &models.AISession{SessionID: "s1"}}
+	resp := &aiStubResponseHandler{}
+	h := NewAIHandlers(&Deps{ResponseHandler: resp, AISvc: aiSvc})
+
+	req := httptest.NewRequest(http.MethodPost, "/ai/sessions", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UIDKey, "uid-123"))
+	rr := httptest.NewRecorder()
+
+	h.CreateSession(rr, req)
+
+	if !aiSvc.called || aiSvc.uid != "uid-123" || aiSvc.title != "" {
+		t.Fatalf("unexpected service call: %+v", aiSvc)
+	}
+	if !resp.writeSuccessCalled || resp.writeSuccessData != aiSvc.session {
+		t.Fatalf("expected session to be written")
+	}
+}
+
+func TestListSessionMessagesPassesPaging(t *testing.T) {
+	aiSvc := &stubAIService{page: dto.AIMessagePage{NextPageToken: "next"}}
+	resp := &aiStubResponseHandler{}
+	h := NewAIHandlers(&Deps{ResponseHandler: resp, AISvc: aiSvc})
+
+	req := httptest.NewRequest(http.MethodGet, "/ai/sessions/s1/messages?limit=10&pageToken=abc", nil)
+	req = withSessionID(req, "s1")
+	rr := httptest.NewRecorder()
+
+	h.ListSessionMessages(rr, req)
+
+	if aiSvc.sessionID != "s1" || aiSvc.limit != 10 || aiSvc.pageToken != "abc" {
+		t.Fatalf("unexpected service call: %+v", aiSvc)
+	}
+	if page, ok := resp.writeSuccessData.(dto.AIMessagePage); !ok || page.NextPageToken != "next" {
+		t.Fatalf("unexpected response data: %+v", resp.writeSuccessData)
+	}
+}
+
+func TestListSessionMessagesInvalidLimit(t *testing.T) {
+	aiSvc := &stubAIService{}
+	resp := &aiStubResponseHandler{}
+	h := NewAIHandlers(&Deps{ResponseHandler: resp, AISvc: aiSvc})
+
+	req := httptest.NewRequest(http.MethodGet, "/ai/sessions/s1/messages?limit=abc", nil)
+	req = withSessionID(req, "s1")
+	rr := httptest.NewRecorder()
+
+	h.ListSessionMessages(rr, req)
+
+	if aiSvc.called {
+		t.Fatalf("service should not be called with an invalid limit")
+	}
+	var vErr *errs.ValidationError
+	if !errors.As(resp.handleError, &vErr) {
+		t.Fatalf("expected validation error, got %v", resp.handleError)
+	}
+}
+
+func TestDeleteSessionUsesPathID(t *testing.T) {
+	aiSvc := &stubAIService{}
+	resp := &aiStubResponseHandler{}
+	h := NewAIHandlers(&Deps{ResponseHandler: resp, AISvc: aiSvc})
+
+	req := httptest.NewRequest(http.MethodDelete, "/ai/sessions/s1", nil)
+	req = withSessionID(req, "s1")
+	rr := httptest.NewRecorder()
+
+	h.DeleteSession(rr, req)
+
+	if !aiSvc.deleted || aiSvc.uid != "uid-123" || aiSvc.sessionID != "s1" {
+		t.Fatalf("unexpected service call: %+v", aiSvc)
+	}
+	if !resp.writeSuccessCalled {
+		t.Fatalf("expected WriteSuccess")
+	}
+}
+
+func TestAIQueryStreamWritesEvents(t *testing.T) {
+	aiSvc := &stubAIService{events: []dto.AIStreamEvent{
+		{Type: dto.AIEventToolCall, Data: dto.AIStreamToolCall{Step: 1, Tool: "get_spend_total"}},
+		{Type: dto.AIEventText, Data: dto.AIStreamText{Delta: "You spent "}},
+		{Type: dto.AIEventDone, Data: dto.AIQueryResponse{Answer: "You spent $5."}},
+	}}
+	resp := &aiStubResponseHandler{}
+	h := NewAIHandlers(&Deps{ResponseHandler: resp, AISvc: aiSvc})
+
+	body := `{"sessionId":"s1","message":"hello"}`
+	req := httptest.NewRequest(http.MethodPost, "/ai/query/stream", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(helpers.TestCtx(), middleware.UIDKey, "uid-123"))
+	rr := httptest.NewRecorder()
+
+	h.QueryStream(rr, req)
+
+	if aiSvc.uid != "uid-123" || aiSvc.sessionID != "s1" || aiSvc.message != "hello" {
+		t.Fatalf("unexpected service args: %+v", aiSvc)
+	}
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response: %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if !rr.Flushed {
+		t.Fatalf("expected events to be flushed")
+	}
+	want := "event: tool_call\ndata: {\"step\":1,\"tool\":\"get_spend_total\",\"args\":null}\n\n" +
+		"event: text\ndata: {\"delta\":\"You spent \"}\n\n" +
+		"event: done\ndata: {\"answer\":\"You spent $5.\"}\n\n"
+	if rr.Body.String() != want {
+		t.Fatalf("unexpected stream:\n%s", rr.Body.String())
+	}
+}
+
+func TestAIQueryStreamErrorBeforeFirstEvent(t *testing.T) {
+	aiSvc := &stubAIService{err: errs.NewValidationError("session expired")}
+	resp := &aiStubResponseHandler{}
+	h := NewAIHandlers(&Deps{ResponseHandler: resp, AISvc: aiSvc})
+
+	req := httptest.NewRequest(http.MethodPost, "/ai/query/stream", strings.NewReader(`{"sessionId":"s1","message":"hello"}`))
+	rr := httptest.NewRecorder()
+
+	h.QueryStream(rr, req)
+
+	if !resp.handleErrorCalled || resp.handleError != aiSvc.err {
+		t.Fatalf("expected HandleError with the service error, got %v", resp.handleError)
+	}
+}
+
+func TestAIQueryStreamErrorAfterStartWritesErrorEvent(t *testing.T) {
+	aiSvc := &stubAIService{
+		events: []dto.AIStreamEvent{{Type: dto.AIEventText, Data: dto.AIStreamText{Delta: "You"}}},
+		err:    errs.NewExternalServiceError("vertex", "content stream interrupted", false, nil),
+	}
+	resp := &aiStubResponseHandler{}
+	h := NewAIHandlers(&Deps{ResponseHandler: resp, AISvc: aiSvc})
+
+	req := httptest.NewRequest(http.MethodPost, "/ai/query/stream", strings.NewReader(`{"sessionId":"s1","message":"hello"}`))
+	rr := httptest.NewRecorder()
+
+	h.QueryStream(rr, req)
+
+	if resp.handleErrorCalled {
+		t.Fatalf("expected no HandleError once streaming has started")
+	}
+	want := "event: error\ndata: {\"code\":\"service_unavailable\",\"message\":\"Service temporarily unavailable\"}\n\n"
+	if !strings.HasSuffix(rr.Body.String(), want) {
+		t.Fatalf("expected trailing error event, got:\n%s", rr.Body.String())
+	}
+}
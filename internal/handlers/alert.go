@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/middleware"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/response"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type alertService interface {
+	CreateRule(ctx context.Context, uid string, in dto.AlertRuleInput) (*models.AlertRule, error)
+	ListRules(ctx context.Context, uid string) ([]*models.AlertRule, error)
+	DeleteRule(ctx context.Context, uid, ruleID string) error
+	ListAlerts(ctx context.Context, uid string, unacknowledgedOnly bool, limit int) ([]*models.Alert, error)
+	AcknowledgeAlert(ctx context.Context, uid, alertID string) error
+}
+
+type alertHandlers struct {
+	ResponseHandler response.ResponseHandler
+	AlertSvc        alertService
+}
+
+func NewAlertHandlers(deps *Deps) *alertHandlers {
+	return &alertHandlers{
+		ResponseHandler: deps.ResponseHandler,
+		AlertSvc:        deps.AlertSvc,
+	}
+}
+
+func (h *alertHandlers) AlertRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", h.ListAlerts)
+	r.Post("/{alertId}/acknowledge", h.AcknowledgeAlert)
+	r.Route("/rules", func(r chi.Router) {
+		r.Post("/", h.CreateRule)
+		r.Get("/", h.ListRules)
+		r.Delete("/{ruleId}", h.DeleteRule)
+	})
+	return r
+}
+
+// ListAlerts returns the newest alerts first. ?unacknowledged=true hides alerts the
+// user has already seen.
+func (h *alertHandlers) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	unacknowledged, err := queryBool(params, "unacknowledged")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+	limit, err := queryInt(params, "limit")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	uid := middleware.UID(r.Context())
+	alerts, err := h.AlertSvc.ListAlerts(r.Context(), uid, helpers.Value(unacknowledged), limit)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, alerts)
+}
+
+func (h *alertHandlers) AcknowledgeAlert(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+	alertID := chi.URLParam(r, "alertId")
+
+	if err := h.AlertSvc.AcknowledgeAlert(r.Context(), uid, alertID); err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, nil)
+}
+
+func (h *alertHandlers) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var body dto.AlertRuleInput
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.ResponseHandler.HandleError(w, r, errs.NewValidationError("invalid request body"))
+		return
+	}
+
+	uid := middleware.UID(r.Context())
+	rule, err := h.AlertSvc.CreateRule(r.Context(), uid, body)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, rule)
+}
+
+func (h *alertHandlers) ListRules(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+
+	rules, err := h.AlertSvc.ListRules(r.Context(), uid)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, rules)
+}
+
+func (h *alertHandlers) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+	ruleID := chi.URLParam(r, "ruleId")
+
+	if err := h.AlertSvc.DeleteRule(r.Context(), uid, ruleID); err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, nil)
+}
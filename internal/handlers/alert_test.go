@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+)
+
+type stubAlertService struct {
+	uid                string
+	called             string
+	id                 string
+	input              dto.AlertRuleInput
+	unacknowledgedOnly bool
+	limit              int
+	err                error
+}
+
+func (s *stubAlertService) CreateRule(ctx context.Context, uid string, in dto.AlertRuleInput) (*models.AlertRule, error) {
+	s.uid, s.input, s.called = uid, in, "createRule"
+	return &models.AlertRule{RuleID: "r1"}, s.err
+}
+
+func (s *stubAlertService) ListRules(ctx context.Context, uid string) ([]*models.AlertRule, error) {
+	s.uid, s.called = uid, "listRules"
+	return []*models.AlertRule{}, s.err
+}
+
+func (s *stubAlertService) DeleteRule(ctx context.Context, uid, ruleID string) error {
+	s.uid, s.id, s.called = uid, ruleID, "deleteRule"
+	return s.err
+}
+
+func (s *stubAlertService) ListAlerts(ctx context.Context, uid string, unacknowledgedOnly bool, limit int) ([]*models.Alert, error) {
+	s.uid, s.unacknowledgedOnly, s.limit, s.called = uid, unacknowledgedOnly, limit, "listAlerts"
+	return []*models.Alert{}, s.err
+}
+
+func (s *stubAlertService) AcknowledgeAlert(ctx context.Context, uid, alertID string) error {
+	s.uid, s.id, s.called = uid, alertID, "acknowledge"
+	return s.err
+}
+
+func serveAlerts(svc *stubAlertService, method, url string, body io.Reader) *stubResponseHandler {
+	resp := &stubResponseHandler{}
+	h := NewAlertHandlers(&Deps{ResponseHandler: resp, AlertSvc: svc})
+	req := httptest.NewRequest(method, url, body).WithContext(ctxWithUID(context.Background()))
+	h.AlertRoutes().ServeHTTP(httptest.NewRecorder(), req)
+	return resp
+}
+
+func TestListAlertsHandler(t *testing.T) {
+	svc := &stubAlertService{}
+	resp := serveAlerts(svc, http.MethodGet, "/?unacknowledged=true&limit=10", nil)
+
+	if !resp.writeSuccessCalled || resp.writeSuccessStatus != http.StatusOK {
+		t.Fatalf("WriteSuccess not called with status 200")
+	}
+	if svc.uid != "uid-123" || !svc.unacknowledgedOnly || svc.limit != 10 {
+		t.Fatalf("unexpected service call: %+v", svc)
+	}
+}
+
+func TestListAlertsHandlerInvalidParam(t *testing.T) {
+	svc := &stubAlertService{}
+	resp := serveAlerts(svc, http.MethodGet, "/?unacknowledged=maybe", nil)
+
+	var ve *errs.ValidationError
+	if !resp.handleErrorCalled || !errors.As(resp.handleError, &ve) {
+		t.Fatalf("expected validation error, got %v", resp.handleError)
+	}
+	if svc.called != "" {
+		t.Fatalf("service should not be called")
+	}
+}
+
+func TestAlertRoutes(t *testing.T) {
+	cases := []struct {
+		method string
+		url    string
+		body   string
+		called string
+		id     string
+	}{
+		{http.MethodPost, "/a1/acknowledge", "", "acknowledge", "a1"},
+		{http.MethodGet, "/rules", "", "listRules", ""},
+		{http.MethodPost, "/rules", `{"pfcPrimary":"TRAVEL","newMerchant":true}`, "createRule", ""},
+		{http.MethodDelete, "/rules/r1", "", "deleteRule", "r1"},
+	}
+	for _, tc := range cases {
+		svc := &stubAlertService{}
+		resp := serveAlerts(svc, tc.method, tc.url, strings.NewReader(tc.body))
+		if !resp.writeSuccessCalled {
+			t.Fatalf("%s %s: WriteSuccess not called", tc.method, tc.url)
+		}
+		if svc.called != tc.called || svc.id != tc.id || svc.uid != "uid-123" {
+			t.Fatalf("%s %s: unexpected service call %q id %q", tc.method, tc.url, svc.called, svc.id)
+		}
+	}
+}
+
+func TestCreateAlertRuleHandlerDecodesBody(t *testing.T) {
+	svc := &stubAlertService{}
+	serveAlerts(svc, http.MethodPost, "/rules", strings.NewReader(`{"name":"Big","minAmount":500,"currency":"USD"}`))
+
+	if svc.input.Name != "Big" || svc.input.MinAmount == nil || *svc.input.MinAmount != 500 || svc.input.Currency != "USD" {
+		t.Fatalf("unexpected input: %+v", svc.input)
+	}
+}
+
+func TestAcknowledgeAlertHandlerServiceError(t *testing.T) {
+	svc := &stubAlertService{err: errs.NewNotFoundError("alert not found")}
+	resp := serveAlerts(svc, http.MethodPost, "/missing/acknowledge", nil)
+
+	if !resp.handleErrorCalled {
+		t.Fatalf("expected HandleError to be called")
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/middleware"
+	"github.com/GregMSThompson/finance-backend/internal/response"
+	"github.com/GregMSThompson/finance-backend/internal/taxonomy"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type analyticsService interface {
+	GetSpendTotal(ctx context.Context, uid string, args dto.AnalyticsSpendTotalArgs) (dto.AnalyticsSpendTotalResult, error)
+	GetSpendBreakdown(ctx context.Context, uid string, args dto.AnalyticsSpendBreakdownArgs) (dto.AnalyticsSpendBreakdownResult, error)
+	GetPeriodComparison(ctx context.Context, uid string, args dto.AnalyticsPeriodComparisonArgs) (dto.AnalyticsPeriodComparisonResult, error)
+	GetRecurringTransactions(ctx context.Context, uid string, args dto.AnalyticsRecurringArgs) (dto.RecurringTransactionsResult, error)
+}
+
+type analyticsHandlers struct {
+	ResponseHandler response.ResponseHandler
+	AnalyticsSvc    analyticsService
+}
+
+func NewAnalyticsHandlers(deps *Deps) *analyticsHandlers {
+	return &analyticsHandlers{
+		ResponseHandler: deps.ResponseHandler,
+		AnalyticsSvc:    deps.AnalyticsSvc,
+	}
+}
+
+func (h *analyticsHandlers) AnalyticsRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/spend-total", h.SpendTotal)
+	r.Get("/breakdown", h.Breakdown)
+	r.Get("/compare", h.Compare)
+	r.Get("/recurring", h.Recurring)
+	return r
+}
+
+// analyticsFilters are the transaction filters shared by the analytics endpoints.
+type analyticsFilters struct {
+	pending          *bool
+	pfcPrimary       *string
+	bankID           *string
+	merchant         *string
+	baseCurrency     *string
+	direction        string
+	includeTransfers bool
+}
+
+// parseAnalyticsFilters reads the shared filters. Pending defaults to false so the
+// numbers match what the AI tools report for the same question.
+func parseAnalyticsFilters(params url.Values) (analyticsFilters, error) {
+	pending, err := queryBool(params, "pending")
+	if err != nil {
+		return analyticsFilters{}, err
+	}
+	if pending == nil {
+		pending = helpers.Ptr(false)
+	}
+	includeTransfers, err := queryBool(params, "includeTransfers")
+	if err != nil {
+		return analyticsFilters{}, err
+	}
+
+	primary := queryString(params, "pfcPrimary")
+	if primary != nil && !taxonomy.IsPFCPrimaryAllowed(*primary) {
+		return analyticsFilters{}, errs.NewValidationError(fmt.Sprintf("invalid pfcPrimary: %s", *primary))
+	}
+
+	return analyticsFilters{
+		pending:          pending,
+		pfcPrimary:       primary,
+		bankID:           queryString(params, "bankId"),
+		merchant:         queryString(params, "merchant"),
+		baseCurrency:     queryString(params, "baseCurrency"),
+		direction:        params.Get("direction"),
+		includeTransfers: helpers.Value(includeTransfers),
+	}, nil
+}
+
+func (h *analyticsHandlers) SpendTotal(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	filters, err := parseAnalyticsFilters(params)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+	dateFrom, err := queryDate(params, "dateFrom")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+	dateTo, err := queryDate(params, "dateTo")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	uid := middleware.UID(r.Context())
+	result, err := h.AnalyticsSvc.GetSpendTotal(r.Context(), uid, dto.AnalyticsSpendTotalArgs{
+		Pending:          filters.pending,
+		PFCPrimary:       filters.pfcPrimary,
+		BankID:           filters.bankID,
+		Merchant:         filters.merchant,
+		DateFrom:         dateFrom,
+		DateTo:           dateTo,
+		BaseCurrency:     filters.baseCurrency,
+		Direction:        filters.direction,
+		IncludeTransfers: filters.includeTransfers,
+	})
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, result)
+}
+
+func (h *analyticsHandlers) Breakdown(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	groupBy := params.Get("groupBy")
+	if groupBy == "" {
+		h.ResponseHandler.HandleError(w, r, errs.NewValidationError("groupBy is required"))
+		return
+	}
+	filters, err := parseAnalyticsFilters(params)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+	dateFrom, err := queryDate(params, "dateFrom")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+	dateTo, err := queryDate(params, "dateTo")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	// groupBy values are checked by the service, which rejects unsupported groupings.
+	uid := middleware.UID(r.Context())
+	result, err := h.AnalyticsSvc.GetSpendBreakdown(r.Context(), uid, dto.AnalyticsSpendBreakdownArgs{
+		Pending:          filters.pending,
+		PFCPrimary:       filters.pfcPrimary,
+		BankID:           filters.bankID,
+		Merchant:         filters.merchant,
+		DateFrom:         dateFrom,
+		DateTo:           dateTo,
+		GroupBy:          groupBy,
+		BaseCurrency:     filters.baseCurrency,
+		Direction:        filters.direction,
+		IncludeTransfers: filters.includeTransfers,
+	})
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, result)
+}
+
+func (h *analyticsHandlers) Compare(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	filters, err := parseAnalyticsFilters(params)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	args := dto.AnalyticsPeriodComparisonArgs{
+		Pending:          filters.pending,
+		PFCPrimary:       filters.pfcPrimary,
+		BankID:           filters.bankID,
+		Merchant:         filters.merchant,
+		GroupBy:          params.Get("groupBy"),
+		BaseCurrency:     filters.baseCurrency,
+		Direction:        filters.direction,
+		IncludeTransfers: filters.includeTransfers,
+	}
+	for _, p := range []struct {
+		key string
+		dst *string
+	}{
+		{"currentFrom", &args.CurrentFrom},
+		{"currentTo", &args.CurrentTo},
+		{"previousFrom", &args.PreviousFrom},
+		{"previousTo", &args.PreviousTo},
+	} {
+		if *p.dst, err = requiredQueryDate(params, p.key); err != nil {
+			h.ResponseHandler.HandleError(w, r, err)
+			return
+		}
+	}
+
+	uid := middleware.UID(r.Context())
+	result, err := h.AnalyticsSvc.GetPeriodComparison(r.Context(), uid, args)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, result)
+}
+
+func (h *analyticsHandlers) Recurring(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	dateFrom, err := requiredQueryDate(params, "dateFrom")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+	dateTo, err := requiredQueryDate(params, "dateTo")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	uid := middleware.UID(r.Context())
+	result, err := h.AnalyticsSvc.GetRecurringTransactions(r.Context(), uid, dto.AnalyticsRecurringArgs{
+		BankID:       queryString(params, "bankId"),
+		DateFrom:     dateFrom,
+		DateTo:       dateTo,
+		BaseCurrency: queryString(params, "baseCurrency"),
+	})
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, result)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/money"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type stubAnalyticsService struct {
+	called        bool
+	totalArgs     dto.AnalyticsSpendTotalArgs
+	breakdownArgs dto.AnalyticsSpendBreakdownArgs
+	compareArgs   dto.AnalyticsPeriodComparisonArgs
+	recurringArgs dto.AnalyticsRecurringArgs
+	err           error
+}
+
+func (s *stubAnalyticsService) GetSpendTotal(ctx context.Context, uid string, args dto.AnalyticsSpendTotalArgs) (dto.AnalyticsSpendTotalResult, error) {
+	s.called = true
+	s.totalArgs = args
+	return dto.AnalyticsSpendTotalResult{Total: money.New(4200, "USD")}, s.err
+}
+
+func (s *stubAnalyticsService) GetSpendBreakdown(ctx context.Context, uid string, args dto.AnalyticsSpendBreakdownArgs) (dto.AnalyticsSpendBreakdownResult, error) {
+	s.called = true
+	s.breakdownArgs = args
+	return dto.AnalyticsSpendBreakdownResult{}, s.err
+}
+
+func (s *stubAnalyticsService) GetPeriodComparison(ctx context.Context, uid string, args dto.AnalyticsPeriodComparisonArgs) (dto.AnalyticsPeriodComparisonResult, error) {
+	s.called = true
+	s.compareArgs = args
+	return dto.AnalyticsPeriodComparisonResult{}, s.err
+}
+
+func (s *stubAnalyticsService) GetRecurringTransactions(ctx context.Context, uid string, args dto.AnalyticsRecurringArgs) (dto.RecurringTransactionsResult, error) {
+	s.called = true
+	s.recurringArgs = args
+	return dto.RecurringTransactionsResult{}, s.err
+}
+
+func serveAnalytics(svc *stubAnalyticsService, url string) *stubResponseHandler {
+	resp := &stubResponseHandler{}
+	h := NewAnalyticsHandlers(&Deps{ResponseHandler: resp, AnalyticsSvc: svc})
+	req := httptest.NewRequest(http.MethodGet, url, nil).WithContext(ctxWithUID(context.Background()))
+	h.AnalyticsRoutes().ServeHTTP(httptest.NewRecorder(), req)
+	return resp
+}
+
+func TestSpendTotalHandler(t *testing.T) {
+	svc := &stubAnalyticsService{}
+	resp := serveAnalytics(svc, "/spend-total?pfcPrimary=DINING&bankId=b1&merchant=cafe&dateFrom=2025-01-01&dateTo=2025-01-31&direction=inflow&includeTransfers=true")
+
+	if !resp.writeSuccessCalled || resp.writeSuccessStatus != http.StatusOK {
+		t.Fatalf("WriteSuccess not called with status 200")
+	}
+	args := svc.totalArgs
+	if args.Pending == nil || *args.Pending {
+		t.Fatalf("expected pending to default to false, got %v", args.Pending)
+	}
+	if helpers.Value(args.PFCPrimary) != "DINING" || helpers.Value(args.BankID) != "b1" || helpers.Value(args.Merchant) != "cafe" ||
+		helpers.Value(args.DateFrom) != "2025-01-01" || helpers.Value(args.DateTo) != "2025-01-31" {
+		t.Fatalf("unexpected args: %+v", args)
+	}
+	if args.Direction != "inflow" || !args.IncludeTransfers {
+		t.Fatalf("unexpected flow args: direction=%q includeTransfers=%v", args.Direction, args.IncludeTransfers)
+	}
+}
+
+func TestBreakdownHandlerPassesGroupBy(t *testing.T) {
+	svc := &stubAnalyticsService{}
+	resp := serveAnalytics(svc, "/breakdown?groupBy=pfcPrimary&pending=true&merchant=cafe")
+
+	if !resp.writeSuccessCalled {
+		t.Fatalf("expected WriteSuccess to be called")
+	}
+	if svc.breakdownArgs.GroupBy != "pfcPrimary" || !helpers.Value(svc.breakdownArgs.Pending) || helpers.Value(svc.breakdownArgs.Merchant) != "cafe" {
+		t.Fatalf("unexpected args: %+v", svc.breakdownArgs)
+	}
+}
+
+func TestCompareHandlerParsesPeriods(t *testing.T) {
+	svc := &stubAnalyticsService{}
+	resp := serveAnalytics(svc, "/compare?currentFrom=2025-02-01&currentTo=2025-02-28&previousFrom=2025-01-01&previousTo=2025-01-31&groupBy=pfcPrimary")
+
+	if !resp.writeSuccessCalled {
+		t.Fatalf("expected WriteSuccess to be called")
+	}
+	args := svc.compareArgs
+	if args.CurrentFrom != "2025-02-01" || args.CurrentTo != "2025-02-28" || args.PreviousFrom != "2025-01-01" ||
+		args.PreviousTo != "2025-01-31" || args.GroupBy != "pfcPrimary" {
+		t.Fatalf("unexpected args: %+v", args)
+	}
+}
+
+func TestRecurringHandler(t *testing.T) {
+	svc := &stubAnalyticsService{}
+	resp := serveAnalytics(svc, "/recurring?dateFrom=2025-01-01&dateTo=2025-06-30&bankId=b1")
+
+	if !resp.writeSuccessCalled {
+		t.Fatalf("expected WriteSuccess to be called")
+	}
+	if svc.recurringArgs.DateFrom != "2025-01-01" || svc.recurringArgs.DateTo != "2025-06-30" || helpers.Value(svc.recurringArgs.BankID) != "b1" {
+		t.Fatalf("unexpected args: %+v", svc.recurringArgs)
+	}
+}
+
+func TestAnalyticsHandlersRejectInvalidParams(t *testing.T) {
+	urls := []string{
+		"/spend-total?pfcPrimary=NOT_A_CATEGORY",
+		"/spend-total?dateFrom=01-02-2025",
+		"/spend-total?pending=maybe",
+		"/spend-total?includeTransfers=sometimes",
+		"/breakdown",
+		"/compare?currentFrom=2025-02-01&currentTo=2025-02-28&previousFrom=2025-01-01",
+		"/recurring?dateFrom=2025-01-01",
+	}
+	for _, url := range urls {
+		svc := &stubAnalyticsService{}
+		resp := serveAnalytics(svc, url)
+
+		if !resp.handleErrorCalled {
+			t.Fatalf("%s: expected HandleError to be called", url)
+		}
+		if svc.called {
+			t.Fatalf("%s: service should not be called", url)
+		}
+	}
+}
+
+func TestAnalyticsHandlersServiceError(t *testing.T) {
+	svc := &stubAnalyticsService{err: errors.New("boom")}
+	resp := serveAnalytics(svc, "/breakdown?groupBy=weekday")
+
+	if !resp.handleErrorCalled {
+		t.Fatalf("expected HandleError to be called")
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/middleware"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/response"
+)
+
+type budgetService interface {
+	CreateBudget(ctx context.Context, uid string, in dto.BudgetInput) (*models.Budget, error)
+	ListBudgets(ctx context.Context, uid string) ([]*models.Budget, error)
+	GetBudget(ctx context.Context, uid, budgetID string) (*models.Budget, error)
+	UpdateBudget(ctx context.Context, uid, budgetID string, in dto.BudgetInput) (*models.Budget, error)
+	DeleteBudget(ctx context.Context, uid, budgetID string) error
+	BudgetStatus(ctx context.Context, uid string) ([]dto.BudgetStatus, error)
+}
+
+type budgetHandlers struct {
+	ResponseHandler response.ResponseHandler
+	BudgetSvc       budgetService
+}
+
+func NewBudgetHandlers(deps *Deps) *budgetHandlers {
+	return &budgetHandlers{
+		ResponseHandler: deps.ResponseHandler,
+		BudgetSvc:       deps.BudgetSvc,
+	}
+}
+
+func (h *budgetHandlers) BudgetRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/", h.CreateBudget)
+	r.Get("/", h.ListBudgets)
+	r.Get("/status", h.BudgetStatus)
+	r.Route("/{budgetId}", func(r chi.Router) {
+		r.Get("/", h.GetBudget)
+		r.Put("/", h.UpdateBudget)
+		r.Delete("/", h.DeleteBudget)
+	})
+	return r
+}
+
+func (h *budgetHandlers) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	var body dto.BudgetInput
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.ResponseHandler.HandleError(w, r, errs.NewValidationError("invalid request body"))
+		return
+	}
+
+	uid := middleware.UID(r.Context())
+	budget, err := h.BudgetSvc.CreateBudget(r.Context(), uid, body)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, budget)
+}
+
+func (h *budgetHandlers) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+
+	budgets, err := h.BudgetSvc.ListBudgets(r.Context(), uid)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, budgets)
+}
+
+func (h *budgetHandlers) GetBudget(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+	budgetID := chi.URLParam(r, "budgetId")
+
+	budget, err := h.BudgetSvc.GetBudget(r.Context(), uid, budgetID)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, budget)
+}
+
+func (h *budgetHandlers) UpdateBudget(w http.ResponseWriter, r *http.Request) {
+	var body dto.BudgetInput
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.ResponseHandler.HandleError(w, r, errs.NewValidationError("invalid request body"))
+		return
+	}
+
+	uid := middleware.UID(r.Context())
+	budgetID := chi.URLParam(r, "budgetId")
+	budget, err := h.BudgetSvc.UpdateBudget(r.Context(), uid, budgetID, body)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, budget)
+}
+
+func (h *budgetHandlers) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+	budgetID := chi.URLParam(r, "budgetId")
+
+	if err := h.BudgetSvc.DeleteBudget(r.Context(), uid, budgetID); err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, nil)
+}
+
+// BudgetStatus reports spent, remaining and projected spend for every budget in its
+// current period.
+func (h *budgetHandlers) BudgetStatus(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+
+	statuses, err := h.BudgetSvc.BudgetStatus(r.Context(), uid)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, statuses)
+}
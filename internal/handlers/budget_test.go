@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+)
+
+type stubBudgetService struct {
+	uid      string
+	budgetID string
+	input    dto.BudgetInput
+	called   string
+	err      error
+}
+
+func (s *stubBudgetService) CreateBudget(ctx context.Context, uid string, in dto.BudgetInput) (*models.Budget, error) {
+	s.uid, s.input, s.called = uid, in, "create"
+	return &models.Budget{BudgetID: "b1"}, s.err
+}
+
+func (s *stubBudgetService) ListBudgets(ctx context.Context, uid string) ([]*models.Budget, error) {
+	s.uid, s.called = uid, "list"
+	return []*models.Budget{{BudgetID: "b1"}}, s.err
+}
+
+func (s *stubBudgetService) GetBudget(ctx context.Context, uid, budgetID string) (*models.Budget, error) {
+	s.uid, s.budgetID, s.called = uid, budgetID, "get"
+	return &models.Budget{BudgetID: budgetID}, s.err
+}
+
+func (s *stubBudgetService) UpdateBudget(ctx context.Context, uid, budgetID string, in dto.BudgetInput) (*models.Budget, error) {
+	s.uid, s.budgetID, s.input, s.called = uid, budgetID, in, "update"
+	return &models.Budget{BudgetID: budgetID}, s.err
+}
+
+func (s *stubBudgetService) DeleteBudget(ctx context.Context, uid, budgetID string) error {
+	s.uid, s.budgetID, s.called = uid, budgetID, "delete"
+	return s.err
+}
+
+func (s *stubBudgetService) BudgetStatus(ctx context.Context, uid string) ([]dto.BudgetStatus, error) {
+	s.uid, s.called = uid, "status"
+	return []dto.BudgetStatus{{PeriodStart: "2025-04-01"}}, s.err
+}
+
+func serveBudgets(svc *stubBudgetService, method, url string, body io.Reader) *stubResponseHandler {
+	resp := &stubResponseHandler{}
+	h := NewBudgetHandlers(&Deps{ResponseHandler: resp, BudgetSvc: svc})
+	req := httptest.NewRequest(method, url, body).WithContext(ctxWithUID(context.Background()))
+	h.BudgetRoutes().ServeHTTP(httptest.NewRecorder(), req)
+	return resp
+}
+
+func TestCreateBudgetHandler(t *testing.T) {
+	svc := &stubBudgetService{}
+	body := `{"name":"Food","pfcPrimary":"DINING","period":"monthly","amount":250.5,"currency":"USD"}`
+	resp := serveBudgets(svc, http.MethodPost, "/", strings.NewReader(body))
+
+	if !resp.writeSuccessCalled || resp.writeSuccessStatus != http.StatusOK {
+		t.Fatalf("WriteSuccess not called with status 200")
+	}
+	want := dto.BudgetInput{Name: "Food", PFCPrimary: "DINING", Period: "monthly", Amount: 250.5, Currency: "USD"}
+	if svc.uid != "uid-123" || svc.input != want {
+		t.Fatalf("unexpected service call: uid %q input %+v", svc.uid, svc.input)
+	}
+}
+
+func TestCreateBudgetHandlerInvalidBody(t *testing.T) {
+	svc := &stubBudgetService{}
+	resp := serveBudgets(svc, http.MethodPost, "/", strings.NewReader("{"))
+
+	var ve *errs.ValidationError
+	if !resp.handleErrorCalled || !errors.As(resp.handleError, &ve) {
+		t.Fatalf("expected validation error, got %v", resp.handleError)
+	}
+	if svc.called != "" {
+		t.Fatalf("service should not be called")
+	}
+}
+
+func TestBudgetRoutes(t *testing.T) {
+	cases := []struct {
+		method   string
+		url      string
+		body     string
+		called   string
+		budgetID string
+	}{
+		{http.MethodGet, "/", "", "list", ""},
+		{http.MethodGet, "/status", "", "status", ""},
+		{http.MethodGet, "/b1", "", "get", "b1"},
+		{http.MethodPut, "/b1", `{"period":"weekly","amount":40,"currency":"EUR"}`, "update", "b1"},
+		{http.MethodDelete, "/b1", "", "delete", "b1"},
+	}
+	for _, tc := range cases {
+		svc := &stubBudgetService{}
+		resp := serveBudgets(svc, tc.method, tc.url, strings.NewReader(tc.body))
+		if !resp.writeSuccessCalled {
+			t.Fatalf("%s %s: WriteSuccess not called", tc.method, tc.url)
+		}
+		if svc.called != tc.called || svc.budgetID != tc.budgetID || svc.uid != "uid-123" {
+			t.Fatalf("%s %s: unexpected service call %q budget %q uid %q", tc.method, tc.url, svc.called, svc.budgetID, svc.uid)
+		}
+	}
+}
+
+func TestBudgetStatusHandlerServiceError(t *testing.T) {
+	svc := &stubBudgetService{err: errors.New("boom")}
+	resp := serveBudgets(svc, http.MethodGet, "/status", nil)
+
+	if !resp.handleErrorCalled {
+		t.Fatalf("expected HandleError to be called")
+	}
+}
@@ -15,5 +15,12 @@ type Deps struct {
 	UserSvc         userService
 	PlaidSvc        plaidService
 	BankSvc         bankService
+	AccountSvc      accountService
+	TransactionSvc  transactionService
+	AnalyticsSvc    analyticsService
+	BudgetSvc       budgetService
+	AlertSvc        alertService
+	NotificationSvc notificationService
 	AISvc           aiService
+	WebhookSvc      webhookService
 }
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+)
+
+// queryString returns a pointer to the named query parameter, or nil when it is absent or empty.
+func queryString(q url.Values, key string) *string {
+	if v := q.Get(key); v != "" {
+		return &v
+	}
+	return nil
+}
+
+// queryBool parses an optional boolean query parameter.
+func queryBool(q url.Values, key string) (*bool, error) {
+	raw := q.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, errs.NewValidationError(fmt.Sprintf("%s must be true or false", key))
+	}
+	return &v, nil
+}
+
+// queryInt parses an optional positive integer query parameter, returning 0 when absent.
+func queryInt(q url.Values, key string) (int, error) {
+	raw := q.Get(key)
+	if raw == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v <= 0 {
+		return 0, errs.NewValidationError(fmt.Sprintf("%s must be a positive integer", key))
+	}
+	return v, nil
+}
+
+// queryDate returns the named YYYY-MM-DD query parameter, or nil when it is absent.
+func queryDate(q url.Values, key string) (*string, error) {
+	v := queryString(q, key)
+	if v == nil {
+		return nil, nil
+	}
+	if _, err := time.Parse("2006-01-02", *v); err != nil {
+		return nil, errs.NewValidationError(fmt.Sprintf("%s must be a date in YYYY-MM-DD format", key))
+	}
+	return v, nil
+}
+
+// requiredQueryDate is queryDate for parameters that must be present.
+func requiredQueryDate(q url.Values, key string) (string, error) {
+	v, err := queryDate(q, key)
+	if err != nil {
+		return "", err
+	}
+	if v == nil {
+		return "", errs.NewValidationError(fmt.Sprintf("%s is required", key))
+	}
+	return *v, nil
+}
@@ -6,10 +6,12 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/middleware"
 	"github.com/GregMSThompson/finance-backend/internal/models"
 	"github.com/GregMSThompson/finance-backend/internal/response"
@@ -17,6 +19,7 @@ import (
 
 type plaidService interface {
 	CreateLinkToken(ctx context.Context, uid string) (string, error)
+	CreateUpdateLinkToken(ctx context.Context, uid, bankID string) (string, error)
 	ExchangePublicToken(ctx context.Context, uid, publicToken, institutionName string) (string, error)
 	SyncTransactions(ctx context.Context, uid string, bankID *string) (dto.PlaidServiceSyncResult, error)
 }
@@ -24,6 +27,7 @@ type plaidService interface {
 type bankService interface {
 	ListBanks(ctx context.Context, uid string) ([]*models.Bank, error)
 	DeleteBank(ctx context.Context, uid, bankID string) error
+	ListSyncHistory(ctx context.Context, uid, bankID string, limit int) ([]*models.SyncRun, error)
 }
 
 type plaidHandlers struct {
@@ -47,6 +51,8 @@ func (h *plaidHandlers) PlaidRoutes() chi.Router {
 		r.Post("/", h.LinkBank)
 		r.Get("/", h.ListBanks)
 		r.Delete("/{bankId}", h.DeleteBank)
+		r.Post("/{bankId}/link-token", h.CreateUpdateLinkToken)
+		r.Get("/{bankId}/sync-history", h.ListSyncHistory)
 	})
 	r.Post("/transactions/sync", h.SyncTransactions)
 	return r
@@ -64,6 +70,19 @@ func (h *plaidHandlers) CreateLinkToken(w http.ResponseWriter, r *http.Request)
 	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, map[string]string{"linkToken": linkToken})
 }
 
+func (h *plaidHandlers) CreateUpdateLinkToken(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+	bankID := chi.URLParam(r, "bankId")
+
+	linkToken, err := h.PlaidSvc.CreateUpdateLinkToken(r.Context(), uid, bankID)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, map[string]string{"linkToken": linkToken})
+}
+
 func (h *plaidHandlers) LinkBank(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		PublicToken     string `json:"publicToken"`
@@ -108,6 +127,29 @@ func (h *plaidHandlers) DeleteBank(w http.ResponseWriter, r *http.Request) {
 	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, nil)
 }
 
+func (h *plaidHandlers) ListSyncHistory(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+	bankID := chi.URLParam(r, "bankId")
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			h.ResponseHandler.HandleError(w, r, errs.NewValidationError("limit must be a positive integer"))
+			return
+		}
+		limit = n
+	}
+
+	runs, err := h.BankSvc.ListSyncHistory(r.Context(), uid, bankID, limit)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, runs)
+}
+
 func (h *plaidHandlers) SyncTransactions(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		BankID *string `json:"bankId,omitempty"`
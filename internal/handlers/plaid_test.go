@@ -11,6 +11,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
+
 	"github.com/GregMSThompson/finance-backend/internal/dto"
 	"github.com/GregMSThompson/finance-backend/internal/middleware"
 	"github.com/GregMSThompson/finance-backend/internal/models"
@@ -35,11 +37,16 @@ type fakePlaidSvc struct {
 		uid    string
 		bankID *string
 	}
+	gotUpdateBankID string
 }
 
 func (f *fakePlaidSvc) CreateLinkToken(ctx context.Context, uid string) (string, error) {
 	return f.linkToken, f.err
 }
+func (f *fakePlaidSvc) CreateUpdateLinkToken(ctx context.Context, uid, bankID string) (string, error) {
+	f.gotUpdateBankID = bankID
+	return f.linkToken, f.err
+}
 func (f *fakePlaidSvc) ExchangePublicToken(ctx context.Context, uid, publicToken, institutionName string) (string, error) {
 	f.gotExchange.uid = uid
 	f.gotExchange.pubTok = publicToken
@@ -54,11 +61,22 @@ func (f *fakePlaidSvc) SyncTransactions(ctx context.Context, uid string, bankID
 
 type fakeBankSvc struct {
 	banks []*models.Bank
+	runs  []*models.SyncRun
 	err   error
+
+	gotHistory struct {
+		bankID string
+		limit  int
+	}
 }
 
 func (f *fakeBankSvc) ListBanks(ctx context.Context, uid string) ([]*models.Bank, error) { return f.banks, f.err }
 func (f *fakeBankSvc) DeleteBank(ctx context.Context, uid, bankID string) error          { return f.err }
+func (f *fakeBankSvc) ListSyncHistory(ctx context.Context, uid, bankID string, limit int) ([]*models.SyncRun, error) {
+	f.gotHistory.bankID = bankID
+	f.gotHistory.limit = limit
+	return f.runs, f.err
+}
 
 type plaidStubResponseHandler struct {
 	handleErrorCalled bool
@@ -126,6 +144,34 @@ func TestCreateLinkTokenHandler(t *testing.T) {
 	}
 }
 
+func TestCreateUpdateLinkTokenHandler(t *testing.T) {
+	p := &fakePlaidSvc{linkToken: "link-update"}
+	h := newTestPlaidHandler(p, &fakeBankSvc{})
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("bankId", "item-1")
+	ctx := context.WithValue(ctxWithUID(context.Background()), chi.RouteCtxKey, rctx)
+	req := httptest.NewRequest(http.MethodPost, "/banks/item-1/link-token", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	h.CreateUpdateLinkToken(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if p.gotUpdateBankID != "item-1" {
+		t.Fatalf("update link token called with bank %q", p.gotUpdateBankID)
+	}
+	var resp struct {
+		Success bool
+		Data    map[string]string
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.Data["linkToken"] != "link-update" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
 func TestLinkBankHandler(t *testing.T) {
 	p := &fakePlaidSvc{bankID: "item-1"}
 	h := newTestPlaidHandler(p, &fakeBankSvc{})
@@ -241,3 +287,49 @@ func TestSyncTransactionsHandlerInvalidJSON(t *testing.T) {
 		t.Fatalf("expected HandleError to be called")
 	}
 }
+
+func TestListSyncHistoryHandler(t *testing.T) {
+	b := &fakeBankSvc{runs: []*models.SyncRun{{RunID: "r1", BankID: "item-1", Status: models.SyncRunStatusSuccess}}}
+	h := newTestPlaidHandler(&fakePlaidSvc{}, b)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("bankId", "item-1")
+	ctx := context.WithValue(ctxWithUID(context.Background()), chi.RouteCtxKey, rctx)
+	req := httptest.NewRequest(http.MethodGet, "/banks/item-1/sync-history?limit=5", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	h.ListSyncHistory(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if b.gotHistory.bankID != "item-1" || b.gotHistory.limit != 5 {
+		t.Fatalf("sync history called with %+v", b.gotHistory)
+	}
+	var resp struct {
+		Success bool
+		Data    []models.SyncRun
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Data) != 1 || resp.Data[0].RunID != "r1" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestListSyncHistoryHandlerInvalidLimit(t *testing.T) {
+	b := &fakeBankSvc{}
+	resp := &plaidStubResponseHandler{}
+	h := newTestPlaidHandlerWithResp(&fakePlaidSvc{}, b, resp)
+
+	req := httptest.NewRequest(http.MethodGet, "/banks/item-1/sync-history?limit=abc", nil).WithContext(ctxWithUID(context.Background()))
+	rr := httptest.NewRecorder()
+
+	h.ListSyncHistory(rr, req)
+
+	if !resp.handleErrorCalled {
+		t.Fatalf("expected HandleError to be called")
+	}
+	if b.gotHistory.bankID != "" {
+		t.Fatalf("service should not be called on invalid limit")
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/middleware"
+	"github.com/GregMSThompson/finance-backend/internal/response"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type transactionService interface {
+	ListTransactions(ctx context.Context, uid string, q dto.TransactionQuery, pageToken string) (dto.TransactionPage, error)
+}
+
+type transactionHandlers struct {
+	ResponseHandler response.ResponseHandler
+	TransactionSvc  transactionService
+}
+
+func NewTransactionHandlers(deps *Deps) *transactionHandlers {
+	return &transactionHandlers{
+		ResponseHandler: deps.ResponseHandler,
+		TransactionSvc:  deps.TransactionSvc,
+	}
+}
+
+func (h *transactionHandlers) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	pending, err := queryBool(params, "pending")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+	desc, err := queryBool(params, "desc")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(params, "pageSize")
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	q := dto.TransactionQuery{
+		Pending:    pending,
+		PFCPrimary: queryString(params, "pfcPrimary"),
+		BankID:     queryString(params, "bankId"),
+		Merchant:   queryString(params, "merchant"),
+		DateFrom:   queryString(params, "dateFrom"),
+		DateTo:     queryString(params, "dateTo"),
+		OrderBy:    params.Get("orderBy"),
+		Desc:       helpers.Value(desc),
+		Limit:      pageSize,
+	}
+
+	uid := middleware.UID(r.Context())
+	page, err := h.TransactionSvc.ListTransactions(r.Context(), uid, q, params.Get("pageToken"))
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, page)
+}
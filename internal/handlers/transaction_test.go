@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type stubTransactionService struct {
+	called bool
+	uid    string
+	query  dto.TransactionQuery
+	token  string
+	page   dto.TransactionPage
+	err    error
+}
+
+func (s *stubTransactionService) ListTransactions(ctx context.Context, uid string, q dto.TransactionQuery, pageToken string) (dto.TransactionPage, error) {
+	s.called = true
+	s.uid = uid
+	s.query = q
+	s.token = pageToken
+	return s.page, s.err
+}
+
+func TestListTransactionsHandler(t *testing.T) {
+	svc := &stubTransactionService{page: dto.TransactionPage{
+		Transactions:  []*models.Transaction{{TransactionID: "t1"}},
+		NextPageToken: "next",
+	}}
+	resp := &stubResponseHandler{}
+	h := NewTransactionHandlers(&Deps{ResponseHandler: resp, TransactionSvc: svc})
+
+	url := "/transactions?pending=false&pfcPrimary=DINING&bankId=b1&merchant=cafe&dateFrom=2025-01-01&dateTo=2025-01-31&orderBy=amount&desc=true&pageSize=25&pageToken=tok"
+	req := httptest.NewRequest(http.MethodGet, url, nil).WithContext(ctxWithUID(context.Background()))
+	rr := httptest.NewRecorder()
+
+	h.ListTransactions(rr, req)
+
+	if svc.uid != "uid-123" || svc.token != "tok" {
+		t.Fatalf("service called with uid=%q token=%q", svc.uid, svc.token)
+	}
+	q := svc.query
+	if q.Pending == nil || *q.Pending || helpers.Value(q.PFCPrimary) != "DINING" || helpers.Value(q.BankID) != "b1" ||
+		helpers.Value(q.Merchant) != "cafe" || helpers.Value(q.DateFrom) != "2025-01-01" || helpers.Value(q.DateTo) != "2025-01-31" ||
+		q.OrderBy != "amount" || !q.Desc || q.Limit != 25 {
+		t.Fatalf("unexpected query: %+v", q)
+	}
+	if !resp.writeSuccessCalled || resp.writeSuccessStatus != http.StatusOK {
+		t.Fatalf("WriteSuccess not called with status 200")
+	}
+	if page, ok := resp.writeSuccessData.(dto.TransactionPage); !ok || page.NextPageToken != "next" {
+		t.Fatalf("unexpected response data: %#v", resp.writeSuccessData)
+	}
+}
+
+func TestListTransactionsHandlerInvalidParams(t *testing.T) {
+	for _, url := range []string{"/transactions?pending=maybe", "/transactions?pageSize=0", "/transactions?desc=x"} {
+		svc := &stubTransactionService{}
+		resp := &stubResponseHandler{}
+		h := NewTransactionHandlers(&Deps{ResponseHandler: resp, TransactionSvc: svc})
+
+		req := httptest.NewRequest(http.MethodGet, url, nil).WithContext(ctxWithUID(context.Background()))
+		h.ListTransactions(httptest.NewRecorder(), req)
+
+		if !resp.handleErrorCalled {
+			t.Fatalf("%s: expected HandleError to be called", url)
+		}
+		if svc.called {
+			t.Fatalf("%s: service should not be called", url)
+		}
+	}
+}
+
+func TestListTransactionsHandlerServiceError(t *testing.T) {
+	svc := &stubTransactionService{err: errors.New("boom")}
+	resp := &stubResponseHandler{}
+	h := NewTransactionHandlers(&Deps{ResponseHandler: resp, TransactionSvc: svc})
+
+	req := httptest.NewRequest(http.MethodGet, "/transactions", nil).WithContext(ctxWithUID(context.Background()))
+	h.ListTransactions(httptest.NewRecorder(), req)
+
+	if !resp.handleErrorCalled {
+		t.Fatalf("expected HandleError to be called")
+	}
+}
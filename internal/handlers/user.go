@@ -7,29 +7,47 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/middleware"
+	"github.com/GregMSThompson/finance-backend/internal/models"
 	"github.com/GregMSThompson/finance-backend/internal/response"
 )
 
 type userService interface {
 	CreateUser(ctx context.Context, uid, email, first, last string) error
+	GetUser(ctx context.Context, uid string) (*models.User, error)
+	UpdateUser(ctx context.Context, uid string, in dto.UserUpdate) (*models.User, error)
+	DeleteUser(ctx context.Context, uid string) error
+}
+
+type notificationService interface {
+	RegisterDevice(ctx context.Context, uid, token, platform string) error
+	UnregisterDevice(ctx context.Context, uid, token string) error
 }
 
 type userHandlers struct {
 	ResponseHandler response.ResponseHandler
 	UserSvc         userService
+	NotificationSvc notificationService
 }
 
 func NewUserHandlers(deps *Deps) *userHandlers {
 	return &userHandlers{
 		ResponseHandler: deps.ResponseHandler,
 		UserSvc:         deps.UserSvc,
+		NotificationSvc: deps.NotificationSvc,
 	}
 }
 
 func (h *userHandlers) UserRoutes() chi.Router {
 	r := chi.NewRouter()
 	r.Post("/", h.CreateUser)
+	r.Get("/me", h.GetMe)
+	r.Patch("/me", h.UpdateMe)
+	r.Delete("/me", h.DeleteMe)
+	r.Post("/me/devices", h.RegisterDevice)
+	r.Delete("/me/devices/{token}", h.UnregisterDevice)
 	return r
 }
 
@@ -57,3 +75,78 @@ func (h *userHandlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	h.ResponseHandler.WriteSuccess(w, r, 200, nil)
 }
+
+func (h *userHandlers) GetMe(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+
+	user, err := h.UserSvc.GetUser(r.Context(), uid)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, user)
+}
+
+// UpdateMe applies a partial profile update; fields missing from the body are kept.
+func (h *userHandlers) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	var body dto.UserUpdate
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.ResponseHandler.HandleError(w, r, errs.NewValidationError("invalid request body"))
+		return
+	}
+
+	uid := middleware.UID(r.Context())
+	user, err := h.UserSvc.UpdateUser(r.Context(), uid, body)
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, user)
+}
+
+// DeleteMe removes the user's Plaid items and every piece of data stored for them.
+func (h *userHandlers) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+
+	if err := h.UserSvc.DeleteUser(r.Context(), uid); err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, nil)
+}
+
+// RegisterDevice stores an FCM registration token so the user's device receives
+// push notifications. Registering a known token again refreshes it.
+func (h *userHandlers) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token    string `json:"token"`
+		Platform string `json:"platform"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.ResponseHandler.HandleError(w, r, errs.NewValidationError("invalid request body"))
+		return
+	}
+
+	uid := middleware.UID(r.Context())
+	if err := h.NotificationSvc.RegisterDevice(r.Context(), uid, body.Token, body.Platform); err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, nil)
+}
+
+func (h *userHandlers) UnregisterDevice(w http.ResponseWriter, r *http.Request) {
+	uid := middleware.UID(r.Context())
+	token := chi.URLParam(r, "token")
+
+	if err := h.NotificationSvc.UnregisterDevice(r.Context(), uid, token); err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, nil)
+}
@@ -8,7 +8,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
 	"github.com/GregMSThompson/finance-backend/internal/middleware"
+	"github.com/GregMSThompson/finance-backend/internal/models"
 )
 
 type stubUserService struct {
@@ -17,6 +21,11 @@ type stubUserService struct {
 	uid, email      string
 	first, lastName string
 	err             error
+
+	user         *models.User
+	update       dto.UserUpdate
+	updateCalled bool
+	deleteCalled bool
 }
 
 func (s *stubUserService) CreateUser(ctx context.Context, uid, email, first, last string) error {
@@ -29,6 +38,24 @@ func (s *stubUserService) CreateUser(ctx context.Context, uid, email, first, las
 	return s.err
 }
 
+func (s *stubUserService) GetUser(ctx context.Context, uid string) (*models.User, error) {
+	s.uid = uid
+	return s.user, s.err
+}
+
+func (s *stubUserService) UpdateUser(ctx context.Context, uid string, in dto.UserUpdate) (*models.User, error) {
+	s.updateCalled = true
+	s.uid = uid
+	s.update = in
+	return s.user, s.err
+}
+
+func (s *stubUserService) DeleteUser(ctx context.Context, uid string) error {
+	s.deleteCalled = true
+	s.uid = uid
+	return s.err
+}
+
 type stubResponseHandler struct {
 	writeSuccessCalled bool
 	writeSuccessStatus int
@@ -159,3 +186,166 @@ func TestCreateUserServiceError(t *testing.T) {
 		t.Fatalf("WriteSuccess should not be called on service error")
 	}
 }
+
+type stubNotificationService struct {
+	registerCalled   bool
+	uid, token       string
+	platform         string
+	unregisterCalled bool
+	err              error
+}
+
+func (s *stubNotificationService) RegisterDevice(ctx context.Context, uid, token, platform string) error {
+	s.registerCalled = true
+	s.uid, s.token, s.platform = uid, token, platform
+	return s.err
+}
+
+func (s *stubNotificationService) UnregisterDevice(ctx context.Context, uid, token string) error {
+	s.unregisterCalled = true
+	s.uid, s.token = uid, token
+	return s.err
+}
+
+func TestRegisterDeviceSuccess(t *testing.T) {
+	svc := &stubNotificationService{}
+	resp := &stubResponseHandler{}
+	h := NewUserHandlers(&Deps{ResponseHandler: resp, NotificationSvc: svc})
+
+	body := `{"token":"fcm-token","platform":"ios"}`
+	req := httptest.NewRequest(http.MethodPost, "/users/me/devices", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UIDKey, "uid-123"))
+	rr := httptest.NewRecorder()
+
+	h.RegisterDevice(rr, req)
+
+	if !svc.registerCalled || svc.uid != "uid-123" || svc.token != "fcm-token" || svc.platform != "ios" {
+		t.Fatalf("unexpected service call: %+v", svc)
+	}
+	if !resp.writeSuccessCalled || resp.writeSuccessStatus != http.StatusOK {
+		t.Fatalf("WriteSuccess not called with status 200")
+	}
+}
+
+func TestRegisterDeviceInvalidJSON(t *testing.T) {
+	svc := &stubNotificationService{}
+	resp := &stubResponseHandler{}
+	h := NewUserHandlers(&Deps{ResponseHandler: resp, NotificationSvc: svc})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/me/devices", strings.NewReader("not-json"))
+	rr := httptest.NewRecorder()
+
+	h.RegisterDevice(rr, req)
+
+	if svc.registerCalled {
+		t.Fatalf("RegisterDevice should not be called when JSON invalid")
+	}
+	if !resp.handleErrorCalled {
+		t.Fatalf("HandleError should be called on invalid JSON")
+	}
+}
+
+func TestUnregisterDeviceUsesPathToken(t *testing.T) {
+	svc := &stubNotificationService{}
+	resp := &stubResponseHandler{}
+	h := NewUserHandlers(&Deps{ResponseHandler: resp, NotificationSvc: svc})
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/me/devices/fcm-token", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("token", "fcm-token")
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	req = req.WithContext(context.WithValue(ctx, middleware.UIDKey, "uid-123"))
+	rr := httptest.NewRecorder()
+
+	h.UnregisterDevice(rr, req)
+
+	if !svc.unregisterCalled || svc.uid != "uid-123" || svc.token != "fcm-token" {
+		t.Fatalf("unexpected service call: %+v", svc)
+	}
+	if !resp.writeSuccessCalled {
+		t.Fatalf("expected WriteSuccess")
+	}
+}
+
+func TestGetMeReturnsUser(t *testing.T) {
+	userSvc := &stubUserService{user: &models.User{UID: "uid-123", FirstName: "Jane"}}
+	resp := &stubResponseHandler{}
+	h := NewUserHandlers(&Deps{ResponseHandler: resp, UserSvc: userSvc})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UIDKey, "uid-123"))
+	rr := httptest.NewRecorder()
+
+	h.GetMe(rr, req)
+
+	if userSvc.uid != "uid-123" {
+		t.Fatalf("unexpected uid: %q", userSvc.uid)
+	}
+	if !resp.writeSuccessCalled || resp.writeSuccessData != userSvc.user {
+		t.Fatalf("expected user to be written, got %+v", resp.writeSuccessData)
+	}
+}
+
+func TestUpdateMeDecodesPartialBody(t *testing.T) {
+	userSvc := &stubUserService{user: &models.User{UID: "uid-123"}}
+	resp := &stubResponseHandler{}
+	h := NewUserHandlers(&Deps{ResponseHandler: resp, UserSvc: userSvc})
+
+	body := `{"timezone":"Europe/London","preferences":{"theme":"dark"}}`
+	req := httptest.NewRequest(http.MethodPatch, "/users/me", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UIDKey, "uid-123"))
+	rr := httptest.NewRecorder()
+
+	h.UpdateMe(rr, req)
+
+	if !userSvc.updateCalled || userSvc.uid != "uid-123" {
+		t.Fatalf("expected UpdateUser to be called for uid-123")
+	}
+	in := userSvc.update
+	if in.FirstName != nil || in.Timezone == nil || *in.Timezone != "Europe/London" || in.Preferences["theme"] != "dark" {
+		t.Fatalf("unexpected update: %+v", in)
+	}
+	if !resp.writeSuccessCalled || resp.writeSuccessData != userSvc.user {
+		t.Fatalf("expected updated user to be written")
+	}
+}
+
+func TestUpdateMeInvalidJSON(t *testing.T) {
+	userSvc := &stubUserService{}
+	resp := &stubResponseHandler{}
+	h := NewUserHandlers(&Deps{ResponseHandler: resp, UserSvc: userSvc})
+
+	req := httptest.NewRequest(http.MethodPatch, "/users/me", strings.NewReader("not-json"))
+	rr := httptest.NewRecorder()
+
+	h.UpdateMe(rr, req)
+
+	if userSvc.updateCalled {
+		t.Fatalf("UpdateUser should not be called when JSON invalid")
+	}
+	if !resp.handleErrorCalled {
+		t.Fatalf("HandleError should be called on invalid JSON")
+	}
+}
+
+func TestDeleteMeServiceError(t *testing.T) {
+	userSvc := &stubUserService{err: errors.New("plaid down")}
+	resp := &stubResponseHandler{}
+	h := NewUserHandlers(&Deps{ResponseHandler: resp, UserSvc: userSvc})
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/me", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UIDKey, "uid-123"))
+	rr := httptest.NewRecorder()
+
+	h.DeleteMe(rr, req)
+
+	if !userSvc.deleteCalled || userSvc.uid != "uid-123" {
+		t.Fatalf("expected DeleteUser to be called for uid-123")
+	}
+	if !resp.handleErrorCalled || !errors.Is(resp.handleError, userSvc.err) {
+		t.Fatalf("expected service error to be handled, got %v", resp.handleError)
+	}
+	if resp.writeSuccessCalled {
+		t.Fatalf("WriteSuccess should not be called on service error")
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/GregMSThompson/finance-backend/internal/response"
+)
+
+// Plaid webhook bodies are small JSON documents; cap reads to avoid abuse of the
+// unauthenticated endpoint.
+const maxWebhookBodyBytes = 1 << 20
+
+type webhookService interface {
+	HandlePlaidWebhook(ctx context.Context, verification string, body []byte) error
+}
+
+type webhookHandlers struct {
+	ResponseHandler response.ResponseHandler
+	WebhookSvc      webhookService
+}
+
+func NewWebhookHandlers(deps *Deps) *webhookHandlers {
+	return &webhookHandlers{
+		ResponseHandler: deps.ResponseHandler,
+		WebhookSvc:      deps.WebhookSvc,
+	}
+}
+
+func (h *webhookHandlers) PlaidWebhook(w http.ResponseWriter, r *http.Request) {
+	// The raw body is needed for signature verification, so it is not decoded here.
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	if err := h.WebhookSvc.HandlePlaidWebhook(r.Context(), r.Header.Get("Plaid-Verification"), body); err != nil {
+		h.ResponseHandler.HandleError(w, r, err)
+		return
+	}
+
+	h.ResponseHandler.WriteSuccess(w, r, http.StatusOK, nil)
+}
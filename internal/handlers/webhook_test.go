@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type stubWebhookService struct {
+	called       bool
+	verification string
+	body         string
+	err          error
+}
+
+func (s *stubWebhookService) HandlePlaidWebhook(ctx context.Context, verification string, body []byte) error {
+	s.called = true
+	s.verification = verification
+	s.body = string(body)
+	return s.err
+}
+
+func TestPlaidWebhookHandlerPassesRawBodyAndHeader(t *testing.T) {
+	svc := &stubWebhookService{}
+	resp := &stubResponseHandler{}
+	h := NewWebhookHandlers(&Deps{ResponseHandler: resp, WebhookSvc: svc})
+
+	body := `{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-1"}`
+	req := httptest.NewRequest(http.MethodPost, "/plaid/webhook", strings.NewReader(body)).WithContext(helpers.TestCtx())
+	req.Header.Set("Plaid-Verification", "jwt-token")
+	rr := httptest.NewRecorder()
+
+	h.PlaidWebhook(rr, req)
+
+	if !svc.called || svc.verification != "jwt-token" || svc.body != body {
+		t.Fatalf("service called with unexpected args: %+v", svc)
+	}
+	if !resp.writeSuccessCalled || resp.writeSuccessStatus != http.StatusOK {
+		t.Fatalf("WriteSuccess not called with status 200")
+	}
+}
+
+func TestPlaidWebhookHandlerServiceError(t *testing.T) {
+	svc := &stubWebhookService{err: errors.New("boom")}
+	resp := &stubResponseHandler{}
+	h := NewWebhookHandlers(&Deps{ResponseHandler: resp, WebhookSvc: svc})
+
+	req := httptest.NewRequest(http.MethodPost, "/plaid/webhook", strings.NewReader(`{}`)).WithContext(helpers.TestCtx())
+	rr := httptest.NewRecorder()
+
+	h.PlaidWebhook(rr, req)
+
+	if !resp.handleErrorCalled {
+		t.Fatalf("expected HandleError to be called")
+	}
+}
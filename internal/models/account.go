@@ -0,0 +1,92 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/money"
+)
+
+type Account struct {
+	AccountID    string `firestore:"accountId" json:"accountId"` // Plaid account_id (doc ID)
+	BankID       string `firestore:"bankId" json:"bankId"`       // Plaid item_id
+	Name         string `firestore:"name" json:"name"`
+	OfficialName string `firestore:"officialName" json:"officialName,omitempty"`
+	Mask         string `firestore:"mask" json:"mask,omitempty"`
+	Type         string `firestore:"type" json:"type"`       // e.g. "depository", "credit", "loan"
+	Subtype      string `firestore:"subtype" json:"subtype"` // e.g. "checking", "credit card"
+	// Balances are minor units of Currency, nil when Plaid didn't report them; see
+	// the matching Money accessors.
+	CurrentBalanceMinor   *int64    `firestore:"currentBalanceMinor,omitempty" json:"-"`
+	AvailableBalanceMinor *int64    `firestore:"availableBalanceMinor,omitempty" json:"-"`
+	CreditLimitMinor      *int64    `firestore:"creditLimitMinor,omitempty" json:"-"`
+	Currency              string    `firestore:"currency" json:"currency"`
+	CreatedAt             time.Time `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt             time.Time `firestore:"updatedAt" json:"updatedAt"`
+
+	// Legacy* are the float balances on documents written before balances were stored
+	// in minor units. They are only read; NormalizeBalances folds them into the *Minor
+	// fields.
+	LegacyCurrentBalance   *float64 `firestore:"currentBalance,omitempty" json:"-"`
+	LegacyAvailableBalance *float64 `firestore:"availableBalance,omitempty" json:"-"`
+	LegacyCreditLimit      *float64 `firestore:"creditLimit,omitempty" json:"-"`
+}
+
+func (a *Account) CurrentBalance() *money.Money {
+	return a.minorToMoney(a.CurrentBalanceMinor)
+}
+
+func (a *Account) AvailableBalance() *money.Money {
+	return a.minorToMoney(a.AvailableBalanceMinor)
+}
+
+func (a *Account) CreditLimit() *money.Money {
+	return a.minorToMoney(a.CreditLimitMinor)
+}
+
+func (a *Account) minorToMoney(minor *int64) *money.Money {
+	if minor == nil {
+		return nil
+	}
+	m := money.New(*minor, a.Currency)
+	return &m
+}
+
+// NormalizeBalances fills the *Minor balances from the legacy float fields on
+// documents that haven't been migrated yet, so readers never see the floats.
+func (a *Account) NormalizeBalances() {
+	for _, f := range []struct {
+		legacy **float64
+		minor  **int64
+	}{
+		{&a.LegacyCurrentBalance, &a.CurrentBalanceMinor},
+		{&a.LegacyAvailableBalance, &a.AvailableBalanceMinor},
+		{&a.LegacyCreditLimit, &a.CreditLimitMinor},
+	} {
+		if *f.legacy == nil {
+			continue
+		}
+		if *f.minor == nil {
+			minor := money.FromFloat(**f.legacy, a.Currency).Minor
+			*f.minor = &minor
+		}
+		*f.legacy = nil
+	}
+}
+
+// HasLegacyBalances reports whether the document still carries float balances.
+func (a *Account) HasLegacyBalances() bool {
+	return a.LegacyCurrentBalance != nil || a.LegacyAvailableBalance != nil || a.LegacyCreditLimit != nil
+}
+
+// MarshalJSON exposes the balances as decimal fields, matching the API shape from
+// before balances were stored in minor units.
+func (a Account) MarshalJSON() ([]byte, error) {
+	type alias Account
+	return json.Marshal(struct {
+		alias
+		CurrentBalance   *money.Money `json:"currentBalance,omitempty"`
+		AvailableBalance *money.Money `json:"availableBalance,omitempty"`
+		CreditLimit      *money.Money `json:"creditLimit,omitempty"`
+	}{alias(a), a.CurrentBalance(), a.AvailableBalance(), a.CreditLimit()})
+}
@@ -3,11 +3,24 @@ package models
 import "time"
 
 type AIMessage struct {
+	MessageID  string         `firestore:"-" json:"messageId,omitempty"` // doc ID
 	Role       string         `firestore:"role" json:"role"`
 	Content    string         `firestore:"content,omitempty" json:"content,omitempty"`
 	ToolName   string         `firestore:"toolName,omitempty" json:"toolName,omitempty"`
 	ToolArgs   map[string]any `firestore:"toolArgs,omitempty" json:"toolArgs,omitempty"`
 	ToolResult map[string]any `firestore:"toolResult,omitempty" json:"toolResult,omitempty"`
+	ToolStep   int            `firestore:"toolStep,omitempty" json:"toolStep,omitempty"` // tool round within a query, from 1
 	CreatedAt  time.Time      `firestore:"createdAt" json:"createdAt"`
 	ExpiresAt  time.Time      `firestore:"expiresAt,omitempty" json:"expiresAt,omitempty"`
 }
+
+// AISession is the parent document of a conversation's messages.
+type AISession struct {
+	SessionID     string     `firestore:"sessionId" json:"sessionId"` // doc ID
+	Title         string     `firestore:"title" json:"title"`
+	LastMessage   string     `firestore:"lastMessage,omitempty" json:"lastMessage,omitempty"` // preview of the latest turn
+	LastMessageAt *time.Time `firestore:"lastMessageAt,omitempty" json:"lastMessageAt,omitempty"`
+	CreatedAt     time.Time  `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time  `firestore:"updatedAt" json:"updatedAt"`
+	ExpiresAt     time.Time  `firestore:"expiresAt,omitempty" json:"expiresAt,omitempty"` // when its latest message expires, or the session itself while empty
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/money"
+)
+
+// AlertRule describes transactions the user wants to hear about. Every condition
+// that is set must match; unset conditions match anything.
+type AlertRule struct {
+	RuleID         string    `firestore:"ruleId" json:"ruleId"`
+	Name           string    `firestore:"name" json:"name"`
+	MinAmountMinor int64     `firestore:"minAmountMinor" json:"-"` // charges of at least this much, in minor units of Currency; 0 for any
+	Currency       string    `firestore:"currency,omitempty" json:"currency,omitempty"`
+	PFCPrimary     string    `firestore:"pfcPrimary,omitempty" json:"pfcPrimary,omitempty"`
+	Merchant       string    `firestore:"merchant,omitempty" json:"merchant,omitempty"` // case-insensitive match on the transaction name
+	BankID         string    `firestore:"bankId,omitempty" json:"bankId,omitempty"`
+	NewMerchant    bool      `firestore:"newMerchant" json:"newMerchant"` // only merchants the user hasn't transacted with before
+	CreatedAt      time.Time `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt      time.Time `firestore:"updatedAt" json:"updatedAt"`
+}
+
+func (r *AlertRule) MinAmount() money.Money {
+	return money.New(r.MinAmountMinor, r.Currency)
+}
+
+func (r AlertRule) MarshalJSON() ([]byte, error) {
+	type alias AlertRule
+	out := struct {
+		alias
+		MinAmount *money.Money `json:"minAmount,omitempty"`
+	}{alias: alias(r)}
+	if r.MinAmountMinor > 0 {
+		amount := r.MinAmount()
+		out.MinAmount = &amount
+	}
+	return json.Marshal(out)
+}
+
+// Alert records one transaction matching one rule. AlertID is derived from both, so
+// a transaction raises at most one alert per rule.
+type Alert struct {
+	AlertID        string     `firestore:"alertId" json:"alertId"`
+	RuleID         string     `firestore:"ruleId" json:"ruleId"`
+	RuleName       string     `firestore:"ruleName" json:"ruleName"`
+	TransactionID  string     `firestore:"transactionId" json:"transactionId"`
+	BankID         string     `firestore:"bankId" json:"bankId"`
+	Name           string     `firestore:"name" json:"name"`
+	AmountMinor    int64      `firestore:"amountMinor" json:"-"` // minor units of Currency; see Amount
+	Currency       string     `firestore:"currency" json:"currency"`
+	Date           string     `firestore:"date" json:"date"`
+	PFCPrimary     string     `firestore:"pfcPrimary,omitempty" json:"pfcPrimary,omitempty"`
+	Acknowledged   bool       `firestore:"acknowledged" json:"acknowledged"`
+	AcknowledgedAt *time.Time `firestore:"acknowledgedAt,omitempty" json:"acknowledgedAt,omitempty"`
+	CreatedAt      time.Time  `firestore:"createdAt" json:"createdAt"`
+}
+
+func (a *Alert) Amount() money.Money {
+	return money.New(a.AmountMinor, a.Currency)
+}
+
+func (a Alert) MarshalJSON() ([]byte, error) {
+	type alias Alert
+	return json.Marshal(struct {
+		alias
+		Amount money.Money `json:"amount"`
+	}{alias(a), a.Amount()})
+}
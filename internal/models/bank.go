@@ -4,11 +4,24 @@ import (
 	"time"
 )
 
+const (
+	BankStatusActive            = "active"
+	BankStatusError             = "error"
+	BankStatusLoginRequired     = "login_required"
+	BankStatusPendingExpiration = "pending_expiration"
+	BankStatusRevoked           = "revoked"
+)
+
 type Bank struct {
-	BankID           string    `firestore:"bankId" json:"bankId"`
-	Institution      string    `firestore:"institution" json:"institution"`
-	Status           string    `firestore:"status" json:"status"` // e.g. "active", "inactive"
-	PlaidPublicToken string    `firestore:"plaidPublicToken" json:"-"`
-	CreatedAt        time.Time `firestore:"createdAt" json:"createdAt"`
-	UpdatedAt        time.Time `firestore:"updatedAt" json:"updatedAt"`
+	BankID           string     `firestore:"bankId" json:"bankId"`
+	Institution      string     `firestore:"institution" json:"institution"`
+	Status           string     `firestore:"status" json:"status"`                           // one of the BankStatus* values
+	ErrorCode        string     `firestore:"errorCode,omitempty" json:"errorCode,omitempty"` // last Plaid item error code
+	PlaidPublicToken string     `firestore:"plaidPublicToken" json:"-"`
+	LastSyncAt       *time.Time `firestore:"lastSyncAt,omitempty" json:"lastSyncAt,omitempty"`
+	LastSyncError    string     `firestore:"lastSyncError,omitempty" json:"lastSyncError,omitempty"`
+	SyncFailures     int        `firestore:"syncFailures,omitempty" json:"-"` // consecutive scheduled sync failures
+	NextSyncAt       *time.Time `firestore:"nextSyncAt,omitempty" json:"-"`   // scheduled syncs back off until this time
+	CreatedAt        time.Time  `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt        time.Time  `firestore:"updatedAt" json:"updatedAt"`
 }
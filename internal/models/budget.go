@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/money"
+)
+
+const (
+	BudgetPeriodMonthly = "monthly"
+	BudgetPeriodWeekly  = "weekly"
+)
+
+// Budget caps spending over a recurring period. It covers one pfcPrimary category,
+// one merchant, or (when neither is set) all spending.
+type Budget struct {
+	BudgetID    string    `firestore:"budgetId" json:"budgetId"`
+	Name        string    `firestore:"name" json:"name"`
+	PFCPrimary  string    `firestore:"pfcPrimary" json:"pfcPrimary,omitempty"`
+	Merchant    string    `firestore:"merchant" json:"merchant,omitempty"`
+	Period      string    `firestore:"period" json:"period"` // monthly | weekly
+	AmountMinor int64     `firestore:"amountMinor" json:"-"` // minor units of Currency; see Amount
+	Currency    string    `firestore:"currency" json:"currency"`
+	CreatedAt   time.Time `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time `firestore:"updatedAt" json:"updatedAt"`
+}
+
+func (b *Budget) Amount() money.Money {
+	return money.New(b.AmountMinor, b.Currency)
+}
+
+func (b Budget) MarshalJSON() ([]byte, error) {
+	type alias Budget
+	return json.Marshal(struct {
+		alias
+		Amount money.Money `json:"amount"`
+	}{alias(b), b.Amount()})
+}
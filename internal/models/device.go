@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+const (
+	DevicePlatformIOS     = "ios"
+	DevicePlatformAndroid = "android"
+	DevicePlatformWeb     = "web"
+)
+
+// Device is an FCM registration token for one of the user's app installs.
+type Device struct {
+	Token     string    `firestore:"token" json:"token"`
+	Platform  string    `firestore:"platform" json:"platform"`
+	CreatedAt time.Time `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `firestore:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	SyncRunStatusSuccess = "success"
+	SyncRunStatusFailed  = "failed"
+)
+
+// SyncRun records one /transactions/sync pass for a bank.
+type SyncRun struct {
+	RunID                string    `firestore:"runId" json:"runId"`
+	BankID               string    `firestore:"bankId" json:"bankId"`
+	Status               string    `firestore:"status" json:"status"` // one of the SyncRunStatus* values
+	StartedAt            time.Time `firestore:"startedAt" json:"startedAt"`
+	EndedAt              time.Time `firestore:"endedAt" json:"endedAt"`
+	TransactionsInserted int       `firestore:"transactionsInserted" json:"transactionsInserted"`
+	TransactionsUpdated  int       `firestore:"transactionsUpdated" json:"transactionsUpdated"`
+	TransactionsRemoved  int       `firestore:"transactionsRemoved" json:"transactionsRemoved"`
+	ErrorCode            string    `firestore:"errorCode,omitempty" json:"errorCode,omitempty"`
+	ErrorMessage         string    `firestore:"errorMessage,omitempty" json:"errorMessage,omitempty"`
+	CursorBefore         string    `firestore:"cursorBefore" json:"cursorBefore"`
+	CursorAfter          string    `firestore:"cursorAfter" json:"cursorAfter"`
+}
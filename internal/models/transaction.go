@@ -1,14 +1,18 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/money"
 )
 
 type Transaction struct {
 	TransactionID  string    `firestore:"transactionId" json:"transactionId"` // Plaid transaction_id (doc ID)
 	BankID         string    `firestore:"bankId" json:"bankId"`               // Plaid item_id
+	AccountID      string    `firestore:"accountId" json:"accountId"`         // Plaid account_id
 	Name           string    `firestore:"name" json:"name"`
-	Amount         float64   `firestore:"amount" json:"amount"`
+	AmountMinor    int64     `firestore:"amountMinor" json:"-"` // minor units of Currency; see Amount
 	Currency       string    `firestore:"currency" json:"currency"`
 	Pending        bool      `firestore:"pending" json:"pending"`
 	Date           string    `firestore:"date" json:"date"` // YYYY-MM-DD as Plaid returns
@@ -20,4 +24,34 @@ type Transaction struct {
 	PFCIconURL     string    `firestore:"pfcIconUrl" json:"pfcIconUrl,omitempty"`
 	CreatedAt      time.Time `firestore:"createdAt" json:"createdAt"`
 	UpdatedAt      time.Time `firestore:"updatedAt" json:"updatedAt"`
+
+	// LegacyAmount is the float amount on documents written before amounts were stored
+	// in minor units. It is only read; NormalizeAmount folds it into AmountMinor.
+	LegacyAmount *float64 `firestore:"amount,omitempty" json:"-"`
+}
+
+func (t *Transaction) Amount() money.Money {
+	return money.New(t.AmountMinor, t.Currency)
+}
+
+// NormalizeAmount fills AmountMinor from LegacyAmount on documents that haven't been
+// migrated yet, so readers never see the float field.
+func (t *Transaction) NormalizeAmount() {
+	if t.LegacyAmount == nil {
+		return
+	}
+	if t.AmountMinor == 0 {
+		t.AmountMinor = money.FromFloat(*t.LegacyAmount, t.Currency).Minor
+	}
+	t.LegacyAmount = nil
+}
+
+// MarshalJSON exposes the amount as a decimal "amount" field, matching the API shape
+// from before amounts were stored in minor units.
+func (t Transaction) MarshalJSON() ([]byte, error) {
+	type alias Transaction
+	return json.Marshal(struct {
+		alias
+		Amount money.Money `json:"amount"`
+	}{alias(t), t.Amount()})
 }
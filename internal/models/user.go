@@ -5,10 +5,13 @@ import (
 )
 
 type User struct {
-	UID       string    `firestore:"uid" json:"uid"`
-	Email     string    `firestore:"email" json:"email"`
-	FirstName string    `firestore:"firstName" json:"firstName"`
-	LastName  string    `firestore:"lastName" json:"lastName"`
-	CreatedAt time.Time `firestore:"createdAt" json:"createdAt"`
-	UpdatedAt time.Time `firestore:"updatedAt" json:"updatedAt"`
+	UID          string            `firestore:"uid" json:"uid"`
+	Email        string            `firestore:"email" json:"email"`
+	FirstName    string            `firestore:"firstName" json:"firstName"`
+	LastName     string            `firestore:"lastName" json:"lastName"`
+	Timezone     string            `firestore:"timezone,omitempty" json:"timezone,omitempty"`         // IANA name, e.g. Europe/London
+	BaseCurrency string            `firestore:"baseCurrency,omitempty" json:"baseCurrency,omitempty"` // ISO 4217
+	Preferences  map[string]string `firestore:"preferences,omitempty" json:"preferences,omitempty"`   // client-defined settings
+	CreatedAt    time.Time         `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt    time.Time         `firestore:"updatedAt" json:"updatedAt"`
 }
@@ -0,0 +1,110 @@
+package money
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is a fixed-point amount held as integer minor units of its currency (cents for
+// USD, yen for JPY), so sums over many transactions are exact. Arithmetic between
+// amounts assumes they share a currency; convert first when they don't.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+// minorExponents lists ISO 4217 currencies whose minor unit isn't the usual 1/100.
+var minorExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+func New(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// FromFloat converts a decimal amount, such as one returned by Plaid, rounding half
+// away from zero to the nearest minor unit.
+func FromFloat(amount float64, currency string) Money {
+	return Money{Minor: int64(math.Round(amount * scale(currency))), Currency: currency}
+}
+
+// Exponent returns the number of minor-unit digits for currency, defaulting to 2 for
+// unknown or empty codes.
+func Exponent(currency string) int {
+	if exp, ok := minorExponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+func scale(currency string) float64 {
+	return math.Pow10(Exponent(currency))
+}
+
+// Float64 returns the amount in major units. Use it for ratios and display only.
+func (m Money) Float64() float64 {
+	return float64(m.Minor) / scale(m.Currency)
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+// Add returns m+o. A zero-value m adopts o's currency so totals can start empty.
+func (m Money) Add(o Money) Money {
+	if m.Currency == "" {
+		m.Currency = o.Currency
+	}
+	m.Minor += o.Minor
+	return m
+}
+
+func (m Money) Sub(o Money) Money {
+	return m.Add(o.Neg())
+}
+
+func (m Money) Neg() Money {
+	m.Minor = -m.Minor
+	return m
+}
+
+// Mul scales the amount by f, rounding half away from zero to the nearest minor unit.
+func (m Money) Mul(f float64) Money {
+	m.Minor = int64(math.Round(float64(m.Minor) * f))
+	return m
+}
+
+// Convert applies an exchange rate from m's currency into currency, rescaling for
+// currencies with a different number of minor digits.
+func (m Money) Convert(rate float64, currency string) Money {
+	major := m.Float64() * rate
+	return FromFloat(major, currency)
+}
+
+// String formats the amount in major units with the currency's minor digits, e.g. "-12.30".
+func (m Money) String() string {
+	exp := Exponent(m.Currency)
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	digits := strconv.FormatInt(minor, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// MarshalJSON renders the amount as an exact JSON number in major units, so API
+// responses keep the shape they had when amounts were floats.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
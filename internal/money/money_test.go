@@ -0,0 +1,83 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFromFloatRoundsToMinorUnits(t *testing.T) {
+	cases := []struct {
+		amount   float64
+		currency string
+		want     int64
+	}{
+		{12.34, "USD", 1234},
+		{0.1 + 0.2, "USD", 30},
+		{-4.005, "EUR", -401},
+		{1500, "JPY", 1500},
+		{1.2345, "KWD", 1235},
+	}
+	for _, tc := range cases {
+		if got := FromFloat(tc.amount, tc.currency).Minor; got != tc.want {
+			t.Fatalf("FromFloat(%v, %s) = %d, want %d", tc.amount, tc.currency, got, tc.want)
+		}
+	}
+}
+
+func TestSumIsExact(t *testing.T) {
+	total := Money{}
+	for i := 0; i < 10000; i++ {
+		total = total.Add(FromFloat(0.1, "USD"))
+	}
+	if total.Minor != 100000 || total.Currency != "USD" {
+		t.Fatalf("unexpected total: %+v", total)
+	}
+}
+
+func TestString(t *testing.T) {
+	cases := []struct {
+		m    Money
+		want string
+	}{
+		{New(1234, "USD"), "12.34"},
+		{New(-5, "USD"), "-0.05"},
+		{New(0, ""), "0.00"},
+		{New(1500, "JPY"), "1500"},
+		{New(1, "BHD"), "0.001"},
+	}
+	for _, tc := range cases {
+		if got := tc.m.String(); got != tc.want {
+			t.Fatalf("%+v.String() = %q, want %q", tc.m, got, tc.want)
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	raw, err := json.Marshal(struct {
+		Total Money `json:"total"`
+	}{New(-1230, "USD")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(raw) != `{"total":-12.30}` {
+		t.Fatalf("unexpected json: %s", raw)
+	}
+}
+
+func TestConvertRescalesExponent(t *testing.T) {
+	if got := New(1000, "USD").Convert(150, "JPY"); got != New(1500, "JPY") {
+		t.Fatalf("USD->JPY = %+v", got)
+	}
+	if got := New(1500, "JPY").Convert(0.0066, "USD"); got != New(990, "USD") {
+		t.Fatalf("JPY->USD = %+v", got)
+	}
+}
+
+func TestMulRounds(t *testing.T) {
+	if got := New(1000, "USD").Mul(4.33); got.Minor != 4330 {
+		t.Fatalf("Mul = %+v", got)
+	}
+	if got := New(1000, "USD").Mul(1.0 / 3); got.Minor != 333 {
+		t.Fatalf("Mul = %+v", got)
+	}
+}
@@ -45,6 +45,14 @@ func (h *responseHandler) HandleError(w http.ResponseWriter, r *http.Request, er
 		log.Warn("validation failed", "error", e.Message)
 		h.WriteError(w, r, http.StatusBadRequest, "invalid_input", e.Message)
 
+	case *errs.MissingExchangeRateError:
+		log.Warn("missing exchange rate", "from", e.From, "to", e.To)
+		h.WriteError(w, r, http.StatusBadRequest, "invalid_input", e.Message)
+
+	case *errs.UnauthorizedError:
+		log.Warn("unauthorized request", "error", e.Message, "cause", e.Cause)
+		h.WriteError(w, r, http.StatusUnauthorized, "unauthorized", e.Message)
+
 	case *errs.UnsupportedGroupByError:
 		log.Warn("unsupported operation", "error", e.Message)
 		h.WriteError(w, r, http.StatusBadRequest, "invalid_input", e.Message)
@@ -0,0 +1,35 @@
+package retry
+
+import (
+	"expvar"
+)
+
+// Counters are published once per process. The API serves them on its
+// authenticated /debug/vars route.
+var (
+	attemptsVar  = expvar.NewMap("retry_attempts")
+	retriesVar   = expvar.NewMap("retry_retries")
+	failuresVar  = expvar.NewMap("retry_failures")
+	exhaustedVar = expvar.NewMap("retry_exhausted")
+)
+
+// ExpvarMetrics counts attempts per operation in process-wide expvar maps.
+type ExpvarMetrics struct{}
+
+func NewExpvarMetrics() *ExpvarMetrics {
+	return &ExpvarMetrics{}
+}
+
+func (m *ExpvarMetrics) RecordAttempt(op string, attempt int, err error) {
+	attemptsVar.Add(op, 1)
+	if attempt > 1 {
+		retriesVar.Add(op, 1)
+	}
+	if err != nil {
+		failuresVar.Add(op, 1)
+	}
+}
+
+func (m *ExpvarMetrics) RecordExhausted(op string, attempts int, err error) {
+	exhaustedVar.Add(op, 1)
+}
@@ -0,0 +1,142 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/pkg/logger"
+)
+
+const (
+	DefaultMaxAttempts = 3
+	DefaultBaseDelay   = 200 * time.Millisecond
+	DefaultMaxDelay    = 5 * time.Second
+
+	// Rate limits clear more slowly than transient outages, so backoff starts higher.
+	rateLimitDelayFactor = 4
+)
+
+// Metrics observes every attempt an operation makes.
+type Metrics interface {
+	RecordAttempt(op string, attempt int, err error)
+	RecordExhausted(op string, attempts int, err error)
+}
+
+// Policy retries operations that fail with a transient external-service error,
+// backing off exponentially with jitter between attempts.
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Metrics     Metrics
+
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func() float64
+}
+
+func NewPolicy(maxAttempts int, baseDelay, maxDelay time.Duration, metrics Metrics) *Policy {
+	return &Policy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   baseDelay,
+		MaxDelay:    maxDelay,
+		Metrics:     metrics,
+		sleep:       sleepContext,
+		jitter:      rand.Float64,
+	}
+}
+
+// Default returns the policy used for Plaid and Vertex calls.
+func Default() *Policy {
+	return NewPolicy(DefaultMaxAttempts, DefaultBaseDelay, DefaultMaxDelay, NewExpvarMetrics())
+}
+
+// Do runs fn until it succeeds, returns a non-retryable error, runs out of attempts,
+// or ctx is done. The last error from fn is returned unchanged. A nil policy runs fn once.
+func (p *Policy) Do(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	if p == nil {
+		return fn(ctx)
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if p.Metrics != nil {
+			p.Metrics.RecordAttempt(op, attempt, err)
+		}
+		if err == nil || !Retryable(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			if p.Metrics != nil {
+				p.Metrics.RecordExhausted(op, attempt, err)
+			}
+			return err
+		}
+
+		delay := p.backoff(attempt, err)
+		log := logger.FromContext(ctx)
+		log.Warn("retrying transient error", "op", op, "attempt", attempt, "delay", delay, "error", err)
+		if sleepErr := p.sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// backoff uses "equal jitter": half the exponential delay is fixed and half random,
+// which spreads retries out without ever retrying immediately.
+func (p *Policy) backoff(attempt int, err error) time.Duration {
+	delay := p.BaseDelay
+	if IsRateLimited(err) {
+		delay *= rateLimitDelayFactor
+	}
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(p.jitter()*float64(half))
+}
+
+// Retryable reports whether err is a transient external-service failure.
+func Retryable(err error) bool {
+	var extErr *errs.ExternalServiceError
+	if errors.As(err, &extErr) {
+		return extErr.Transient || IsRateLimited(err)
+	}
+	return false
+}
+
+// IsRateLimited reports whether err came from Plaid's RATE_LIMIT_EXCEEDED or a gRPC
+// ResourceExhausted status, such as a Vertex AI quota error.
+func IsRateLimited(err error) bool {
+	var extErr *errs.ExternalServiceError
+	if errors.As(err, &extErr) && extErr.Code == "RATE_LIMIT_EXCEEDED" {
+		return true
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if st, ok := status.FromError(e); ok && st.Code() == codes.ResourceExhausted {
+			return true
+		}
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
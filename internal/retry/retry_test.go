@@ -0,0 +1,157 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type fakeMetrics struct {
+	attempts  []int
+	exhausted int
+}
+
+func (f *fakeMetrics) RecordAttempt(op string, attempt int, err error) {
+	f.attempts = append(f.attempts, attempt)
+}
+
+func (f *fakeMetrics) RecordExhausted(op string, attempts int, err error) {
+	f.exhausted++
+}
+
+func newTestPolicy(metrics Metrics, delays *[]time.Duration) *Policy {
+	p := NewPolicy(3, 100*time.Millisecond, time.Second, metrics)
+	p.jitter = func() float64 { return 1 }
+	p.sleep = func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return ctx.Err()
+	}
+	return p
+}
+
+func transientErr() error {
+	return errs.NewExternalServiceError("plaid", "boom", true, nil)
+}
+
+func TestDoRetriesTransientErrors(t *testing.T) {
+	metrics := &fakeMetrics{}
+	var delays []time.Duration
+	p := newTestPolicy(metrics, &delays)
+
+	calls := 0
+	err := p.Do(helpers.TestCtx(), "op", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return transientErr()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+	if len(delays) != 2 || delays[0] != 100*time.Millisecond || delays[1] != 200*time.Millisecond {
+		t.Fatalf("unexpected delays: %v", delays)
+	}
+	if len(metrics.attempts) != 3 || metrics.exhausted != 0 {
+		t.Fatalf("unexpected metrics: %+v", metrics)
+	}
+}
+
+func TestDoStopsOnPermanentError(t *testing.T) {
+	var delays []time.Duration
+	p := newTestPolicy(nil, &delays)
+
+	calls := 0
+	permanent := errs.NewExternalServiceError("plaid", "bad token", false, nil)
+	err := p.Do(helpers.TestCtx(), "op", func(ctx context.Context) error {
+		calls++
+		return permanent
+	})
+	if err != permanent {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+	if calls != 1 || len(delays) != 0 {
+		t.Fatalf("expected a single attempt, got calls=%d delays=%v", calls, delays)
+	}
+}
+
+func TestDoGivesUpAfterMaxAttempts(t *testing.T) {
+	metrics := &fakeMetrics{}
+	var delays []time.Duration
+	p := newTestPolicy(metrics, &delays)
+
+	calls := 0
+	err := p.Do(helpers.TestCtx(), "op", func(ctx context.Context) error {
+		calls++
+		return transientErr()
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if calls != 3 || metrics.exhausted != 1 {
+		t.Fatalf("expected 3 calls and exhaustion, got calls=%d metrics=%+v", calls, metrics)
+	}
+}
+
+func TestDoStopsWhenContextCancelled(t *testing.T) {
+	var delays []time.Duration
+	p := newTestPolicy(nil, &delays)
+
+	ctx, cancel := context.WithCancel(helpers.TestCtx())
+	cancel()
+
+	calls := 0
+	err := p.Do(ctx, "op", func(ctx context.Context) error {
+		calls++
+		return transientErr()
+	})
+	if err == nil || calls != 1 {
+		t.Fatalf("expected one failed attempt, got calls=%d err=%v", calls, err)
+	}
+}
+
+func TestDoRateLimitedBacksOffLonger(t *testing.T) {
+	var delays []time.Duration
+	p := newTestPolicy(nil, &delays)
+
+	rateLimited := errs.NewExternalServiceError("plaid", "slow down", true, nil)
+	rateLimited.Code = "RATE_LIMIT_EXCEEDED"
+	_ = p.Do(helpers.TestCtx(), "op", func(ctx context.Context) error { return rateLimited })
+
+	if len(delays) == 0 || delays[0] != 400*time.Millisecond {
+		t.Fatalf("expected rate limited backoff of 400ms, got %v", delays)
+	}
+}
+
+func TestIsRateLimitedResourceExhausted(t *testing.T) {
+	quota := status.Error(codes.ResourceExhausted, "quota exceeded")
+	err := errs.NewExternalServiceError("vertex", "failed to generate content", true, quota)
+	if !IsRateLimited(err) {
+		t.Fatalf("expected ResourceExhausted to be rate limited")
+	}
+	if IsRateLimited(errs.NewExternalServiceError("vertex", "x", true, errors.New("other"))) {
+		t.Fatalf("expected plain error not to be rate limited")
+	}
+}
+
+func TestNilPolicyRunsOnce(t *testing.T) {
+	var p *Policy
+	calls := 0
+	_ = p.Do(helpers.TestCtx(), "op", func(ctx context.Context) error {
+		calls++
+		return transientErr()
+	})
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
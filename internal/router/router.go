@@ -1,6 +1,8 @@
 package router
 
 import (
+	"expvar"
+
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 
@@ -17,17 +19,40 @@ func NewRouter(deps *handlers.Deps) chi.Router {
 
 	r.Use(chimiddleware.RequestID)   // 1. Generate request_id
 	r.Use(loggerMw.LoggerMiddleware) // 2. Add logger with request context
-	r.Use(auth.FirebaseAuth)         // 3. Add user context to logger
-	r.Use(chimiddleware.Logger)      // 4. Chi's HTTP logging
-	r.Use(chimiddleware.Recoverer)   // 5. Panic recovery
+	r.Use(chimiddleware.Logger)      // 3. Chi's HTTP logging
+	r.Use(chimiddleware.Recoverer)   // 4. Panic recovery
 
 	// handlers
 	ush := handlers.NewUserHandlers(deps)
 	ph := handlers.NewPlaidHandlers(deps)
 	aih := handlers.NewAIHandlers(deps)
+	ach := handlers.NewAccountHandlers(deps)
+	wh := handlers.NewWebhookHandlers(deps)
+	th := handlers.NewTransactionHandlers(deps)
+	anh := handlers.NewAnalyticsHandlers(deps)
+	bgh := handlers.NewBudgetHandlers(deps)
+	alh := handlers.NewAlertHandlers(deps)
+
+	// Plaid webhooks authenticate with a signed JWT rather than a Firebase token.
+	r.Post("/plaid/webhook", wh.PlaidWebhook)
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.FirebaseAuth) // Add user context to logger
+
+		r.Mount("/users", ush.UserRoutes())
+		r.Mount("/", ph.PlaidRoutes())
+		r.Mount("/ai", aih.AIRoutes())
+		r.Mount("/accounts", ach.AccountRoutes())
+		r.Mount("/analytics", anh.AnalyticsRoutes())
+		r.Mount("/budgets", bgh.BudgetRoutes())
+		r.Mount("/alerts", alh.AlertRoutes())
+
+		// Registered directly rather than mounted so POST /transactions/sync keeps
+		// routing to the Plaid handlers.
+		r.Get("/transactions", th.ListTransactions)
 
-	r.Mount("/users", ush.UserRoutes())
-	r.Mount("/", ph.PlaidRoutes())
-	r.Mount("/ai", aih.AIRoutes())
+		// Process metrics, such as the retry counters.
+		r.Handle("/debug/vars", expvar.Handler())
+	})
 	return r
 }
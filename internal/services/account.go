@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+
+	"github.com/GregMSThompson/finance-backend/internal/models"
+)
+
+type accountASStore interface {
+	List(ctx context.Context, uid string) ([]*models.Account, error)
+}
+
+type accountService struct {
+	accounts accountASStore
+}
+
+func NewAccountService(accounts accountASStore) *accountService {
+	return &accountService{accounts: accounts}
+}
+
+func (s *accountService) ListAccounts(ctx context.Context, uid string) ([]*models.Account, error) {
+	return s.accounts.List(ctx, uid)
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type accountFakeStore struct {
+	list []*models.Account
+	err  error
+	uid  string
+}
+
+func (f *accountFakeStore) List(ctx context.Context, uid string) ([]*models.Account, error) {
+	f.uid = uid
+	return f.list, f.err
+}
+
+func TestAccountServiceListAccounts(t *testing.T) {
+	store := &accountFakeStore{list: []*models.Account{{AccountID: "acc-1", Type: "credit"}}}
+	svc := NewAccountService(store)
+
+	ctx := helpers.TestCtx()
+	got, err := svc.ListAccounts(ctx, "uid-1")
+	if err != nil {
+		t.Fatalf("ListAccounts returned error: %v", err)
+	}
+	if store.uid != "uid-1" || len(got) != 1 || got[0].Type != "credit" {
+		t.Fatalf("unexpected accounts: %+v", got)
+	}
+}
+
+func TestAccountServiceListAccountsPropagatesError(t *testing.T) {
+	svc := NewAccountService(&accountFakeStore{err: errors.New("store down")})
+
+	ctx := helpers.TestCtx()
+	if _, err := svc.ListAccounts(ctx, "uid-1"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/GregMSThompson/finance-backend/internal/dto"
@@ -15,8 +18,13 @@ import (
 	"github.com/GregMSThompson/finance-backend/pkg/logger"
 )
 
+// maxToolSteps bounds how many rounds of tool calls one query may make before the
+// model must answer from the results it has.
+const maxToolSteps = 4
+
 type vertexClient interface {
 	GenerateContent(ctx context.Context, req dto.VertexGenerateRequest) (dto.VertexGenerateResponse, error)
+	GenerateContentStream(ctx context.Context, req dto.VertexGenerateRequest, onText func(string) error) (dto.VertexGenerateResponse, error)
 }
 
 type analyticsClient interface {
@@ -30,6 +38,13 @@ type analyticsClient interface {
 type aiStore interface {
 	SaveMessage(ctx context.Context, uid, sessionID string, msg models.AIMessage) error
 	ListMessages(ctx context.Context, uid, sessionID string, limit int) ([]models.AIMessage, error)
+	ListMessagesBefore(ctx context.Context, uid, sessionID string, limit int, before *dto.AIMessageCursor) ([]models.AIMessage, error)
+	CreateSession(ctx context.Context, uid string, session *models.AISession) error
+	ListSessions(ctx context.Context, uid string, limit int) ([]*models.AISession, error)
+	GetSession(ctx context.Context, uid, sessionID string) (*models.AISession, error)
+	RenameSession(ctx context.Context, uid, sessionID, title string) error
+	TouchSession(ctx context.Context, uid, sessionID string, activity dto.AISessionActivity) error
+	DeleteSession(ctx context.Context, uid, sessionID string) error
 }
 
 type aiService struct {
@@ -51,136 +66,238 @@ func NewAIService(vertex vertexClient, analysis analyticsClient, store aiStore,
 }
 
 func (s *aiService) Query(ctx context.Context, uid, sessionID, message string) (dto.AIQueryResponse, error) {
+	return s.query(ctx, uid, sessionID, message, nil)
+}
+
+// QueryStream answers like Query but reports progress through emit as it happens:
+// each tool call and a summary of its result, answer text as the model produces
+// it, and finally the complete response. An error from emit stops the query.
+func (s *aiService) QueryStream(ctx context.Context, uid, sessionID, message string, emit func(dto.AIStreamEvent) error) error {
+	resp, err := s.query(ctx, uid, sessionID, message, emit)
+	if err != nil {
+		return err
+	}
+	return emit(dto.AIStreamEvent{Type: dto.AIEventDone, Data: resp})
+}
+
+// query runs one question through the model and its tools. emit is nil for
+// non-streaming queries.
+func (s *aiService) query(ctx context.Context, uid, sessionID, message string, emit func(dto.AIStreamEvent) error) (dto.AIQueryResponse, error) {
 	log := logger.FromContext(ctx)
 
 	history, err := s.store.ListMessages(ctx, uid, sessionID, 8)
 	if err != nil {
 		return dto.AIQueryResponse{}, err
 	}
+	history = unexpiredMessages(history, s.clockNow())
 
-	contents := convertMessagesToContents(history, message)
-	req := dto.VertexGenerateRequest{
-		System:   systemPrompt(s.clockNow()),
-		Contents: contents,
-		Tools:    toolSchemas(),
-		ToolConfig: &dto.VertexToolConfig{
-			Mode: dto.FunctionCallingModeAuto,
-		},
-	}
+	// The turn is persisted only once the whole exchange has succeeded, so a failed
+	// model call or tool leaves no dangling user turn or tool results in the session.
+	// Messages keep the time they happened at, so they are saved in order.
+	turn := []models.AIMessage{{Role: "user", Content: message, CreatedAt: s.clockNow()}}
 
-	resp, err := s.vertex.GenerateContent(ctx, req)
-	if err != nil {
-		var malformed *errs.MalformedFunctionCallError
-		if errors.As(err, &malformed) {
-			strictReq := req
-			strictReq.System = strictSystemPrompt(s.clockNow())
-			resp, err = s.vertex.GenerateContent(ctx, strictReq)
-		}
-	}
+	contents := convertMessagesToContents(history, message)
+	resp, err := s.generate(ctx, contents, dto.FunctionCallingModeAuto, emit)
 	if err != nil {
 		return dto.AIQueryResponse{}, err
 	}
 
-	if len(resp.ToolCalls) == 0 {
-		if err := s.saveMessage(ctx, uid, sessionID, models.AIMessage{
-			Role:    "user",
-			Content: message,
-		}); err != nil {
-			return dto.AIQueryResponse{}, err
+	var debug *dto.AIDebugInfo
+	for step := 1; len(resp.ToolCalls) > 0; step++ {
+		// Not every model honours mode NONE, so the round budget is enforced here too.
+		if step > maxToolSteps {
+			log.Warn("model kept calling tools past the step limit", "session_id", sessionID, "tool_calls", len(resp.ToolCalls))
+			if resp.Text == "" {
+				return dto.AIQueryResponse{}, errs.NewExternalServiceError("vertex", "model did not answer within the tool step limit", false, nil)
+			}
+			resp.ToolCalls = nil
+			break
 		}
-		// Only save non-empty assistant responses
-		if resp.Text != "" {
-			if err := s.saveMessage(ctx, uid, sessionID, models.AIMessage{
-				Role:    "assistant",
-				Content: resp.Text,
-			}); err != nil {
-				return dto.AIQueryResponse{}, err
+		for _, call := range resp.ToolCalls {
+			if !isValidToolName(call.Name) {
+				return dto.AIQueryResponse{}, errs.NewValidationError(fmt.Sprintf("model requested unknown tool: %s", call.Name))
 			}
 		}
-		log.Info("ai query completed", "session_id", sessionID)
-		return dto.AIQueryResponse{Answer: resp.Text}, nil
-	}
 
-	// Handle multiple tool calls (currently only processing the first one)
-	if len(resp.ToolCalls) > 1 {
-		log.Warn("received multiple tool calls, only processing the first", "count", len(resp.ToolCalls))
-	}
+		if emit != nil {
+			for _, call := range resp.ToolCalls {
+				if err := emit(dto.AIStreamEvent{Type: dto.AIEventToolCall, Data: dto.AIStreamToolCall{Step: step, Tool: call.Name, Args: call.Args}}); err != nil {
+					return dto.AIQueryResponse{}, err
+				}
+			}
+		}
 
-	toolCall := resp.ToolCalls[0]
+		results, err := s.executeTools(ctx, uid, resp.ToolCalls)
+		if err != nil {
+			return dto.AIQueryResponse{}, err
+		}
 
-	// Validate tool call name before executing
-	if !isValidToolName(toolCall.Name) {
-		return dto.AIQueryResponse{}, errs.NewValidationError(fmt.Sprintf("model requested unknown tool: %s", toolCall.Name))
-	}
+		if emit != nil {
+			for _, result := range results {
+				if err := emit(dto.AIStreamEvent{Type: dto.AIEventToolResult, Data: dto.AIStreamToolResult{Step: step, Tool: result.Name, Summary: summarizeToolResult(result.Response)}}); err != nil {
+					return dto.AIQueryResponse{}, err
+				}
+			}
+		}
 
-	log.Info("executing tool", "tool", toolCall.Name)
+		callParts := make([]dto.VertexPart, 0, len(resp.ToolCalls))
+		resultParts := make([]dto.VertexPart, 0, len(results))
+		for i := range resp.ToolCalls {
+			call, result := resp.ToolCalls[i], results[i]
+			turn = append(turn, models.AIMessage{
+				Role:       "tool",
+				ToolName:   call.Name,
+				ToolArgs:   call.Args,
+				ToolResult: result.Response,
+				ToolStep:   step,
+				CreatedAt:  s.clockNow(),
+			})
+			callParts = append(callParts, dto.VertexPart{FunctionCall: &call})
+			resultParts = append(resultParts, dto.VertexPart{FunctionResponse: &result})
 
-	toolResult, err := s.executeTool(ctx, uid, toolCall)
-	if err != nil {
-		return dto.AIQueryResponse{}, fmt.Errorf("failed to execute tool %s: %w", toolCall.Name, err)
+			if debug == nil {
+				debug = &dto.AIDebugInfo{Tool: call.Name, Args: call.Args}
+			}
+			debug.Calls = append(debug.Calls, dto.AIDebugToolCall{Step: step, Tool: call.Name, Args: call.Args})
+		}
+
+		// Every call from the turn goes back in one model message, answered by one
+		// message carrying all of the results.
+		contents = append(contents,
+			dto.VertexContent{Role: "model", Parts: callParts},
+			dto.VertexContent{Role: "user", Parts: resultParts},
+		)
+
+		// Once the round budget is spent the model has to answer with what it has.
+		mode := dto.FunctionCallingModeAuto
+		if step >= maxToolSteps {
+			mode = dto.FunctionCallingModeNone
+		}
+		resp, err = s.generate(ctx, contents, mode, emit)
+		if err != nil {
+			return dto.AIQueryResponse{}, err
+		}
 	}
 
-	if err := s.saveMessage(ctx, uid, sessionID, models.AIMessage{
-		Role:    "user",
-		Content: message,
-	}); err != nil {
-		return dto.AIQueryResponse{}, err
+	// Only save non-empty assistant responses
+	if resp.Text != "" {
+		turn = append(turn, models.AIMessage{Role: "assistant", Content: resp.Text, CreatedAt: s.clockNow()})
 	}
-	if err := s.saveMessage(ctx, uid, sessionID, models.AIMessage{
-		Role:       "tool",
-		ToolName:   toolCall.Name,
-		ToolArgs:   toolCall.Args,
-		ToolResult: toolResult.Response,
-	}); err != nil {
-		return dto.AIQueryResponse{}, err
+	for _, msg := range turn {
+		if err := s.saveMessage(ctx, uid, sessionID, msg); err != nil {
+			return dto.AIQueryResponse{}, err
+		}
 	}
 
-	// For the second request after tool execution, add the tool result to contents
-	contentsWithToolResult := append(contents, dto.VertexContent{
-		Role: "model",
-		Parts: []dto.VertexPart{
-			{FunctionCall: &toolCall},
-		},
-	}, dto.VertexContent{
-		Role: "user",
-		Parts: []dto.VertexPart{
-			{FunctionResponse: &toolResult},
-		},
-	})
+	s.touchSession(ctx, uid, sessionID, message, resp.Text)
 
-	finalResp, err := s.vertex.GenerateContent(ctx, dto.VertexGenerateRequest{
-		System:   systemPrompt(s.clockNow()),
-		Contents: contentsWithToolResult,
+	if debug != nil {
+		log.Info("ai query completed", "session_id", sessionID, "tool_calls", len(debug.Calls))
+	} else {
+		log.Info("ai query completed", "session_id", sessionID)
+	}
+	return dto.AIQueryResponse{Answer: resp.Text, Debug: debug}, nil
+}
+
+// generate sends one turn to the model, streaming answer text through emit when it
+// is set.
+func (s *aiService) generate(ctx context.Context, contents []dto.VertexContent, mode dto.FunctionCallingMode, emit func(dto.AIStreamEvent) error) (dto.VertexGenerateResponse, error) {
+	send := s.vertex.GenerateContent
+	if emit != nil {
+		send = func(ctx context.Context, req dto.VertexGenerateRequest) (dto.VertexGenerateResponse, error) {
+			return s.vertex.GenerateContentStream(ctx, req, func(text string) error {
+				return emit(dto.AIStreamEvent{Type: dto.AIEventText, Data: dto.AIStreamText{Delta: text}})
+			})
+		}
+	}
+	return generateTurn(ctx, send, s.clockNow(), contents, mode)
+}
+
+// generateTurn sends contents to the model with the assistant's prompt and tools as
+// of now. A malformed function call is retried once with the stricter prompt.
+func generateTurn(ctx context.Context, send func(context.Context, dto.VertexGenerateRequest) (dto.VertexGenerateResponse, error), now time.Time, contents []dto.VertexContent, mode dto.FunctionCallingMode) (dto.VertexGenerateResponse, error) {
+	req := dto.VertexGenerateRequest{
+		System:   systemPrompt(now),
+		Contents: contents,
 		Tools:    toolSchemas(),
 		ToolConfig: &dto.VertexToolConfig{
-			Mode: dto.FunctionCallingModeNone,
+			Mode: mode,
 		},
-	})
+	}
+
+	resp, err := send(ctx, req)
 	if err != nil {
-		return dto.AIQueryResponse{}, err
+		var malformed *errs.MalformedFunctionCallError
+		if errors.As(err, &malformed) {
+			strictReq := req
+			strictReq.System = strictSystemPrompt(now)
+			resp, err = send(ctx, strictReq)
+		}
 	}
+	return resp, err
+}
 
-	if err := s.saveMessage(ctx, uid, sessionID, models.AIMessage{
-		Role:    "assistant",
-		Content: finalResp.Text,
-	}); err != nil {
-		return dto.AIQueryResponse{}, err
+// summarizeToolResult describes a tool result in a few words for stream clients,
+// e.g. "12 transactions" or "total 84.2 USD".
+func summarizeToolResult(result map[string]any) string {
+	var parts []string
+	for _, key := range []string{"transactions", "items"} {
+		if list, ok := result[key].([]any); ok {
+			parts = append(parts, fmt.Sprintf("%d %s", len(list), key))
+		}
+	}
+	for _, key := range []string{"total", "totalMonthlyEquivalent"} {
+		v, ok := result[key].(float64)
+		if !ok {
+			continue
+		}
+		part := "total " + strconv.FormatFloat(v, 'f', -1, 64)
+		if currency, _ := result["currency"].(string); currency != "" {
+			part += " " + currency
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "done"
 	}
+	return strings.Join(parts, ", ")
+}
 
-	log.Info("ai query completed", "session_id", sessionID, "tool", toolCall.Name)
-	return dto.AIQueryResponse{
-		Answer: finalResp.Text,
-		Debug: &dto.AIDebugInfo{
-			Tool: toolCall.Name,
-			Args: toolCall.Args,
-		},
-	}, nil
+// executeTools runs every call from one model turn concurrently. The tools only read
+// analytics, so calls within a turn can't depend on each other. Results keep the
+// order of calls.
+func (s *aiService) executeTools(ctx context.Context, uid string, calls []dto.VertexToolCall) ([]dto.VertexToolResult, error) {
+	log := logger.FromContext(ctx)
+
+	results := make([]dto.VertexToolResult, len(calls))
+	errList := make([]error, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		log.Info("executing tool", "tool", call.Name)
+		wg.Add(1)
+		go func(i int, call dto.VertexToolCall) {
+			defer wg.Done()
+			results[i], errList[i] = s.executeTool(ctx, uid, call)
+		}(i, call)
+	}
+	wg.Wait()
+
+	for i, err := range errList {
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute tool %s: %w", calls[i].Name, err)
+		}
+	}
+	return results, nil
 }
 
 func convertMessagesToContents(history []models.AIMessage, currentMessage string) []dto.VertexContent {
 	contents := make([]dto.VertexContent, 0, len(history)+1)
 
+	toolStep := 0 // step of the tool call just emitted, or 0
 	for _, msg := range history {
+		if msg.Role != "tool" {
+			toolStep = 0
+		}
 		switch msg.Role {
 		case "user":
 			contents = append(contents, dto.VertexContent{
@@ -201,30 +318,30 @@ func convertMessagesToContents(history []models.AIMessage, currentMessage string
 			}
 
 		case "tool":
-			// Tool calls and results need explicit function call/response parts.
-			if msg.ToolName != "" && msg.ToolArgs != nil {
-				contents = append(contents, dto.VertexContent{
-					Role: "model",
-					Parts: []dto.VertexPart{
-						{FunctionCall: &dto.VertexToolCall{
-							Name: msg.ToolName,
-							Args: msg.ToolArgs,
-						}},
-					},
-				})
+			// Tool calls and results need explicit function call/response parts. Calls made
+			// in the same step were one model turn, so they share a call and a response message.
+			if msg.ToolName == "" || msg.ToolResult == nil {
+				continue
 			}
-
-			if msg.ToolName != "" && msg.ToolResult != nil {
-				contents = append(contents, dto.VertexContent{
-					Role: "user",
-					Parts: []dto.VertexPart{
-						{FunctionResponse: &dto.VertexToolResult{
-							Name:     msg.ToolName,
-							Response: msg.ToolResult,
-						}},
-					},
-				})
+			call := dto.VertexPart{FunctionCall: &dto.VertexToolCall{
+				Name: msg.ToolName,
+				Args: msg.ToolArgs,
+			}}
+			result := dto.VertexPart{FunctionResponse: &dto.VertexToolResult{
+				Name:     msg.ToolName,
+				Response: msg.ToolResult,
+			}}
+			if msg.ToolStep > 0 && msg.ToolStep == toolStep {
+				last := len(contents) - 1
+				contents[last-1].Parts = append(contents[last-1].Parts, call)
+				contents[last].Parts = append(contents[last].Parts, result)
+				continue
 			}
+			toolStep = msg.ToolStep
+			contents = append(contents,
+				dto.VertexContent{Role: "model", Parts: []dto.VertexPart{call}},
+				dto.VertexContent{Role: "user", Parts: []dto.VertexPart{result}},
+			)
 		}
 	}
 
@@ -249,6 +366,19 @@ func (s *aiService) saveMessage(ctx context.Context, uid, sessionID string, msg
 	return s.store.SaveMessage(ctx, uid, sessionID, msg)
 }
 
+// unexpiredMessages drops messages past their expiresAt. Firestore TTL deletes them
+// eventually, but not promptly, so reads must not rely on it.
+func unexpiredMessages(msgs []models.AIMessage, now time.Time) []models.AIMessage {
+	out := msgs[:0]
+	for _, msg := range msgs {
+		if !msg.ExpiresAt.IsZero() && !msg.ExpiresAt.After(now) {
+			continue
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
 func (s *aiService) executeTool(ctx context.Context, uid string, call dto.VertexToolCall) (dto.VertexToolResult, error) {
 	switch call.Name {
 	case "get_spend_total":
@@ -390,12 +520,15 @@ func toolSchemas() []dto.VertexTool {
 			Parameters: &dto.VertexSchema{
 				Type: "object",
 				Properties: map[string]*dto.VertexSchema{
-					"pfcPrimary": {Type: "string", Enum: taxonomy.PFCPrimaryList, Description: "Primary category filter."},
-					"pending":    {Type: "boolean", Description: "Defaults to false if omitted."},
-					"bankId":     {Type: "string", Description: "Filter by bank id."},
-					"merchant":   {Type: "string", Description: "Partial, case-insensitive merchant name filter."},
-					"dateFrom":   {Type: "string", Description: "YYYY-MM-DD start date; defaults to month-to-date."},
-					"dateTo":     {Type: "string", Description: "YYYY-MM-DD end date; defaults to today when month-to-date."},
+					"pfcPrimary":       {Type: "string", Enum: taxonomy.PFCPrimaryList, Description: "Primary category filter."},
+					"pending":          {Type: "boolean", Description: "Defaults to false if omitted."},
+					"bankId":           {Type: "string", Description: "Filter by bank id."},
+					"merchant":         {Type: "string", Description: "Partial, case-insensitive merchant name filter."},
+					"dateFrom":         {Type: "string", Description: "YYYY-MM-DD start date; defaults to month-to-date."},
+					"dateTo":           {Type: "string", Description: "YYYY-MM-DD end date; defaults to today when month-to-date."},
+					"baseCurrency":     {Type: "string", Description: "ISO currency code to convert totals into. Omit to get per-currency totals."},
+					"direction":        {Type: "string", Enum: []string{dto.FlowOutflow, dto.FlowInflow, dto.FlowNet}, Description: "outflow (spending, the default), inflow (income and refunds), or net."},
+					"includeTransfers": {Type: "boolean", Description: "Count transfers between the user's own accounts. Defaults to false."},
 				},
 			},
 		},
@@ -415,6 +548,9 @@ func toolSchemas() []dto.VertexTool {
 						"merchant",
 						"day",
 					}, Description: "Required. Group by category, merchant, or day."},
+					"baseCurrency":     {Type: "string", Description: "ISO currency code to convert totals into. Omit to get per-currency totals."},
+					"direction":        {Type: "string", Enum: []string{dto.FlowOutflow, dto.FlowInflow, dto.FlowNet}, Description: "outflow (spending, the default), inflow (income and refunds), or net."},
+					"includeTransfers": {Type: "boolean", Description: "Count transfers between the user's own accounts. Defaults to false."},
 				},
 				Required: []string{"groupBy"},
 			},
@@ -446,9 +582,10 @@ func toolSchemas() []dto.VertexTool {
 			Parameters: &dto.VertexSchema{
 				Type: "object",
 				Properties: map[string]*dto.VertexSchema{
-					"dateFrom": {Type: "string", Description: "YYYY-MM-DD start of the lookback window. Required. Default to 3 months ago."},
-					"dateTo":   {Type: "string", Description: "YYYY-MM-DD end of the lookback window. Required. Default to today."},
-					"bankId":   {Type: "string", Description: "Filter by bank id."},
+					"dateFrom":     {Type: "string", Description: "YYYY-MM-DD start of the lookback window. Required. Default to 3 months ago."},
+					"dateTo":       {Type: "string", Description: "YYYY-MM-DD end of the lookback window. Required. Default to today."},
+					"bankId":       {Type: "string", Description: "Filter by bank id."},
+					"baseCurrency": {Type: "string", Description: "ISO currency code to convert totals into. Omit to get per-currency totals."},
 				},
 				Required: []string{"dateFrom", "dateTo"},
 			},
@@ -468,10 +605,13 @@ func toolSchemas() []dto.VertexTool {
 						"merchant",
 						"day",
 					}, Description: "Optional. Group comparison by category, merchant, or day. Omit for totals only."},
-					"pfcPrimary": {Type: "string", Enum: taxonomy.PFCPrimaryList, Description: "Primary category filter."},
-					"pending":    {Type: "boolean", Description: "Defaults to false if omitted."},
-					"bankId":     {Type: "string", Description: "Filter by bank id."},
-					"merchant":   {Type: "string", Description: "Partial, case-insensitive merchant name filter."},
+					"pfcPrimary":       {Type: "string", Enum: taxonomy.PFCPrimaryList, Description: "Primary category filter."},
+					"pending":          {Type: "boolean", Description: "Defaults to false if omitted."},
+					"bankId":           {Type: "string", Description: "Filter by bank id."},
+					"merchant":         {Type: "string", Description: "Partial, case-insensitive merchant name filter."},
+					"baseCurrency":     {Type: "string", Description: "ISO currency code to convert totals into. Omit to get per-currency totals."},
+					"direction":        {Type: "string", Enum: []string{dto.FlowOutflow, dto.FlowInflow, dto.FlowNet}, Description: "outflow (spending, the default), inflow (income and refunds), or net."},
+					"includeTransfers": {Type: "boolean", Description: "Count transfers between the user's own accounts. Defaults to false."},
 				},
 				Required: []string{"currentFrom", "currentTo", "previousFrom", "previousTo"},
 			},
@@ -483,7 +623,8 @@ func systemPrompt(now time.Time) string {
 	today := now.Format("2006-01-02")
 	weekday := now.Weekday().String()
 	return "You are a finance analytics assistant. Use tools for deterministic queries. " +
-		"Make only one tool call per request. For multi-part questions, address the primary question first. " +
+		"Call several tools in one turn when a question needs independent figures, and call more tools after seeing results when an answer depends on them. " +
+		"For multi-part questions, answer every part. " +
 		"Calculate date ranges from natural language (e.g., 'last week', 'this month'). A week is defined as Monday to Sunday. " +
 		"All financial data (transactions, amounts, categories) must come from tool results - never fabricate these. " +
 		"If a query is ambiguous (e.g., which category?), ask for clarification. " +
@@ -552,10 +693,10 @@ func toMap(value any) (map[string]any, error) {
 
 func isValidToolName(name string) bool {
 	validTools := map[string]bool{
-		"get_spend_total":          true,
-		"get_spend_breakdown":      true,
-		"get_transactions":         true,
-		"get_period_comparison":    true,
+		"get_spend_total":            true,
+		"get_spend_breakdown":        true,
+		"get_transactions":           true,
+		"get_period_comparison":      true,
 		"get_recurring_transactions": true,
 	}
 	return validTools[name]
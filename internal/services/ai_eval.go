@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+)
+
+type aiEvalService struct {
+	vertex   vertexClient
+	clockNow func() time.Time
+}
+
+func NewAIEvalService(vertex vertexClient) *aiEvalService {
+	return &aiEvalService{vertex: vertex, clockNow: time.Now}
+}
+
+// RunSuite asks the model each question with the assistant's prompt and tools and
+// checks the tool calls of its first turn against the case; any one call may match.
+// Tools are not executed. Model errors fail the case rather than the run; only a bad
+// suite is returned as an error.
+func (s *aiEvalService) RunSuite(ctx context.Context, suite dto.AIEvalSuite) (dto.AIEvalReport, error) {
+	report := dto.AIEvalReport{Results: []dto.AIEvalResult{}}
+
+	now := s.clockNow()
+	if suite.Now != "" {
+		day, err := time.Parse("2006-01-02", suite.Now)
+		if err != nil {
+			return report, errs.NewValidationError("invalid now: expected YYYY-MM-DD")
+		}
+		now = day.Add(12 * time.Hour)
+	}
+	for i, c := range suite.Cases {
+		if c.Question == "" {
+			return report, errs.NewValidationError(fmt.Sprintf("case %d has no question", i+1))
+		}
+		if c.Tool != "" && !isValidToolName(c.Tool) {
+			return report, errs.NewValidationError(fmt.Sprintf("case %d expects unknown tool: %s", i+1, c.Tool))
+		}
+	}
+
+	for i, c := range suite.Cases {
+		result := dto.AIEvalResult{Name: c.Name}
+		if result.Name == "" {
+			result.Name = fmt.Sprintf("case %d", i+1)
+		}
+
+		contents := convertMessagesToContents(nil, c.Question)
+		resp, err := generateTurn(ctx, s.vertex.GenerateContent, now, contents, dto.FunctionCallingModeAuto)
+		if err != nil {
+			result.Reason = fmt.Sprintf("model error: %v", err)
+		} else {
+			var call dto.VertexToolCall
+			call, result.Reason = evalTurn(c, resp.ToolCalls)
+			result.Tool, result.Args = call.Name, call.Args
+			result.Passed = result.Reason == ""
+		}
+
+		report.Total++
+		if result.Passed {
+			report.Passed++
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if report.Total > 0 {
+		report.Accuracy = float64(report.Passed) / float64(report.Total)
+	}
+	return report, nil
+}
+
+// evalTurn returns the call that matches c, with an empty reason, or the closest
+// call with a description of how it differs: the first call to the expected tool,
+// else the first call.
+func evalTurn(c dto.AIEvalCase, calls []dto.VertexToolCall) (dto.VertexToolCall, string) {
+	if c.Tool == "" {
+		if len(calls) > 0 {
+			return calls[0], fmt.Sprintf("expected no tool call, got %s", calls[0].Name)
+		}
+		return dto.VertexToolCall{}, ""
+	}
+	if len(calls) == 0 {
+		return dto.VertexToolCall{}, fmt.Sprintf("expected %s, got no tool call", c.Tool)
+	}
+
+	var closest *dto.VertexToolCall
+	var reason string
+	names := make([]string, 0, len(calls))
+	for i, call := range calls {
+		names = append(names, call.Name)
+		if call.Name != c.Tool {
+			continue
+		}
+		diff := argsMismatch(c.Args, call.Args)
+		if diff == "" {
+			return call, ""
+		}
+		if closest == nil {
+			closest, reason = &calls[i], diff
+		}
+	}
+	if closest != nil {
+		return *closest, reason
+	}
+	return calls[0], fmt.Sprintf("expected %s, got %s", c.Tool, strings.Join(names, ", "))
+}
+
+// argsMismatch lists the expected args that got lacks or holds a different value
+// for, or returns "" when all match. Args the case doesn't mention are ignored.
+func argsMismatch(want, got map[string]any) string {
+	keys := make([]string, 0, len(want))
+	for key := range want {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var diffs []string
+	for _, key := range keys {
+		w, g := jsonValue(want[key]), jsonValue(got[key])
+		if _, ok := got[key]; !ok {
+			g = "missing"
+		}
+		if w != g {
+			diffs = append(diffs, fmt.Sprintf("%s: expected %s, got %s", key, w, g))
+		}
+	}
+	return strings.Join(diffs, "; ")
+}
+
+// jsonValue renders v as JSON so numbers compare equal whether they came from a
+// suite file or a model response.
+func jsonValue(v any) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(raw)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+func TestRunSuiteScoresToolCalls(t *testing.T) {
+	vertex := &fakeVertexClient{
+		responses: []dto.VertexGenerateResponse{
+			{ToolCalls: []dto.VertexToolCall{{Name: "get_transactions", Args: map[string]any{"limit": float64(5), "desc": true}}}},
+			{ToolCalls: []dto.VertexToolCall{{Name: "get_spend_total", Args: map[string]any{"dateFrom": "2025-02-01"}}}},
+			{ToolCalls: []dto.VertexToolCall{{Name: "get_spend_total"}}},
+			{Text: "Hello!"},
+			{ToolCalls: []dto.VertexToolCall{
+				{Name: "get_spend_total", Args: map[string]any{"pfcPrimary": "DINING"}},
+				{Name: "get_spend_total", Args: map[string]any{"pfcPrimary": "GROCERIES"}},
+			}},
+		},
+	}
+	svc := NewAIEvalService(vertex)
+
+	suite := dto.AIEvalSuite{
+		Now: "2025-02-19",
+		Cases: []dto.AIEvalCase{
+			{Name: "top 5", Question: "Largest 5?", Tool: "get_transactions", Args: map[string]any{"limit": 5, "desc": true}},
+			{Name: "last week", Question: "Last week?", Tool: "get_spend_total", Args: map[string]any{"dateFrom": "2025-02-10"}},
+			{Name: "breakdown", Question: "By category?", Tool: "get_spend_breakdown"},
+			{Question: "Hi"},
+			{Name: "two totals", Question: "Dining and groceries?", Tool: "get_spend_total", Args: map[string]any{"pfcPrimary": "GROCERIES"}},
+		},
+	}
+	report, err := svc.RunSuite(helpers.TestCtx(), suite)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.Total != 5 || report.Passed != 3 || report.Accuracy != 0.6 {
+		t.Fatalf("unexpected score: %+v", report)
+	}
+	if r := report.Results[1]; r.Passed || r.Reason != `dateFrom: expected "2025-02-10", got "2025-02-01"` {
+		t.Fatalf("unexpected arg mismatch: %+v", r)
+	}
+	if r := report.Results[2]; r.Passed || r.Reason != "expected get_spend_breakdown, got get_spend_total" {
+		t.Fatalf("unexpected tool mismatch: %+v", r)
+	}
+	if r := report.Results[3]; !r.Passed || r.Name != "case 4" {
+		t.Fatalf("expected the no-tool case to pass: %+v", r)
+	}
+	if r := report.Results[4]; !r.Passed || r.Args["pfcPrimary"] != "GROCERIES" {
+		t.Fatalf("expected the second call in the turn to match: %+v", r)
+	}
+	if !strings.Contains(vertex.requests[0].System, "Today is 2025-02-19") {
+		t.Fatalf("expected the suite date in the prompt, got %q", vertex.requests[0].System)
+	}
+}
+
+func TestRunSuiteModelErrorFailsCase(t *testing.T) {
+	vertex := &fakeVertexClient{errors: []error{errors.New("quota exceeded")}}
+	svc := NewAIEvalService(vertex)
+
+	report, err := svc.RunSuite(helpers.TestCtx(), dto.AIEvalSuite{
+		Cases: []dto.AIEvalCase{{Name: "total", Question: "Total?", Tool: "get_spend_total"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := report.Results[0]; r.Passed || !strings.Contains(r.Reason, "quota exceeded") {
+		t.Fatalf("expected a failed case, got %+v", r)
+	}
+}
+
+func TestRunSuiteRejectsBadSuite(t *testing.T) {
+	svc := NewAIEvalService(&fakeVertexClient{})
+
+	for _, suite := range []dto.AIEvalSuite{
+		{Now: "19/02/2025", Cases: []dto.AIEvalCase{{Question: "Total?"}}},
+		{Cases: []dto.AIEvalCase{{Name: "empty"}}},
+		{Cases: []dto.AIEvalCase{{Question: "Total?", Tool: "get_balance"}}},
+	} {
+		_, err := svc.RunSuite(helpers.TestCtx(), suite)
+		var vErr *errs.ValidationError
+		if !errors.As(err, &vErr) {
+			t.Fatalf("expected validation error for %+v, got %v", suite, err)
+		}
+	}
+}
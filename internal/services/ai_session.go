@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/logger"
+)
+
+const (
+	defaultSessionListLimit = 20
+	maxSessionListLimit     = 100
+
+	defaultMessagePageSize = 50
+	maxMessagePageSize     = 200
+
+	maxSessionTitleLen = 80
+	maxPreviewLen      = 120
+)
+
+// messagePageToken is the decoded form of a message page token.
+type messagePageToken struct {
+	CreatedAt time.Time `json:"t"`
+	MessageID string    `json:"id"`
+}
+
+// CreateSession starts an empty session. With a TTL it expires like a message sent
+// now would, so sessions that never get a message are cleaned up too.
+func (s *aiService) CreateSession(ctx context.Context, uid, title string) (*models.AISession, error) {
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) > maxSessionTitleLen {
+		return nil, errs.NewValidationError("title is too long")
+	}
+	session := &models.AISession{Title: title}
+	if s.ttl > 0 {
+		session.ExpiresAt = s.clockNow().Add(s.ttl)
+	}
+	if err := s.store.CreateSession(ctx, uid, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ListSessions returns the user's sessions, most recently active first. A
+// non-positive limit uses the default; larger limits are capped.
+func (s *aiService) ListSessions(ctx context.Context, uid string, limit int) ([]*models.AISession, error) {
+	if limit <= 0 {
+		limit = defaultSessionListLimit
+	}
+	if limit > maxSessionListLimit {
+		limit = maxSessionListLimit
+	}
+	sessions, err := s.store.ListSessions(ctx, uid, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sessions whose messages have all expired are left for TTL or the sweeper.
+	now := s.clockNow()
+	live := sessions[:0]
+	for _, session := range sessions {
+		if !session.ExpiresAt.IsZero() && !session.ExpiresAt.After(now) {
+			continue
+		}
+		live = append(live, session)
+	}
+	return live, nil
+}
+
+func (s *aiService) RenameSession(ctx context.Context, uid, sessionID, title string) (*models.AISession, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, errs.NewValidationError("title is required")
+	}
+	if utf8.RuneCountInString(title) > maxSessionTitleLen {
+		return nil, errs.NewValidationError("title is too long")
+	}
+	if err := s.store.RenameSession(ctx, uid, sessionID, title); err != nil {
+		return nil, err
+	}
+	return s.store.GetSession(ctx, uid, sessionID)
+}
+
+func (s *aiService) DeleteSession(ctx context.Context, uid, sessionID string) error {
+	if err := s.store.DeleteSession(ctx, uid, sessionID); err != nil {
+		return err
+	}
+	log := logger.FromContext(ctx)
+	log.Info("ai session deleted", "session_id", sessionID)
+	return nil
+}
+
+// ListSessionMessages pages backwards through a session: the first page holds the
+// newest messages and each token fetches the ones before it.
+func (s *aiService) ListSessionMessages(ctx context.Context, uid, sessionID string, limit int, token string) (dto.AIMessagePage, error) {
+	page := dto.AIMessagePage{Messages: []models.AIMessage{}}
+
+	if limit <= 0 {
+		limit = defaultMessagePageSize
+	}
+	if limit > maxMessagePageSize {
+		limit = maxMessagePageSize
+	}
+
+	var before *dto.AIMessageCursor
+	if token != "" {
+		cursor, err := decodeMessagePageToken(token)
+		if err != nil {
+			return page, err
+		}
+		before = cursor
+	}
+
+	// Fetch one extra row to learn whether an older page exists.
+	msgs, err := s.store.ListMessagesBefore(ctx, uid, sessionID, limit+1, before)
+	if err != nil {
+		return page, err
+	}
+	msgs = unexpiredMessages(msgs, s.clockNow())
+	if len(msgs) > limit {
+		// Messages come back oldest first, so the extra row is the first one.
+		msgs = msgs[1:]
+		next, err := encodeMessagePageToken(messagePageToken{
+			CreatedAt: msgs[0].CreatedAt,
+			MessageID: msgs[0].MessageID,
+		})
+		if err != nil {
+			return page, err
+		}
+		page.NextPageToken = next
+	}
+	page.Messages = append(page.Messages, msgs...)
+	return page, nil
+}
+
+// touchSession records the finished turn on the session. The first user message
+// becomes the title of an untitled session.
+func (s *aiService) touchSession(ctx context.Context, uid, sessionID, message, answer string) {
+	preview := answer
+	if preview == "" {
+		preview = message
+	}
+	activity := dto.AISessionActivity{
+		Title:       truncateText(message, maxSessionTitleLen),
+		LastMessage: truncateText(preview, maxPreviewLen),
+		At:          s.clockNow(),
+	}
+	if s.ttl > 0 {
+		activity.ExpiresAt = activity.At.Add(s.ttl)
+	}
+	err := s.store.TouchSession(ctx, uid, sessionID, activity)
+	if err != nil {
+		log := logger.FromContext(ctx)
+		log.Warn("failed to update ai session", "session_id", sessionID, "error", err)
+	}
+}
+
+// truncateText collapses whitespace and shortens s to at most max runes, cutting at
+// a word boundary where possible.
+func truncateText(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	cut := string(runes[:max-1])
+	if i := strings.LastIndex(cut, " "); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
+
+func encodeMessagePageToken(t messagePageToken) (string, error) {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("encode page token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeMessagePageToken(token string) (*dto.AIMessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errs.NewValidationError("invalid pageToken")
+	}
+	var t messagePageToken
+	if err := json.Unmarshal(raw, &t); err != nil || t.MessageID == "" || t.CreatedAt.IsZero() {
+		return nil, errs.NewValidationError("invalid pageToken")
+	}
+	return &dto.AIMessageCursor{CreatedAt: t.CreatedAt, MessageID: t.MessageID}, nil
+}
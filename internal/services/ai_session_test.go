@@ -0,0 +1,187 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+func TestAIQueryTouchesSession(t *testing.T) {
+	vertex := &fakeVertexClient{
+		responses: []dto.VertexGenerateResponse{{Text: "You spent $5 on coffee this month."}},
+	}
+	store := &fakeAIStore{}
+	svc := NewAIService(vertex, &fakeAnalyticsClient{}, store, 0)
+	now := time.Date(2025, time.February, 15, 12, 0, 0, 0, time.UTC)
+	svc.clockNow = func() time.Time { return now }
+
+	if _, err := svc.Query(helpers.TestCtx(), "uid", "s1", "  How much   did I spend on coffee?"); err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if len(store.activity) != 1 {
+		t.Fatalf("expected session to be touched once, got %d", len(store.activity))
+	}
+	got := store.activity[0]
+	if got.Title != "How much did I spend on coffee?" || got.LastMessage != "You spent $5 on coffee this month." || !got.At.Equal(now) {
+		t.Fatalf("unexpected activity: %+v", got)
+	}
+}
+
+func TestListSessionMessagesPagesBackwards(t *testing.T) {
+	store := &fakeAIStore{}
+	for i := 1; i <= 5; i++ {
+		_ = store.SaveMessage(helpers.TestCtx(), "uid", "s1", models.AIMessage{
+			Role:      "user",
+			CreatedAt: time.Date(2025, 1, 1, 0, i, 0, 0, time.UTC),
+		})
+	}
+	svc := NewAIService(&fakeVertexClient{}, &fakeAnalyticsClient{}, store, 0)
+	ctx := helpers.TestCtx()
+
+	var pages [][]string
+	token := ""
+	for {
+		page, err := svc.ListSessionMessages(ctx, "uid", "s1", 2, token)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var ids []string
+		for _, m := range page.Messages {
+			ids = append(ids, m.MessageID)
+		}
+		pages = append(pages, ids)
+		if page.NextPageToken == "" {
+			break
+		}
+		token = page.NextPageToken
+	}
+
+	want := "m4,m5|m2,m3|m1"
+	var got []string
+	for _, ids := range pages {
+		got = append(got, strings.Join(ids, ","))
+	}
+	if strings.Join(got, "|") != want {
+		t.Fatalf("unexpected pages: %v", got)
+	}
+}
+
+func TestListSessionMessagesRejectsBadToken(t *testing.T) {
+	svc := NewAIService(&fakeVertexClient{}, &fakeAnalyticsClient{}, &fakeAIStore{}, 0)
+
+	_, err := svc.ListSessionMessages(helpers.TestCtx(), "uid", "s1", 0, "not-a-token")
+	var vErr *errs.ValidationError
+	if !errors.As(err, &vErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
+
+func TestRenameSession(t *testing.T) {
+	store := &fakeAIStore{}
+	svc := NewAIService(&fakeVertexClient{}, &fakeAnalyticsClient{}, store, 0)
+	ctx := helpers.TestCtx()
+
+	session, err := svc.CreateSession(ctx, "uid", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var vErr *errs.ValidationError
+	if _, err := svc.RenameSession(ctx, "uid", session.SessionID, "  "); !errors.As(err, &vErr) {
+		t.Fatalf("expected validation error for empty title, got %v", err)
+	}
+	renamed, err := svc.RenameSession(ctx, "uid", session.SessionID, " Groceries ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if renamed.Title != "Groceries" || store.renamed != session.SessionID {
+		t.Fatalf("unexpected rename: %+v", renamed)
+	}
+
+	var nfErr *errs.NotFoundError
+	if _, err := svc.RenameSession(ctx, "uid", "missing", "x"); !errors.As(err, &nfErr) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestCreateSessionSetsExpiry(t *testing.T) {
+	store := &fakeAIStore{}
+	svc := NewAIService(&fakeVertexClient{}, &fakeAnalyticsClient{}, store, 24*time.Hour)
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	svc.clockNow = func() time.Time { return now }
+
+	session, err := svc.CreateSession(helpers.TestCtx(), "uid", "Groceries")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !session.ExpiresAt.Equal(now.Add(24 * time.Hour)) {
+		t.Fatalf("unexpected expiry: %v", session.ExpiresAt)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	if got := truncateText("short", 10); got != "short" {
+		t.Fatalf("unexpected: %q", got)
+	}
+	if got := truncateText("how much did I spend", 12); got != "how much…" {
+		t.Fatalf("unexpected: %q", got)
+	}
+	if got := truncateText("supercalifragilistic", 6); got != "super…" {
+		t.Fatalf("unexpected: %q", got)
+	}
+}
+
+func TestAIReadsSkipExpiredMessagesAndSessions(t *testing.T) {
+	now := time.Date(2025, time.February, 15, 12, 0, 0, 0, time.UTC)
+	store := &fakeAIStore{
+		sessions: []*models.AISession{
+			{SessionID: "live", ExpiresAt: now.Add(time.Hour)},
+			{SessionID: "expired", ExpiresAt: now.Add(-time.Hour)},
+			{SessionID: "no-ttl"},
+		},
+	}
+	for _, msg := range []models.AIMessage{
+		{Role: "user", Content: "old question", ExpiresAt: now.Add(-time.Minute)},
+		{Role: "assistant", Content: "old answer", ExpiresAt: now},
+		{Role: "user", Content: "recent question", ExpiresAt: now.Add(time.Minute)},
+	} {
+		_ = store.SaveMessage(helpers.TestCtx(), "uid", "live", msg)
+	}
+	vertex := &fakeVertexClient{responses: []dto.VertexGenerateResponse{{Text: "ok"}}}
+	svc := NewAIService(vertex, &fakeAnalyticsClient{}, store, time.Hour)
+	svc.clockNow = func() time.Time { return now }
+	ctx := helpers.TestCtx()
+
+	sessions, err := svc.ListSessions(ctx, "uid", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].SessionID != "live" || sessions[1].SessionID != "no-ttl" {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+
+	page, err := svc.ListSessionMessages(ctx, "uid", "live", 0, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Messages) != 1 || page.Messages[0].Content != "recent question" {
+		t.Fatalf("unexpected messages: %+v", page.Messages)
+	}
+
+	if _, err := svc.Query(ctx, "uid", "live", "next question"); err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	// History is the one live message plus the new question.
+	if got := len(vertex.requests[0].Contents); got != 2 {
+		t.Fatalf("expected expired messages to be left out of history, got %d contents", got)
+	}
+	if exp := store.activity[0].ExpiresAt; !exp.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected session expiry to follow the TTL, got %v", exp)
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/pkg/logger"
+)
+
+// aiSweepStore finds and removes expired AI data across all users.
+type aiSweepStore interface {
+	DeleteExpiredMessages(ctx context.Context, before time.Time) (int, []dto.UserSession, error)
+	ListExpiredSessions(ctx context.Context, before time.Time) ([]dto.UserSession, error)
+	DeleteSessionIfEmpty(ctx context.Context, uid, sessionID string) (bool, error)
+}
+
+// aiSweeperService enforces the AI message TTL where Firestore's native TTL isn't
+// available, such as the emulator. In production it only catches what TTL hasn't
+// deleted yet.
+type aiSweeperService struct {
+	store    aiSweepStore
+	clockNow func() time.Time
+}
+
+func NewAISweeperService(store aiSweepStore) *aiSweeperService {
+	return &aiSweeperService{
+		store:    store,
+		clockNow: time.Now,
+	}
+}
+
+// SweepExpired deletes expired messages, then removes sessions that have no messages
+// left: those the deleted messages belonged to and those whose own expiry has passed.
+func (s *aiSweeperService) SweepExpired(ctx context.Context) (dto.AISweepResult, error) {
+	result := dto.AISweepResult{}
+	log := logger.FromContext(ctx)
+	now := s.clockNow()
+
+	deleted, touched, err := s.store.DeleteExpiredMessages(ctx, now)
+	if err != nil {
+		return result, err
+	}
+	result.MessagesDeleted = deleted
+
+	expired, err := s.store.ListExpiredSessions(ctx, now)
+	if err != nil {
+		return result, err
+	}
+
+	seen := make(map[dto.UserSession]bool, len(touched)+len(expired))
+	for _, us := range append(touched, expired...) {
+		if seen[us] {
+			continue
+		}
+		seen[us] = true
+
+		removed, err := s.store.DeleteSessionIfEmpty(ctx, us.UID, us.SessionID)
+		if err != nil {
+			return result, err
+		}
+		if removed {
+			result.SessionsDeleted++
+		}
+	}
+
+	log.Info("ai sweep completed", "messages_deleted", result.MessagesDeleted, "sessions_deleted", result.SessionsDeleted)
+	return result, nil
+}
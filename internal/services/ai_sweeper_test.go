@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type fakeSweepStore struct {
+	deletedMessages int
+	touched         []dto.UserSession
+	expired         []dto.UserSession
+	nonEmpty        map[dto.UserSession]bool
+	checked         []dto.UserSession
+	before          time.Time
+	err             error
+}
+
+func (f *fakeSweepStore) DeleteExpiredMessages(ctx context.Context, before time.Time) (int, []dto.UserSession, error) {
+	f.before = before
+	return f.deletedMessages, f.touched, f.err
+}
+
+func (f *fakeSweepStore) ListExpiredSessions(ctx context.Context, before time.Time) ([]dto.UserSession, error) {
+	return f.expired, nil
+}
+
+func (f *fakeSweepStore) DeleteSessionIfEmpty(ctx context.Context, uid, sessionID string) (bool, error) {
+	us := dto.UserSession{UID: uid, SessionID: sessionID}
+	f.checked = append(f.checked, us)
+	return !f.nonEmpty[us], nil
+}
+
+func TestSweepExpiredDeletesEmptySessions(t *testing.T) {
+	a := dto.UserSession{UID: "u1", SessionID: "s1"}
+	b := dto.UserSession{UID: "u1", SessionID: "s2"}
+	c := dto.UserSession{UID: "u2", SessionID: "s1"}
+	store := &fakeSweepStore{
+		deletedMessages: 7,
+		touched:         []dto.UserSession{a, b},
+		expired:         []dto.UserSession{b, c},
+		nonEmpty:        map[dto.UserSession]bool{b: true},
+	}
+	svc := NewAISweeperService(store)
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	svc.clockNow = func() time.Time { return now }
+
+	res, err := svc.SweepExpired(helpers.TestCtx())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !store.before.Equal(now) {
+		t.Fatalf("expected expiry cutoff %v, got %v", now, store.before)
+	}
+	if len(store.checked) != 3 {
+		t.Fatalf("expected each session checked once, got %v", store.checked)
+	}
+	if res.MessagesDeleted != 7 || res.SessionsDeleted != 2 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestSweepExpiredPropagatesErrors(t *testing.T) {
+	store := &fakeSweepStore{err: errors.New("firestore down")}
+	svc := NewAISweeperService(store)
+
+	if _, err := svc.SweepExpired(helpers.TestCtx()); !errors.Is(err, store.err) {
+		t.Fatalf("expected store error, got %v", err)
+	}
+	if len(store.checked) != 0 {
+		t.Fatalf("sessions should not be checked after a failure")
+	}
+}
@@ -3,13 +3,16 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/GregMSThompson/finance-backend/internal/dto"
 	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/money"
 	"github.com/GregMSThompson/finance-backend/pkg/helpers"
 )
 
@@ -34,7 +37,26 @@ func (f *fakeVertexClient) GenerateContent(ctx context.Context, req dto.VertexGe
 	return resp, nil
 }
 
+// GenerateContentStream replays the next response, passing its text to onText one
+// word at a time.
+func (f *fakeVertexClient) GenerateContentStream(ctx context.Context, req dto.VertexGenerateRequest, onText func(string) error) (dto.VertexGenerateResponse, error) {
+	resp, err := f.GenerateContent(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	for _, word := range strings.SplitAfter(resp.Text, " ") {
+		if word == "" {
+			continue
+		}
+		if err := onText(word); err != nil {
+			return dto.VertexGenerateResponse{}, err
+		}
+	}
+	return resp, nil
+}
+
 type fakeAnalyticsClient struct {
+	mu                sync.Mutex
 	totalCalls        int
 	totalArgs         dto.AnalyticsSpendTotalArgs
 	totalResp         dto.AnalyticsSpendTotalResult
@@ -58,6 +80,8 @@ type fakeAnalyticsClient struct {
 }
 
 func (f *fakeAnalyticsClient) GetSpendTotal(ctx context.Context, uid string, args dto.AnalyticsSpendTotalArgs) (dto.AnalyticsSpendTotalResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.totalCalls++
 	f.totalArgs = args
 	if f.totalErr != nil {
@@ -67,6 +91,8 @@ func (f *fakeAnalyticsClient) GetSpendTotal(ctx context.Context, uid string, arg
 }
 
 func (f *fakeAnalyticsClient) GetSpendBreakdown(ctx context.Context, uid string, args dto.AnalyticsSpendBreakdownArgs) (dto.AnalyticsSpendBreakdownResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.breakdownCalls++
 	f.breakdownArgs = args
 	if f.breakdownErr != nil {
@@ -76,6 +102,8 @@ func (f *fakeAnalyticsClient) GetSpendBreakdown(ctx context.Context, uid string,
 }
 
 func (f *fakeAnalyticsClient) GetTransactions(ctx context.Context, uid string, args dto.AnalyticsTransactionsArgs) (dto.AnalyticsTransactionsResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.transactionsCalls++
 	f.transactionsArgs = args
 	if f.transactionsErr != nil {
@@ -85,6 +113,8 @@ func (f *fakeAnalyticsClient) GetTransactions(ctx context.Context, uid string, a
 }
 
 func (f *fakeAnalyticsClient) GetPeriodComparison(ctx context.Context, uid string, args dto.AnalyticsPeriodComparisonArgs) (dto.AnalyticsPeriodComparisonResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.comparisonCalls++
 	f.comparisonArgs = args
 	if f.comparisonErr != nil {
@@ -94,6 +124,8 @@ func (f *fakeAnalyticsClient) GetPeriodComparison(ctx context.Context, uid strin
 }
 
 func (f *fakeAnalyticsClient) GetRecurringTransactions(ctx context.Context, uid string, args dto.AnalyticsRecurringArgs) (dto.RecurringTransactionsResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.recurringCalls++
 	f.recurringArgs = args
 	if f.recurringErr != nil {
@@ -104,9 +136,14 @@ func (f *fakeAnalyticsClient) GetRecurringTransactions(ctx context.Context, uid
 
 type fakeAIStore struct {
 	messages []models.AIMessage
+	sessions []*models.AISession
+	activity []dto.AISessionActivity
+	renamed  string
+	deleted  string
 }
 
 func (f *fakeAIStore) SaveMessage(ctx context.Context, uid, sessionID string, msg models.AIMessage) error {
+	msg.MessageID = fmt.Sprintf("m%d", len(f.messages)+1)
 	f.messages = append(f.messages, msg)
 	return nil
 }
@@ -118,6 +155,61 @@ func (f *fakeAIStore) ListMessages(ctx context.Context, uid, sessionID string, l
 	return append([]models.AIMessage{}, f.messages...), nil
 }
 
+func (f *fakeAIStore) ListMessagesBefore(ctx context.Context, uid, sessionID string, limit int, before *dto.AIMessageCursor) ([]models.AIMessage, error) {
+	end := len(f.messages)
+	if before != nil {
+		for i, m := range f.messages {
+			if m.MessageID == before.MessageID {
+				end = i
+			}
+		}
+	}
+	start := 0
+	if limit > 0 && end > limit {
+		start = end - limit
+	}
+	return append([]models.AIMessage{}, f.messages[start:end]...), nil
+}
+
+func (f *fakeAIStore) CreateSession(ctx context.Context, uid string, session *models.AISession) error {
+	session.SessionID = fmt.Sprintf("s%d", len(f.sessions)+1)
+	f.sessions = append(f.sessions, session)
+	return nil
+}
+
+func (f *fakeAIStore) ListSessions(ctx context.Context, uid string, limit int) ([]*models.AISession, error) {
+	return f.sessions, nil
+}
+
+func (f *fakeAIStore) GetSession(ctx context.Context, uid, sessionID string) (*models.AISession, error) {
+	for _, session := range f.sessions {
+		if session.SessionID == sessionID {
+			return session, nil
+		}
+	}
+	return nil, errs.NewNotFoundError("AI session not found")
+}
+
+func (f *fakeAIStore) RenameSession(ctx context.Context, uid, sessionID, title string) error {
+	session, err := f.GetSession(ctx, uid, sessionID)
+	if err != nil {
+		return err
+	}
+	session.Title = title
+	f.renamed = sessionID
+	return nil
+}
+
+func (f *fakeAIStore) TouchSession(ctx context.Context, uid, sessionID string, activity dto.AISessionActivity) error {
+	f.activity = append(f.activity, activity)
+	return nil
+}
+
+func (f *fakeAIStore) DeleteSession(ctx context.Context, uid, sessionID string) error {
+	f.deleted = sessionID
+	return nil
+}
+
 func TestAIQueryToolFlow(t *testing.T) {
 	vertex := &fakeVertexClient{
 		responses: []dto.VertexGenerateResponse{
@@ -130,7 +222,7 @@ func TestAIQueryToolFlow(t *testing.T) {
 		},
 	}
 	analytics := &fakeAnalyticsClient{
-		totalResp: dto.AnalyticsSpendTotalResult{Total: 5, Currency: "USD"},
+		totalResp: dto.AnalyticsSpendTotalResult{Total: money.New(500, "USD"), Currency: "USD"},
 	}
 	store := &fakeAIStore{}
 	svc := NewAIService(vertex, analytics, store, 0)
@@ -200,9 +292,12 @@ func TestAIQueryUnknownTool(t *testing.T) {
 	if err == nil {
 		t.Fatalf("expected error for unknown tool")
 	}
+	if len(store.messages) != 0 {
+		t.Fatalf("failed turn should not be saved, got %+v", store.messages)
+	}
 }
 
-func TestAIQueryMultipleToolCallsUsesFirst(t *testing.T) {
+func TestAIQueryExecutesAllToolCalls(t *testing.T) {
 	vertex := &fakeVertexClient{
 		responses: []dto.VertexGenerateResponse{
 			{
@@ -215,21 +310,168 @@ func TestAIQueryMultipleToolCallsUsesFirst(t *testing.T) {
 		},
 	}
 	analytics := &fakeAnalyticsClient{
-		totalResp: dto.AnalyticsSpendTotalResult{Total: 1, Currency: "USD"},
+		totalResp: dto.AnalyticsSpendTotalResult{Total: money.New(100, "USD"), Currency: "USD"},
 	}
 	store := &fakeAIStore{}
 	svc := NewAIService(vertex, analytics, store, 0)
 
 	ctx := helpers.TestCtx()
-	_, err := svc.Query(ctx, "user", "session", "Multi")
+	resp, err := svc.Query(ctx, "user", "session", "Multi")
 	if err != nil {
 		t.Fatalf("Query error: %v", err)
 	}
-	if analytics.totalCalls != 1 {
-		t.Fatalf("expected spend total call, got %d", analytics.totalCalls)
+	if analytics.totalCalls != 1 || analytics.transactionsCalls != 1 {
+		t.Fatalf("expected both tools to run: total=%d tx=%d", analytics.totalCalls, analytics.transactionsCalls)
+	}
+	if resp.Debug == nil || len(resp.Debug.Calls) != 2 || resp.Debug.Tool != "get_spend_total" {
+		t.Fatalf("unexpected debug info: %+v", resp.Debug)
+	}
+
+	// The results go back as one model turn with both calls and one reply with both results.
+	if len(vertex.requests) != 2 {
+		t.Fatalf("expected 2 vertex requests, got %d", len(vertex.requests))
+	}
+	contents := vertex.requests[1].Contents
+	calls, results := contents[len(contents)-2], contents[len(contents)-1]
+	if calls.Role != "model" || len(calls.Parts) != 2 || calls.Parts[1].FunctionCall.Name != "get_transactions" {
+		t.Fatalf("unexpected call content: %+v", calls)
+	}
+	if results.Role != "user" || len(results.Parts) != 2 || results.Parts[0].FunctionResponse.Name != "get_spend_total" {
+		t.Fatalf("unexpected result content: %+v", results)
+	}
+
+	roles := make([]string, 0, len(store.messages))
+	for _, msg := range store.messages {
+		roles = append(roles, msg.Role)
+		if msg.Role == "tool" && msg.ToolStep != 1 {
+			t.Fatalf("expected tool step 1, got %d", msg.ToolStep)
+		}
+	}
+	if strings.Join(roles, ",") != "user,tool,tool,assistant" {
+		t.Fatalf("unexpected saved messages: %v", roles)
+	}
+}
+
+func TestAIQueryRunsFollowUpToolRounds(t *testing.T) {
+	vertex := &fakeVertexClient{
+		responses: []dto.VertexGenerateResponse{
+			{ToolCalls: []dto.VertexToolCall{{Name: "get_spend_total", Args: map[string]any{}}}},
+			{ToolCalls: []dto.VertexToolCall{{Name: "get_spend_breakdown", Args: map[string]any{"groupBy": "merchant"}}}},
+			{Text: "Mostly coffee."},
+		},
+	}
+	analytics := &fakeAnalyticsClient{}
+	store := &fakeAIStore{}
+	svc := NewAIService(vertex, analytics, store, 0)
+
+	resp, err := svc.Query(helpers.TestCtx(), "user", "session", "Where did it go?")
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if resp.Answer != "Mostly coffee." {
+		t.Fatalf("answer mismatch: %q", resp.Answer)
+	}
+	if analytics.totalCalls != 1 || analytics.breakdownCalls != 1 {
+		t.Fatalf("expected one call per round: total=%d breakdown=%d", analytics.totalCalls, analytics.breakdownCalls)
+	}
+	for i, req := range vertex.requests {
+		if req.ToolConfig.Mode != dto.FunctionCallingModeAuto {
+			t.Fatalf("request %d: expected AUTO mode, got %s", i, req.ToolConfig.Mode)
+		}
+	}
+	if resp.Debug == nil || len(resp.Debug.Calls) != 2 || resp.Debug.Calls[1].Step != 2 {
+		t.Fatalf("unexpected debug info: %+v", resp.Debug)
+	}
+}
+
+func TestAIQueryForcesAnswerAfterMaxToolSteps(t *testing.T) {
+	toolTurn := dto.VertexGenerateResponse{ToolCalls: []dto.VertexToolCall{{Name: "get_spend_total", Args: map[string]any{}}}}
+	responses := make([]dto.VertexGenerateResponse, 0, maxToolSteps+1)
+	for i := 0; i < maxToolSteps; i++ {
+		responses = append(responses, toolTurn)
+	}
+	responses = append(responses, dto.VertexGenerateResponse{Text: "Here is what I found."})
+	vertex := &fakeVertexClient{responses: responses}
+	analytics := &fakeAnalyticsClient{}
+	svc := NewAIService(vertex, analytics, &fakeAIStore{}, 0)
+
+	resp, err := svc.Query(helpers.TestCtx(), "user", "session", "Keep digging")
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if resp.Answer != "Here is what I found." {
+		t.Fatalf("answer mismatch: %q", resp.Answer)
 	}
-	if analytics.transactionsCalls != 0 || analytics.breakdownCalls != 0 {
-		t.Fatalf("unexpected analytics calls: tx=%d breakdown=%d", analytics.transactionsCalls, analytics.breakdownCalls)
+	if analytics.totalCalls != maxToolSteps {
+		t.Fatalf("expected %d tool calls, got %d", maxToolSteps, analytics.totalCalls)
+	}
+	last := vertex.requests[len(vertex.requests)-1]
+	if last.ToolConfig.Mode != dto.FunctionCallingModeNone {
+		t.Fatalf("expected final request to disable tools, got %s", last.ToolConfig.Mode)
+	}
+}
+
+func TestAIQueryStopsWhenModelIgnoresModeNone(t *testing.T) {
+	// A model that keeps calling tools even when told not to.
+	toolTurn := dto.VertexGenerateResponse{ToolCalls: []dto.VertexToolCall{{Name: "get_spend_total", Args: map[string]any{}}}}
+	responses := make([]dto.VertexGenerateResponse, 0, maxToolSteps+3)
+	for i := 0; i < maxToolSteps; i++ {
+		responses = append(responses, toolTurn)
+	}
+	responses = append(responses,
+		dto.VertexGenerateResponse{Text: "Partial answer.", ToolCalls: toolTurn.ToolCalls},
+		toolTurn, toolTurn,
+	)
+	vertex := &fakeVertexClient{responses: responses}
+	analytics := &fakeAnalyticsClient{}
+	svc := NewAIService(vertex, analytics, &fakeAIStore{}, 0)
+
+	resp, err := svc.Query(helpers.TestCtx(), "user", "session", "Keep digging")
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if resp.Answer != "Partial answer." {
+		t.Fatalf("answer mismatch: %q", resp.Answer)
+	}
+	if analytics.totalCalls != maxToolSteps || len(vertex.requests) != maxToolSteps+1 {
+		t.Fatalf("expected the loop to stop at the step limit, got %d tool calls and %d model calls", analytics.totalCalls, len(vertex.requests))
+	}
+
+	// Without any text to fall back on, the query fails instead of looping.
+	toolsOnly := make([]dto.VertexGenerateResponse, 0, maxToolSteps+3)
+	for i := 0; i < maxToolSteps+3; i++ {
+		toolsOnly = append(toolsOnly, toolTurn)
+	}
+	vertex = &fakeVertexClient{responses: toolsOnly}
+	svc = NewAIService(vertex, &fakeAnalyticsClient{}, &fakeAIStore{}, 0)
+	if _, err := svc.Query(helpers.TestCtx(), "user", "session", "Keep digging"); err == nil {
+		t.Fatalf("expected an error when the model never answers")
+	}
+	if len(vertex.requests) != maxToolSteps+1 {
+		t.Fatalf("expected %d model calls, got %d", maxToolSteps+1, len(vertex.requests))
+	}
+}
+
+func TestConvertMessagesGroupsToolStep(t *testing.T) {
+	history := []models.AIMessage{
+		{Role: "user", Content: "Question"},
+		{Role: "tool", ToolName: "get_spend_total", ToolArgs: map[string]any{}, ToolResult: map[string]any{"total": 1}, ToolStep: 1},
+		{Role: "tool", ToolName: "get_transactions", ToolArgs: map[string]any{}, ToolResult: map[string]any{"count": 2}, ToolStep: 1},
+		{Role: "tool", ToolName: "get_spend_breakdown", ToolArgs: map[string]any{}, ToolResult: map[string]any{"items": 3}, ToolStep: 2},
+		{Role: "assistant", Content: "Answer"},
+	}
+
+	contents := convertMessagesToContents(history, "Next")
+
+	// user, step 1 call+result, step 2 call+result, assistant, current message
+	if len(contents) != 7 {
+		t.Fatalf("expected 7 contents, got %d", len(contents))
+	}
+	if len(contents[1].Parts) != 2 || len(contents[2].Parts) != 2 {
+		t.Fatalf("expected step 1 calls grouped, got %d and %d parts", len(contents[1].Parts), len(contents[2].Parts))
+	}
+	if len(contents[3].Parts) != 1 || contents[3].Parts[0].FunctionCall.Name != "get_spend_breakdown" {
+		t.Fatalf("expected step 2 in its own turn: %+v", contents[3])
 	}
 }
 
@@ -254,6 +496,27 @@ func TestAIQueryAnalyticsErrorPropagates(t *testing.T) {
 	if err == nil {
 		t.Fatalf("expected error from analytics")
 	}
+	if len(store.messages) != 0 {
+		t.Fatalf("failed turn should not be saved, got %+v", store.messages)
+	}
+}
+
+func TestAIQueryFailedFollowUpSavesNothing(t *testing.T) {
+	vertex := &fakeVertexClient{
+		responses: []dto.VertexGenerateResponse{
+			{ToolCalls: []dto.VertexToolCall{{Name: "get_spend_total", Args: map[string]any{}}}},
+			// no answer configured for the follow-up call
+		},
+	}
+	store := &fakeAIStore{}
+	svc := NewAIService(vertex, &fakeAnalyticsClient{}, store, 0)
+
+	if _, err := svc.Query(helpers.TestCtx(), "user", "session", "How much?"); err == nil {
+		t.Fatalf("expected error from the follow-up call")
+	}
+	if len(store.messages) != 0 {
+		t.Fatalf("failed turn should not be saved, got %+v", store.messages)
+	}
 }
 
 func TestAIQueryDoesNotRetryOnOtherErrors(t *testing.T) {
@@ -304,3 +567,67 @@ func TestAIQueryRetriesWithStrictPromptOnMalformedCall(t *testing.T) {
 		t.Fatalf("expected strict prompt on retry")
 	}
 }
+
+func TestAIQueryStreamEvents(t *testing.T) {
+	vertex := &fakeVertexClient{
+		responses: []dto.VertexGenerateResponse{
+			{ToolCalls: []dto.VertexToolCall{{Name: "get_spend_total", Args: map[string]any{}}}},
+			{Text: "You spent $5."},
+		},
+	}
+	analytics := &fakeAnalyticsClient{
+		totalResp: dto.AnalyticsSpendTotalResult{Total: money.New(500, "USD"), Currency: "USD"},
+	}
+	svc := NewAIService(vertex, analytics, &fakeAIStore{}, 0)
+
+	var events []dto.AIStreamEvent
+	err := svc.QueryStream(helpers.TestCtx(), "user", "session", "How much did I spend?", func(ev dto.AIStreamEvent) error {
+		events = append(events, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("QueryStream error: %v", err)
+	}
+
+	var types []string
+	var text strings.Builder
+	for _, ev := range events {
+		types = append(types, ev.Type)
+		if delta, ok := ev.Data.(dto.AIStreamText); ok {
+			text.WriteString(delta.Delta)
+		}
+	}
+	want := "tool_call,tool_result,text,text,text,done"
+	if got := strings.Join(types, ","); got != want {
+		t.Fatalf("event order mismatch: got %s, want %s", got, want)
+	}
+	if call := events[0].Data.(dto.AIStreamToolCall); call.Step != 1 || call.Tool != "get_spend_total" {
+		t.Fatalf("unexpected tool call event: %+v", call)
+	}
+	if result := events[1].Data.(dto.AIStreamToolResult); result.Summary != "total 5 USD" {
+		t.Fatalf("unexpected tool result summary: %q", result.Summary)
+	}
+	if text.String() != "You spent $5." {
+		t.Fatalf("streamed text mismatch: %q", text.String())
+	}
+	if done := events[len(events)-1].Data.(dto.AIQueryResponse); done.Answer != "You spent $5." {
+		t.Fatalf("done answer mismatch: %q", done.Answer)
+	}
+}
+
+func TestAIQueryStreamStopsWhenEmitFails(t *testing.T) {
+	vertex := &fakeVertexClient{responses: []dto.VertexGenerateResponse{{Text: "Hello there."}}}
+	store := &fakeAIStore{}
+	svc := NewAIService(vertex, &fakeAnalyticsClient{}, store, 0)
+
+	gone := errors.New("client went away")
+	err := svc.QueryStream(helpers.TestCtx(), "user", "session", "hi", func(dto.AIStreamEvent) error {
+		return gone
+	})
+	if !errors.Is(err, gone) {
+		t.Fatalf("expected emit error, got %v", err)
+	}
+	if len(store.messages) != 0 {
+		t.Fatalf("expected nothing saved after a failed stream, got %+v", store.messages)
+	}
+}
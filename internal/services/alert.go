@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/money"
+)
+
+const (
+	defaultAlertListLimit = 50
+	maxAlertListLimit     = 200
+
+	// Every user gets an alert for a charge at least largeTransactionFactor times
+	// their average at the same merchant, once there are largeTransactionMinHistory
+	// earlier charges to average. Only the newest largeTransactionHistory are read.
+	largeTransactionRuleID     = "large_transaction"
+	largeTransactionRuleName   = "Unusually large transaction"
+	largeTransactionFactor     = 3
+	largeTransactionMinHistory = 3
+	largeTransactionHistory    = 50
+	// Older transactions arriving in a sync are backfill rather than new spending.
+	largeTransactionMaxAge = 7 * 24 * time.Hour
+)
+
+type alertASStore interface {
+	CreateRule(ctx context.Context, uid string, rule *models.AlertRule) error
+	ListRules(ctx context.Context, uid string) ([]*models.AlertRule, error)
+	DeleteRule(ctx context.Context, uid, ruleID string) error
+	CreateAlerts(ctx context.Context, uid string, alerts []models.Alert) ([]models.Alert, error)
+	ListAlerts(ctx context.Context, uid string, unacknowledgedOnly bool, limit int) ([]*models.Alert, error)
+	Acknowledge(ctx context.Context, uid, alertID string, at time.Time) error
+}
+
+// transactionASStore answers whether a merchant has been seen before and what the
+// user usually spends there.
+type transactionASStore interface {
+	ListIDsByName(ctx context.Context, uid, name string, limit int) ([]string, error)
+	ListByName(ctx context.Context, uid, name string, limit int) ([]*models.Transaction, error)
+}
+
+type alertService struct {
+	alerts   alertASStore
+	txs      transactionASStore
+	notify   notifier
+	clockNow func() time.Time
+}
+
+// NewAlertService builds the alert service. notify may be nil to skip push notifications.
+func NewAlertService(alerts alertASStore, txs transactionASStore, notify notifier) *alertService {
+	return &alertService{
+		alerts:   alerts,
+		txs:      txs,
+		notify:   notify,
+		clockNow: time.Now,
+	}
+}
+
+func (s *alertService) CreateRule(ctx context.Context, uid string, in dto.AlertRuleInput) (*models.AlertRule, error) {
+	rule := &models.AlertRule{
+		Name:        strings.TrimSpace(in.Name),
+		PFCPrimary:  in.PFCPrimary,
+		Merchant:    strings.TrimSpace(in.Merchant),
+		BankID:      in.BankID,
+		NewMerchant: in.NewMerchant,
+	}
+	if err := validatePrimary(&rule.PFCPrimary); err != nil {
+		return nil, err
+	}
+	if in.MinAmount != nil {
+		rule.Currency = strings.ToUpper(strings.TrimSpace(in.Currency))
+		if len(rule.Currency) != 3 {
+			return nil, errs.NewValidationError("currency must be a 3-letter ISO code when minAmount is set")
+		}
+		rule.MinAmountMinor = money.FromFloat(*in.MinAmount, rule.Currency).Minor
+		if rule.MinAmountMinor <= 0 {
+			return nil, errs.NewValidationError("minAmount must be greater than zero")
+		}
+	}
+	if rule.MinAmountMinor == 0 && rule.PFCPrimary == "" && rule.Merchant == "" && rule.BankID == "" && !rule.NewMerchant {
+		return nil, errs.NewValidationError("an alert rule needs at least one condition")
+	}
+
+	if err := s.alerts.CreateRule(ctx, uid, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *alertService) ListRules(ctx context.Context, uid string) ([]*models.AlertRule, error) {
+	return s.alerts.ListRules(ctx, uid)
+}
+
+func (s *alertService) DeleteRule(ctx context.Context, uid, ruleID string) error {
+	return s.alerts.DeleteRule(ctx, uid, ruleID)
+}
+
+func (s *alertService) ListAlerts(ctx context.Context, uid string, unacknowledgedOnly bool, limit int) ([]*models.Alert, error) {
+	if limit <= 0 {
+		limit = defaultAlertListLimit
+	}
+	if limit > maxAlertListLimit {
+		limit = maxAlertListLimit
+	}
+	return s.alerts.ListAlerts(ctx, uid, unacknowledgedOnly, limit)
+}
+
+func (s *alertService) AcknowledgeAlert(ctx context.Context, uid, alertID string) error {
+	return s.alerts.Acknowledge(ctx, uid, alertID, s.clockNow())
+}
+
+// EvaluateTransactions checks newly stored transactions against the user's rules and
+// the built-in unusually-large-transaction check, persists an alert for every match
+// and pushes new alerts to the user's devices. It returns how many alerts were new.
+// Pending transactions are skipped: Plaid gives the posted transaction a new id, so
+// alerting on both would report one purchase twice.
+func (s *alertService) EvaluateTransactions(ctx context.Context, uid string, txs []models.Transaction) (int, error) {
+	if len(txs) == 0 {
+		return 0, nil
+	}
+	rules, err := s.alerts.ListRules(ctx, uid)
+	if err != nil {
+		return 0, err
+	}
+
+	merchants := newMerchantChecker(s.txs, uid, txs)
+	now := s.clockNow()
+	recentFrom := now.Add(-largeTransactionMaxAge).Format("2006-01-02")
+	alerts := make([]models.Alert, 0)
+	for _, tx := range txs {
+		if tx.Pending {
+			continue
+		}
+		for _, rule := range rules {
+			if !ruleMatches(rule, tx) {
+				continue
+			}
+			if rule.NewMerchant {
+				isNew, err := merchants.isNew(ctx, tx.Name)
+				if err != nil {
+					return 0, err
+				}
+				if !isNew {
+					continue
+				}
+			}
+			alerts = append(alerts, newAlert(rule.RuleID, rule.Name, tx, now))
+		}
+		if tx.Date >= recentFrom {
+			isLarge, err := merchants.isLarge(ctx, tx)
+			if err != nil {
+				return 0, err
+			}
+			if isLarge {
+				alerts = append(alerts, newAlert(largeTransactionRuleID, largeTransactionRuleName, tx, now))
+			}
+		}
+	}
+	created, err := s.alerts.CreateAlerts(ctx, uid, alerts)
+	if len(created) > 0 {
+		sendNotification(ctx, s.notify, uid, alertNotification(created))
+	}
+	return len(created), err
+}
+
+func newAlert(ruleID, ruleName string, tx models.Transaction, now time.Time) models.Alert {
+	return models.Alert{
+		AlertID:       ruleID + "_" + tx.TransactionID,
+		RuleID:        ruleID,
+		RuleName:      ruleName,
+		TransactionID: tx.TransactionID,
+		BankID:        tx.BankID,
+		Name:          tx.Name,
+		AmountMinor:   tx.AmountMinor,
+		Currency:      tx.Currency,
+		Date:          tx.Date,
+		PFCPrimary:    tx.PFCPrimary,
+		CreatedAt:     now,
+	}
+}
+
+// ruleMatches checks every condition except NewMerchant, which needs the store.
+func ruleMatches(rule *models.AlertRule, tx models.Transaction) bool {
+	if rule.BankID != "" && tx.BankID != rule.BankID {
+		return false
+	}
+	if rule.PFCPrimary != "" && tx.PFCPrimary != rule.PFCPrimary {
+		return false
+	}
+	if rule.Merchant != "" && !strings.Contains(strings.ToLower(tx.Name), strings.ToLower(rule.Merchant)) {
+		return false
+	}
+	// Thresholds apply to charges in the rule's own currency; Plaid amounts are
+	// positive for money leaving the account.
+	if rule.MinAmountMinor > 0 && (tx.Currency != rule.Currency || tx.AmountMinor < rule.MinAmountMinor) {
+		return false
+	}
+	return true
+}
+
+// merchantChecker compares transactions with the user's history at the same
+// merchant name. A name is new when the only stored transactions with it are the
+// ones being evaluated.
+type merchantChecker struct {
+	txs     transactionASStore
+	uid     string
+	batch   map[string]int // name -> transactions with that name in the batch
+	ids     map[string]bool
+	cached  map[string]bool
+	history map[string][]*models.Transaction
+}
+
+func newMerchantChecker(txs transactionASStore, uid string, batch []models.Transaction) *merchantChecker {
+	c := &merchantChecker{
+		txs:     txs,
+		uid:     uid,
+		batch:   map[string]int{},
+		ids:     map[string]bool{},
+		cached:  map[string]bool{},
+		history: map[string][]*models.Transaction{},
+	}
+	for _, tx := range batch {
+		c.batch[tx.Name]++
+		c.ids[tx.TransactionID] = true
+	}
+	return c
+}
+
+func (c *merchantChecker) isNew(ctx context.Context, name string) (bool, error) {
+	if name == "" {
+		return false, nil
+	}
+	if isNew, ok := c.cached[name]; ok {
+		return isNew, nil
+	}
+	ids, err := c.txs.ListIDsByName(ctx, c.uid, name, c.batch[name]+1)
+	if err != nil {
+		return false, err
+	}
+	isNew := true
+	for _, id := range ids {
+		if !c.ids[id] {
+			isNew = false
+			break
+		}
+	}
+	c.cached[name] = isNew
+	return isNew, nil
+}
+
+// isLarge reports whether tx is a charge of at least largeTransactionFactor times the
+// average of the earlier posted charges with its name and currency.
+func (c *merchantChecker) isLarge(ctx context.Context, tx models.Transaction) (bool, error) {
+	if tx.Name == "" || tx.AmountMinor <= 0 {
+		return false, nil
+	}
+	history, ok := c.history[tx.Name]
+	if !ok {
+		var err error
+		history, err = c.txs.ListByName(ctx, c.uid, tx.Name, c.batch[tx.Name]+largeTransactionHistory)
+		if err != nil {
+			return false, err
+		}
+		c.history[tx.Name] = history
+	}
+
+	var total, count int64
+	for _, h := range history {
+		if c.ids[h.TransactionID] || h.Pending || h.Currency != tx.Currency || h.AmountMinor <= 0 {
+			continue
+		}
+		total += h.AmountMinor
+		count++
+	}
+	if count < largeTransactionMinHistory {
+		return false, nil
+	}
+	return tx.AmountMinor*count >= largeTransactionFactor*total, nil
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type alertFakeStore struct {
+	rules    []*models.AlertRule
+	created  []models.Alert
+	existing map[string]bool
+	ackID    string
+	ackAt    time.Time
+	limit    int
+}
+
+func (f *alertFakeStore) CreateRule(ctx context.Context, uid string, rule *models.AlertRule) error {
+	rule.RuleID = "rule-new"
+	f.rules = append(f.rules, rule)
+	return nil
+}
+
+func (f *alertFakeStore) ListRules(ctx context.Context, uid string) ([]*models.AlertRule, error) {
+	return f.rules, nil
+}
+
+func (f *alertFakeStore) DeleteRule(ctx context.Context, uid, ruleID string) error {
+	return nil
+}
+
+func (f *alertFakeStore) CreateAlerts(ctx context.Context, uid string, alerts []models.Alert) ([]models.Alert, error) {
+	created := make([]models.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if f.existing[a.AlertID] {
+			continue
+		}
+		created = append(created, a)
+	}
+	f.created = append(f.created, created...)
+	return created, nil
+}
+
+func (f *alertFakeStore) ListAlerts(ctx context.Context, uid string, unacknowledgedOnly bool, limit int) ([]*models.Alert, error) {
+	f.limit = limit
+	return nil, nil
+}
+
+func (f *alertFakeStore) Acknowledge(ctx context.Context, uid, alertID string, at time.Time) error {
+	f.ackID, f.ackAt = alertID, at
+	return nil
+}
+
+// alertFakeTxStore maps transaction names to the ids stored under them, and to
+// the stored transactions for amount history.
+type alertFakeTxStore struct {
+	byName  map[string][]string
+	history map[string][]*models.Transaction
+	calls   int
+}
+
+func (f *alertFakeTxStore) ListIDsByName(ctx context.Context, uid, name string, limit int) ([]string, error) {
+	f.calls++
+	ids := f.byName[name]
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+func (f *alertFakeTxStore) ListByName(ctx context.Context, uid, name string, limit int) ([]*models.Transaction, error) {
+	f.calls++
+	txs := f.history[name]
+	if limit > 0 && len(txs) > limit {
+		txs = txs[:limit]
+	}
+	return txs, nil
+}
+
+func TestCreateAlertRuleValidates(t *testing.T) {
+	cases := map[string]dto.AlertRuleInput{
+		"no conditions":    {Name: "empty"},
+		"missing currency": {MinAmount: helpers.Ptr(500.0)},
+		"zero amount":      {MinAmount: helpers.Ptr(0.0), Currency: "USD"},
+		"bad primary":      {PFCPrimary: "NOT_A_CATEGORY"},
+	}
+	for name, in := range cases {
+		svc := NewAlertService(&alertFakeStore{}, &alertFakeTxStore{}, nil)
+		_, err := svc.CreateRule(helpers.TestCtx(), "uid", in)
+		var ve *errs.ValidationError
+		if !errors.As(err, &ve) {
+			t.Fatalf("%s: expected validation error, got %v", name, err)
+		}
+	}
+}
+
+func TestCreateAlertRuleStoresMinorUnits(t *testing.T) {
+	store := &alertFakeStore{}
+	svc := NewAlertService(store, &alertFakeTxStore{}, nil)
+
+	rule, err := svc.CreateRule(helpers.TestCtx(), "uid", dto.AlertRuleInput{Name: "Big", MinAmount: helpers.Ptr(500.0), Currency: "usd"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule.RuleID != "rule-new" || rule.MinAmountMinor != 50000 || rule.Currency != "USD" {
+		t.Fatalf("unexpected rule: %+v", rule)
+	}
+}
+
+func TestEvaluateTransactionsMatchesRules(t *testing.T) {
+	store := &alertFakeStore{rules: []*models.AlertRule{
+		{RuleID: "big", Name: "Big charge", MinAmountMinor: 50000, Currency: "USD"},
+		{RuleID: "dining", Name: "Dining at cafe", PFCPrimary: "DINING", Merchant: "cafe"},
+		{RuleID: "bank", BankID: "bank-2"},
+	}}
+	svc := NewAlertService(store, &alertFakeTxStore{}, nil)
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	svc.clockNow = func() time.Time { return now }
+
+	txs := []models.Transaction{
+		{TransactionID: "t1", BankID: "bank-1", Name: "Airline", AmountMinor: 60000, Currency: "USD"},
+		{TransactionID: "t2", BankID: "bank-1", Name: "Airline", AmountMinor: 60000, Currency: "EUR"},
+		{TransactionID: "t3", BankID: "bank-1", Name: "Corner Cafe", AmountMinor: 450, Currency: "USD", PFCPrimary: "DINING"},
+		{TransactionID: "t4", BankID: "bank-1", Name: "Cafe Refund", AmountMinor: -450, Currency: "USD", PFCPrimary: "GENERAL_MERCHANDISE"},
+		{TransactionID: "t5", BankID: "bank-2", Name: "Pending", AmountMinor: 99999, Currency: "USD", Pending: true},
+	}
+	created, err := svc.EvaluateTransactions(helpers.TestCtx(), "uid", txs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created != 2 || len(store.created) != 2 {
+		t.Fatalf("expected 2 alerts, got %d: %+v", created, store.created)
+	}
+	first, second := store.created[0], store.created[1]
+	if first.AlertID != "big_t1" || first.RuleName != "Big charge" || first.AmountMinor != 60000 || !first.CreatedAt.Equal(now) {
+		t.Fatalf("unexpected first alert: %+v", first)
+	}
+	if second.AlertID != "dining_t3" {
+		t.Fatalf("unexpected second alert: %+v", second)
+	}
+}
+
+func TestEvaluateTransactionsNewMerchant(t *testing.T) {
+	store := &alertFakeStore{rules: []*models.AlertRule{
+		{RuleID: "new-travel", PFCPrimary: "TRAVEL", NewMerchant: true},
+	}}
+	txStore := &alertFakeTxStore{byName: map[string][]string{
+		"Hotel":   {"t1"},
+		"Airline": {"old", "t2"},
+		"Rail":    {"t3", "t4"},
+	}}
+	svc := NewAlertService(store, txStore, nil)
+
+	txs := []models.Transaction{
+		{TransactionID: "t1", Name: "Hotel", PFCPrimary: "TRAVEL"},
+		{TransactionID: "t2", Name: "Airline", PFCPrimary: "TRAVEL"},
+		{TransactionID: "t3", Name: "Rail", PFCPrimary: "TRAVEL"},
+		{TransactionID: "t4", Name: "Rail", PFCPrimary: "TRAVEL"},
+	}
+	if _, err := svc.EvaluateTransactions(helpers.TestCtx(), "uid", txs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make([]string, 0, len(store.created))
+	for _, a := range store.created {
+		got = append(got, a.TransactionID)
+	}
+	// Airline was seen before this batch; Rail is new even though it appears twice.
+	if len(got) != 3 || got[0] != "t1" || got[1] != "t3" || got[2] != "t4" {
+		t.Fatalf("unexpected alerts for: %v", got)
+	}
+	if txStore.calls != 3 {
+		t.Fatalf("expected one lookup per merchant, got %d", txStore.calls)
+	}
+}
+
+func TestEvaluateTransactionsFlagsUnusuallyLarge(t *testing.T) {
+	store := &alertFakeStore{}
+	past := func(id string, minor int64) *models.Transaction {
+		return &models.Transaction{TransactionID: id, AmountMinor: minor, Currency: "USD"}
+	}
+	txStore := &alertFakeTxStore{history: map[string][]*models.Transaction{
+		"Grocer": {past("t1", 45000), past("g1", 4000), past("g2", 6000), past("g3", 5000),
+			{TransactionID: "g4", AmountMinor: 90000, Currency: "USD", Pending: true},
+			past("refund", -5000)},
+		"Cafe":  {past("t2", 1500), past("c1", 400)},
+		"Hotel": {past("t3", 60000), past("h1", 10000), past("h2", 10000), past("h3", 10000)},
+	}}
+	svc := NewAlertService(store, txStore, nil)
+	now := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	svc.clockNow = func() time.Time { return now }
+
+	txs := []models.Transaction{
+		{TransactionID: "t1", Name: "Grocer", AmountMinor: 45000, Currency: "USD", Date: "2025-03-09"},
+		{TransactionID: "t2", Name: "Cafe", AmountMinor: 1500, Currency: "USD", Date: "2025-03-09"},   // too little history
+		{TransactionID: "t3", Name: "Hotel", AmountMinor: 60000, Currency: "USD", Date: "2025-01-09"}, // backfill
+		{TransactionID: "t4", Name: "Grocer", AmountMinor: 45000, Currency: "EUR", Date: "2025-03-09"},
+	}
+	created, err := svc.EvaluateTransactions(helpers.TestCtx(), "uid", txs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created != 1 || len(store.created) != 1 {
+		t.Fatalf("expected 1 alert, got %d: %+v", created, store.created)
+	}
+	if a := store.created[0]; a.AlertID != "large_transaction_t1" || a.RuleName != "Unusually large transaction" {
+		t.Fatalf("unexpected alert: %+v", a)
+	}
+	if txStore.calls != 2 {
+		t.Fatalf("expected one history lookup per recent merchant, got %d", txStore.calls)
+	}
+}
+
+func TestEvaluateTransactionsSkipsExistingAlerts(t *testing.T) {
+	store := &alertFakeStore{
+		rules:    []*models.AlertRule{{RuleID: "bank", BankID: "bank-1"}},
+		existing: map[string]bool{"bank_t1": true},
+	}
+	svc := NewAlertService(store, &alertFakeTxStore{}, nil)
+
+	created, err := svc.EvaluateTransactions(helpers.TestCtx(), "uid", []models.Transaction{{TransactionID: "t1", BankID: "bank-1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created != 0 {
+		t.Fatalf("expected no new alerts, got %d", created)
+	}
+}
+
+func TestAcknowledgeAlertAndListLimits(t *testing.T) {
+	store := &alertFakeStore{}
+	svc := NewAlertService(store, &alertFakeTxStore{}, nil)
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	svc.clockNow = func() time.Time { return now }
+
+	if err := svc.AcknowledgeAlert(helpers.TestCtx(), "uid", "a1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.ackID != "a1" || !store.ackAt.Equal(now) {
+		t.Fatalf("unexpected acknowledge: %s at %v", store.ackID, store.ackAt)
+	}
+
+	if _, err := svc.ListAlerts(helpers.TestCtx(), "uid", true, 0); err != nil || store.limit != defaultAlertListLimit {
+		t.Fatalf("expected default limit, got %d (%v)", store.limit, err)
+	}
+	if _, err := svc.ListAlerts(helpers.TestCtx(), "uid", true, 1000); err != nil || store.limit != maxAlertListLimit {
+		t.Fatalf("expected max limit, got %d (%v)", store.limit, err)
+	}
+}
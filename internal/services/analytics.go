@@ -2,13 +2,16 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/GregMSThompson/finance-backend/internal/dto"
 	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/money"
 	"github.com/GregMSThompson/finance-backend/pkg/helpers"
 )
 
@@ -16,41 +19,54 @@ type transactionAnalyticsStore interface {
 	Query(ctx context.Context, uid string, q dto.TransactionQuery, handle func(*models.Transaction) error) error
 }
 
+// fxRateProvider returns the multiplier that converts an amount in from into to.
+type fxRateProvider interface {
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
 type analyticsService struct {
 	txs transactionAnalyticsStore
+	fx  fxRateProvider
 }
 
-func NewAnalyticsService(txs transactionAnalyticsStore) *analyticsService {
-	return &analyticsService{txs: txs}
+// NewAnalyticsService builds the analytics service. fx may be nil, in which case
+// requests for a base currency are rejected.
+func NewAnalyticsService(txs transactionAnalyticsStore, fx fxRateProvider) *analyticsService {
+	return &analyticsService{txs: txs, fx: fx}
 }
 
 func (s *analyticsService) GetSpendTotal(ctx context.Context, uid string, args dto.AnalyticsSpendTotalArgs) (dto.AnalyticsSpendTotalResult, error) {
 	result := dto.AnalyticsSpendTotalResult{
-		From: helpers.Value(args.DateFrom),
-		To:   helpers.Value(args.DateTo),
+		From:   helpers.Value(args.DateFrom),
+		To:     helpers.Value(args.DateTo),
+		Totals: []dto.CurrencyTotal{},
+	}
+	flow, err := newFlowFilter(args.Direction, args.IncludeTransfers, args.PFCPrimary)
+	if err != nil {
+		return result, err
+	}
+	result.Direction = flow.direction
+	conv, err := s.newConverter(args.BaseCurrency)
+	if err != nil {
+		return result, err
 	}
 
-	var total float64
-	var currency string
-	if err := s.txs.Query(ctx, uid, dto.TransactionQuery{
+	data, err := collectPeriod(ctx, s.txs, uid, dto.TransactionQuery{
 		Pending:    args.Pending,
 		PFCPrimary: args.PFCPrimary,
 		BankID:     args.BankID,
 		Merchant:   args.Merchant,
 		DateFrom:   args.DateFrom,
 		DateTo:     args.DateTo,
-	}, func(tx *models.Transaction) error {
-		total += tx.Amount
-		if currency == "" && tx.Currency != "" {
-			currency = tx.Currency
-		}
-		return nil
-	}); err != nil {
+	}, "", conv, flow)
+	if err != nil {
 		return result, err
 	}
 
-	result.Total = total
-	result.Currency = currency
+	result.Total = data.total
+	result.FlowTotals = data.flows
+	result.Currency = data.currency
+	result.Totals = data.totals.list()
 	return result, nil
 }
 
@@ -63,19 +79,30 @@ func (s *analyticsService) GetSpendBreakdown(ctx context.Context, uid string, ar
 	if err := validateGroupBy(args.GroupBy); err != nil {
 		return result, err
 	}
+	flow, err := newFlowFilter(args.Direction, args.IncludeTransfers, args.PFCPrimary)
+	if err != nil {
+		return result, err
+	}
+	result.Direction = flow.direction
+	conv, err := s.newConverter(args.BaseCurrency)
+	if err != nil {
+		return result, err
+	}
 
 	data, err := collectPeriod(ctx, s.txs, uid, dto.TransactionQuery{
 		Pending:    args.Pending,
 		PFCPrimary: args.PFCPrimary,
 		BankID:     args.BankID,
+		Merchant:   args.Merchant,
 		DateFrom:   args.DateFrom,
 		DateTo:     args.DateTo,
-	}, args.GroupBy)
+	}, args.GroupBy, conv, flow)
 	if err != nil {
 		return result, err
 	}
 
 	result.Currency = data.currency
+	result.Totals = data.totals.list()
 	result.Items = mapBreakdownItems(data.items)
 	return result, nil
 }
@@ -115,6 +142,15 @@ func (s *analyticsService) GetPeriodComparison(ctx context.Context, uid string,
 			return result, err
 		}
 	}
+	flow, err := newFlowFilter(args.Direction, args.IncludeTransfers, args.PFCPrimary)
+	if err != nil {
+		return result, err
+	}
+	result.Direction = flow.direction
+	conv, err := s.newConverter(args.BaseCurrency)
+	if err != nil {
+		return result, err
+	}
 
 	currentQuery := dto.TransactionQuery{
 		Pending:    args.Pending,
@@ -140,11 +176,11 @@ func (s *analyticsService) GetPeriodComparison(ctx context.Context, uid string,
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		currentData, currentErr = collectPeriod(ctx, s.txs, uid, currentQuery, args.GroupBy)
+		currentData, currentErr = collectPeriod(ctx, s.txs, uid, currentQuery, args.GroupBy, conv, flow)
 	}()
 	go func() {
 		defer wg.Done()
-		previousData, previousErr = collectPeriod(ctx, s.txs, uid, previousQuery, args.GroupBy)
+		previousData, previousErr = collectPeriod(ctx, s.txs, uid, previousQuery, args.GroupBy, conv, flow)
 	}()
 	wg.Wait()
 
@@ -162,68 +198,108 @@ func (s *analyticsService) GetPeriodComparison(ctx context.Context, uid string,
 	}
 
 	result.Current = dto.PeriodSummary{
-		Total:    currentData.total,
-		Count:    currentData.count,
-		Currency: currentData.currency,
-		From:     args.CurrentFrom,
-		To:       args.CurrentTo,
-		Items:    currentItems,
+		Total:      currentData.total,
+		Count:      currentData.count,
+		Currency:   currentData.currency,
+		Totals:     currentData.totals.list(),
+		From:       args.CurrentFrom,
+		To:         args.CurrentTo,
+		Items:      currentItems,
+		FlowTotals: currentData.flows,
 	}
 	result.Previous = dto.PeriodSummary{
-		Total:    previousData.total,
-		Count:    previousData.count,
-		Currency: previousData.currency,
-		From:     args.PreviousFrom,
-		To:       args.PreviousTo,
-		Items:    previousItems,
+		Total:      previousData.total,
+		Count:      previousData.count,
+		Currency:   previousData.currency,
+		Totals:     previousData.totals.list(),
+		From:       args.PreviousFrom,
+		To:         args.PreviousTo,
+		Items:      previousItems,
+		FlowTotals: previousData.flows,
 	}
 	result.Change = buildChange(currentData, previousData, args.GroupBy)
 
 	return result, nil
 }
 
-// periodData holds the accumulated totals for a single query period.
+// periodData holds the accumulated totals for a single query period. total and
+// currency follow the rules documented on dto.AnalyticsSpendTotalResult.
 type periodData struct {
-	total    float64
+	total    money.Money
 	count    int
+	flows    dto.FlowTotals
 	currency string
+	totals   currencyTotals
 	items    map[string]*dto.AnalyticsBreakdownItem
 }
 
 // collectPeriod runs a single store query and accumulates totals and an optional
-// group breakdown into a periodData value.
-func collectPeriod(ctx context.Context, store transactionAnalyticsStore, uid string, q dto.TransactionQuery, groupBy string) (periodData, error) {
+// group breakdown into a periodData value. Items are keyed by group and currency so
+// amounts in different currencies are never added together unconverted.
+func collectPeriod(ctx context.Context, store transactionAnalyticsStore, uid string, q dto.TransactionQuery, groupBy string, conv *currencyConverter, flow flowFilter) (periodData, error) {
 	data := periodData{
-		items: map[string]*dto.AnalyticsBreakdownItem{},
+		totals: currencyTotals{},
+		items:  map[string]*dto.AnalyticsBreakdownItem{},
 	}
 	err := store.Query(ctx, uid, q, func(tx *models.Transaction) error {
-		data.total += tx.Amount
-		data.count++
-		if data.currency == "" && tx.Currency != "" {
-			data.currency = tx.Currency
+		if flow.skip(tx) {
+			return nil
+		}
+		data.totals.addFlow(tx.Amount(), flow)
+		amount, err := conv.convert(ctx, tx.Amount())
+		if err != nil {
+			return err
+		}
+		addFlow(&data.flows, amount)
+		if flow.matches(amount) {
+			data.count++
 		}
 		if groupBy != "" {
 			key := breakdownKey(tx, groupBy)
 			if key != "" {
-				item, ok := data.items[key]
+				itemKey := key + "\x00" + amount.Currency
+				item, ok := data.items[itemKey]
 				if !ok {
-					item = &dto.AnalyticsBreakdownItem{Key: key}
-					data.items[key] = item
+					item = &dto.AnalyticsBreakdownItem{Key: key, Currency: amount.Currency}
+					data.items[itemKey] = item
+				}
+				addFlow(&item.FlowTotals, amount)
+				item.Total = flow.total(item.FlowTotals)
+				if flow.matches(amount) {
+					item.Count++
 				}
-				item.Total += tx.Amount
-				item.Count++
 			}
 		}
 		return nil
 	})
-	return data, err
+	if err != nil {
+		return data, err
+	}
+
+	// Groups with nothing in the requested direction would only add noise.
+	for key, item := range data.items {
+		if item.Count == 0 {
+			delete(data.items, key)
+		}
+	}
+
+	currency, ok := conv.headline(data.totals)
+	if !ok {
+		data.flows = dto.FlowTotals{}
+	}
+	data.total = flow.total(data.flows)
+	data.currency = currency
+	return data, nil
 }
 
 func buildChange(current, previous periodData, groupBy string) dto.PeriodChange {
 	change := dto.PeriodChange{
-		AbsoluteChange:   current.total - previous.total,
-		PercentageChange: percentageChange(current.total, previous.total),
-		CountChange:      current.count - previous.count,
+		CountChange: current.count - previous.count,
+	}
+	if comparableHeadlines(current, previous) {
+		abs := current.total.Sub(previous.total)
+		change.AbsoluteChange = &abs
+		change.PercentageChange = percentageChange(current.total, previous.total)
 	}
 
 	if groupBy != "" {
@@ -236,23 +312,27 @@ func buildChange(current, previous periodData, groupBy string) dto.PeriodChange
 		}
 
 		for key := range keys {
-			var currTotal float64
+			var currTotal money.Money
 			var currCount int
-			var prevTotal float64
+			var prevTotal money.Money
 			var prevCount int
+			var ref *dto.AnalyticsBreakdownItem
 
 			if item := current.items[key]; item != nil {
 				currTotal = item.Total
 				currCount = item.Count
+				ref = item
 			}
 			if item := previous.items[key]; item != nil {
 				prevTotal = item.Total
 				prevCount = item.Count
+				ref = item
 			}
 
 			change.Items = append(change.Items, dto.BreakdownItemChange{
-				Key:              key,
-				AbsoluteChange:   currTotal - prevTotal,
+				Key:              ref.Key,
+				Currency:         ref.Currency,
+				AbsoluteChange:   currTotal.Sub(prevTotal),
 				PercentageChange: percentageChange(currTotal, prevTotal),
 				CountChange:      currCount - prevCount,
 			})
@@ -264,14 +344,19 @@ func buildChange(current, previous periodData, groupBy string) dto.PeriodChange
 
 func (s *analyticsService) GetRecurringTransactions(ctx context.Context, uid string, args dto.AnalyticsRecurringArgs) (dto.RecurringTransactionsResult, error) {
 	result := dto.RecurringTransactionsResult{
-		Items: []dto.RecurringItem{},
-		From:  args.DateFrom,
-		To:    args.DateTo,
+		Items:  []dto.RecurringItem{},
+		Totals: []dto.CurrencyTotal{},
+		From:   args.DateFrom,
+		To:     args.DateTo,
+	}
+	conv, err := s.newConverter(args.BaseCurrency)
+	if err != nil {
+		return result, err
 	}
 
 	type merchantGroup struct {
 		dates    []string
-		amounts  []float64
+		amounts  []money.Money
 		currency string
 	}
 
@@ -284,23 +369,27 @@ func (s *analyticsService) GetRecurringTransactions(ctx context.Context, uid str
 		DateFrom: &args.DateFrom,
 		DateTo:   &args.DateTo,
 	}, func(tx *models.Transaction) error {
+		amount, err := conv.convert(ctx, tx.Amount())
+		if err != nil {
+			return err
+		}
 		g, ok := groups[tx.Name]
 		if !ok {
 			g = &merchantGroup{}
 			groups[tx.Name] = g
 		}
 		g.dates = append(g.dates, tx.Date)
-		g.amounts = append(g.amounts, tx.Amount)
-		if g.currency == "" && tx.Currency != "" {
-			g.currency = tx.Currency
+		g.amounts = append(g.amounts, amount)
+		if g.currency == "" && amount.Currency != "" {
+			g.currency = amount.Currency
 		}
 		return nil
 	}); err != nil {
 		return result, err
 	}
 
-	var totalMonthly float64
-	var currency string
+	var totalMonthly money.Money
+	monthlyTotals := currencyTotals{}
 
 	for name, g := range groups {
 		if len(g.dates) < 2 {
@@ -334,14 +423,17 @@ func (s *analyticsService) GetRecurringTransactions(ctx context.Context, uid str
 			LastDate:          g.dates[len(g.dates)-1],
 			MonthlyEquivalent: monthly,
 		})
-		totalMonthly += monthly
-		if currency == "" && g.currency != "" {
-			currency = g.currency
-		}
+		totalMonthly = totalMonthly.Add(monthly)
+		monthlyTotals.add(monthly)
 	}
 
+	currency, ok := conv.headline(monthlyTotals)
+	if !ok {
+		totalMonthly = money.Money{}
+	}
 	result.TotalMonthlyEquivalent = totalMonthly
 	result.Currency = currency
+	result.Totals = monthlyTotals.list()
 	return result, nil
 }
 
@@ -374,18 +466,18 @@ func medianInt(vals []int) int {
 }
 
 // amountStats returns the median amount and whether the spread exceeds 10% of the median.
-func amountStats(amounts []float64) (median float64, variable bool) {
-	sorted := make([]float64, len(amounts))
+func amountStats(amounts []money.Money) (median money.Money, variable bool) {
+	sorted := make([]money.Money, len(amounts))
 	copy(sorted, amounts)
-	sort.Float64s(sorted)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Minor < sorted[j].Minor })
 	n := len(sorted)
 	if n%2 == 0 {
-		median = (sorted[n/2-1] + sorted[n/2]) / 2
+		median = sorted[n/2-1].Add(sorted[n/2]).Mul(0.5)
 	} else {
 		median = sorted[n/2]
 	}
-	if median > 0 {
-		variable = (sorted[n-1]-sorted[0])/median > 0.10
+	if median.Minor > 0 {
+		variable = float64(sorted[n-1].Minor-sorted[0].Minor)/float64(median.Minor) > 0.10
 	}
 	return
 }
@@ -407,30 +499,41 @@ func classifyFrequency(medianGap int) string {
 }
 
 // recurringMonthlyEquivalent normalises an amount to a monthly cost for a given frequency.
-func recurringMonthlyEquivalent(amount float64, frequency string) float64 {
+func recurringMonthlyEquivalent(amount money.Money, frequency string) money.Money {
 	switch frequency {
 	case "weekly":
 		// 52 weeks / 12 months = 4.33 recurring charges per month.
-		return amount * 4.33
+		return amount.Mul(4.33)
 	case "biweekly":
 		// 26 biweekly periods / 12 months = 2.17 recurring charges per month.
-		return amount * 2.17
+		return amount.Mul(2.17)
 	case "monthly":
 		// Already monthly, so no adjustment.
 		return amount
 	case "quarterly":
 		// 1 quarterly charge every 3 months, so divide by 3 for monthly equivalent.
-		return amount / 3
+		return amount.Mul(1.0 / 3)
 	default:
-		return 0
+		return money.New(0, amount.Currency)
+	}
+}
+
+// comparableHeadlines reports whether two periods' headline totals share a currency.
+// A period with no transactions has no currency and compares with any headline; one
+// with mixed currencies and no base currency has no headline at all.
+func comparableHeadlines(a, b periodData) bool {
+	hasHeadline := func(p periodData) bool { return len(p.totals) == 0 || p.currency != "" }
+	if !hasHeadline(a) || !hasHeadline(b) {
+		return false
 	}
+	return a.currency == b.currency || len(a.totals) == 0 || len(b.totals) == 0
 }
 
-func percentageChange(current, previous float64) *float64 {
-	if previous == 0 {
+func percentageChange(current, previous money.Money) *float64 {
+	if previous.Minor == 0 {
 		return nil
 	}
-	pct := (current - previous) / previous * 100
+	pct := float64(current.Minor-previous.Minor) / float64(previous.Minor) * 100
 	return &pct
 }
 
@@ -463,3 +566,173 @@ func validateGroupBy(groupBy string) error {
 		return errs.NewUnsupportedGroupByError()
 	}
 }
+
+// currencyConverter converts amounts into a requested base currency, caching each
+// rate for the lifetime of one request. With no base currency it leaves amounts as-is.
+type currencyConverter struct {
+	fx   fxRateProvider
+	base string
+
+	mu    sync.Mutex
+	rates map[string]float64
+}
+
+func (s *analyticsService) newConverter(baseCurrency *string) (*currencyConverter, error) {
+	base := strings.ToUpper(helpers.Value(baseCurrency))
+	if base == "" {
+		return &currencyConverter{}, nil
+	}
+	if s.fx == nil {
+		return nil, errs.NewValidationError("currency conversion is not configured")
+	}
+	return &currencyConverter{fx: s.fx, base: base, rates: map[string]float64{}}, nil
+}
+
+// convert returns amount in the base currency. Transactions without a currency are
+// assumed to already be in the base currency.
+func (c *currencyConverter) convert(ctx context.Context, amount money.Money) (money.Money, error) {
+	if c.base == "" {
+		return amount, nil
+	}
+	currency := amount.Currency
+	if currency == "" || strings.EqualFold(currency, c.base) {
+		return money.New(amount.Minor, c.base), nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rate, ok := c.rates[currency]
+	if !ok {
+		var err error
+		rate, err = c.fx.Rate(ctx, currency, c.base)
+		if err != nil {
+			return money.Money{}, err
+		}
+		c.rates[currency] = rate
+	}
+	return amount.Convert(rate, c.base), nil
+}
+
+// headline returns the currency of a result's headline figures: the base currency
+// when converting, otherwise the only currency seen. ok is false when unconverted
+// currencies were mixed and a single headline figure would be meaningless.
+func (c *currencyConverter) headline(totals currencyTotals) (currency string, ok bool) {
+	if c.base != "" {
+		return c.base, true
+	}
+	switch len(totals) {
+	case 0:
+		return "", true
+	case 1:
+		for currency := range totals {
+			return currency, true
+		}
+	}
+	return "", false
+}
+
+// currencyTotals accumulates unconverted amounts per currency.
+type currencyTotals map[string]*dto.CurrencyTotal
+
+func (t currencyTotals) get(currency string) *dto.CurrencyTotal {
+	total, ok := t[currency]
+	if !ok {
+		total = &dto.CurrencyTotal{Currency: currency, Total: money.New(0, currency)}
+		t[currency] = total
+	}
+	return total
+}
+
+// add sums amount into its currency's Total regardless of direction.
+func (t currencyTotals) add(amount money.Money) {
+	total := t.get(amount.Currency)
+	total.Total = total.Total.Add(amount)
+	total.Count++
+}
+
+// addFlow tracks amount's direction, keeping Total and Count to the flow's direction.
+func (t currencyTotals) addFlow(amount money.Money, flow flowFilter) {
+	total := t.get(amount.Currency)
+	addFlow(&total.FlowTotals, amount)
+	total.Total = flow.total(total.FlowTotals)
+	if flow.matches(amount) {
+		total.Count++
+	}
+}
+
+// list returns the totals sorted by currency code.
+func (t currencyTotals) list() []dto.CurrencyTotal {
+	out := make([]dto.CurrencyTotal, 0, len(t))
+	for _, total := range t {
+		out = append(out, *total)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Currency < out[j].Currency })
+	return out
+}
+
+// flowFilter decides which transactions count toward a total and how it is signed.
+type flowFilter struct {
+	direction        string
+	includeTransfers bool
+}
+
+func newFlowFilter(direction string, includeTransfers bool, primary *string) (flowFilter, error) {
+	switch direction {
+	case "":
+		direction = dto.FlowOutflow
+	case dto.FlowOutflow, dto.FlowInflow, dto.FlowNet:
+	default:
+		return flowFilter{}, errs.NewValidationError(fmt.Sprintf("invalid direction: %s", direction))
+	}
+	// Filtering on a transfer category is an explicit request to see transfers.
+	if isTransferCategory(helpers.Value(primary)) {
+		includeTransfers = true
+	}
+	return flowFilter{direction: direction, includeTransfers: includeTransfers}, nil
+}
+
+// skip reports whether tx is a transfer between the user's own accounts that should
+// not count as spend or income.
+func (f flowFilter) skip(tx *models.Transaction) bool {
+	return !f.includeTransfers && isTransferCategory(tx.PFCPrimary)
+}
+
+// matches reports whether amount moves in the filter's direction. Plaid amounts are
+// positive for outflows and negative for inflows.
+func (f flowFilter) matches(amount money.Money) bool {
+	switch f.direction {
+	case dto.FlowInflow:
+		return amount.Minor < 0
+	case dto.FlowNet:
+		return true
+	default:
+		return amount.Minor > 0
+	}
+}
+
+// total picks the figure for the filter's direction out of flows.
+func (f flowFilter) total(flows dto.FlowTotals) money.Money {
+	switch f.direction {
+	case dto.FlowInflow:
+		return flows.Income
+	case dto.FlowNet:
+		return flows.Net
+	default:
+		return flows.Spend
+	}
+}
+
+// addFlow adds a Plaid-signed amount to flows.
+func addFlow(flows *dto.FlowTotals, amount money.Money) {
+	switch {
+	case amount.Minor > 0:
+		flows.Spend = flows.Spend.Add(amount)
+	case amount.Minor < 0:
+		flows.Income = flows.Income.Add(amount.Neg())
+	}
+	flows.Net = flows.Net.Sub(amount)
+}
+
+func isTransferCategory(primary string) bool {
+	return primary == "TRANSFER_IN" || primary == "TRANSFER_OUT"
+}
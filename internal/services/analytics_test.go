@@ -5,9 +5,11 @@ import (
 	"errors"
 	"testing"
 
+	fxclient "github.com/GregMSThompson/finance-backend/internal/client/fx"
 	"github.com/GregMSThompson/finance-backend/internal/dto"
 	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/money"
 	"github.com/GregMSThompson/finance-backend/pkg/helpers"
 )
 
@@ -32,18 +34,18 @@ func (f *fakeAnalyticsStore) Query(ctx context.Context, uid string, q dto.Transa
 func TestAnalyticsSpendTotal(t *testing.T) {
 	store := &fakeAnalyticsStore{
 		txs: []*models.Transaction{
-			{Amount: 10.5, Currency: "USD"},
-			{Amount: 2.25, Currency: "USD"},
+			{AmountMinor: 1050, Currency: "USD"},
+			{AmountMinor: 225, Currency: "USD"},
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	got, err := svc.GetSpendTotal(context.Background(), "user", dto.AnalyticsSpendTotalArgs{})
 	if err != nil {
 		t.Fatalf("GetSpendTotal error: %v", err)
 	}
 
-	if got.Total != 12.75 {
+	if got.Total.Minor != 1275 {
 		t.Fatalf("total mismatch: got %v", got.Total)
 	}
 	if got.Currency != "USD" {
@@ -54,12 +56,12 @@ func TestAnalyticsSpendTotal(t *testing.T) {
 func TestAnalyticsSpendBreakdown(t *testing.T) {
 	store := &fakeAnalyticsStore{
 		txs: []*models.Transaction{
-			{Name: "Coffee", Amount: 3, Currency: "USD"},
-			{Name: "Coffee", Amount: 2, Currency: "USD"},
-			{Name: "Lunch", Amount: 8, Currency: "USD"},
+			{Name: "Coffee", AmountMinor: 300, Currency: "USD"},
+			{Name: "Coffee", AmountMinor: 200, Currency: "USD"},
+			{Name: "Lunch", AmountMinor: 800, Currency: "USD"},
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	got, err := svc.GetSpendBreakdown(context.Background(), "user", dto.AnalyticsSpendBreakdownArgs{
 		GroupBy: "merchant",
@@ -79,17 +81,33 @@ func TestAnalyticsSpendBreakdown(t *testing.T) {
 	for _, item := range got.Items {
 		items[item.Key] = item
 	}
-	if items["Coffee"].Total != 5 || items["Coffee"].Count != 2 {
+	if items["Coffee"].Total.Minor != 500 || items["Coffee"].Count != 2 {
 		t.Fatalf("coffee totals mismatch: %+v", items["Coffee"])
 	}
-	if items["Lunch"].Total != 8 || items["Lunch"].Count != 1 {
+	if items["Lunch"].Total.Minor != 800 || items["Lunch"].Count != 1 {
 		t.Fatalf("lunch totals mismatch: %+v", items["Lunch"])
 	}
 }
 
+func TestAnalyticsSpendBreakdownFiltersMerchant(t *testing.T) {
+	store := &fakeAnalyticsStore{}
+	svc := NewAnalyticsService(store, nil)
+
+	merchant := "cafe"
+	if _, err := svc.GetSpendBreakdown(context.Background(), "user", dto.AnalyticsSpendBreakdownArgs{
+		GroupBy:  "pfcPrimary",
+		Merchant: &merchant,
+	}); err != nil {
+		t.Fatalf("GetSpendBreakdown error: %v", err)
+	}
+	if store.lastQuery.Merchant == nil || *store.lastQuery.Merchant != "cafe" {
+		t.Fatalf("merchant mismatch: %+v", store.lastQuery.Merchant)
+	}
+}
+
 func TestAnalyticsSpendBreakdownInvalidGroupBy(t *testing.T) {
 	store := &fakeAnalyticsStore{}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	_, err := svc.GetSpendBreakdown(context.Background(), "user", dto.AnalyticsSpendBreakdownArgs{
 		GroupBy: "unknown",
@@ -110,7 +128,7 @@ func TestAnalyticsTransactions(t *testing.T) {
 			{TransactionID: "t2"},
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	got, err := svc.GetTransactions(context.Background(), "user", dto.AnalyticsTransactionsArgs{})
 	if err != nil {
@@ -125,7 +143,7 @@ func TestAnalyticsSpendTotalPropagatesStoreError(t *testing.T) {
 	store := &fakeAnalyticsStore{
 		err: errors.New("store down"),
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	_, err := svc.GetSpendTotal(context.Background(), "user", dto.AnalyticsSpendTotalArgs{})
 	if err == nil {
@@ -135,7 +153,7 @@ func TestAnalyticsSpendTotalPropagatesStoreError(t *testing.T) {
 
 func TestAnalyticsTransactionsPassesFilters(t *testing.T) {
 	store := &fakeAnalyticsStore{}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	pending := true
 	primary := "food"
@@ -187,7 +205,7 @@ func TestAnalyticsTransactionsPassesFilters(t *testing.T) {
 
 func TestAnalyticsSpendTotalPassesFilters(t *testing.T) {
 	store := &fakeAnalyticsStore{}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	merchant := "starbucks"
 	from := "2025-01-01"
@@ -237,16 +255,16 @@ func TestGetPeriodComparisonBasicTotal(t *testing.T) {
 		fn: func(q dto.TransactionQuery) ([]*models.Transaction, error) {
 			if helpers.Value(q.DateFrom) == "2025-02-01" {
 				return []*models.Transaction{
-					{Amount: 30, Currency: "USD"},
-					{Amount: 20, Currency: "USD"},
+					{AmountMinor: 3000, Currency: "USD"},
+					{AmountMinor: 2000, Currency: "USD"},
 				}, nil
 			}
 			return []*models.Transaction{
-				{Amount: 40, Currency: "USD"},
+				{AmountMinor: 4000, Currency: "USD"},
 			}, nil
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	got, err := svc.GetPeriodComparison(context.Background(), "user", dto.AnalyticsPeriodComparisonArgs{
 		CurrentFrom:  "2025-02-01",
@@ -258,13 +276,13 @@ func TestGetPeriodComparisonBasicTotal(t *testing.T) {
 		t.Fatalf("GetPeriodComparison error: %v", err)
 	}
 
-	if got.Current.Total != 50 || got.Current.Count != 2 {
+	if got.Current.Total.Minor != 5000 || got.Current.Count != 2 {
 		t.Fatalf("current mismatch: total=%v count=%v", got.Current.Total, got.Current.Count)
 	}
-	if got.Previous.Total != 40 || got.Previous.Count != 1 {
+	if got.Previous.Total.Minor != 4000 || got.Previous.Count != 1 {
 		t.Fatalf("previous mismatch: total=%v count=%v", got.Previous.Total, got.Previous.Count)
 	}
-	if got.Change.AbsoluteChange != 10 {
+	if got.Change.AbsoluteChange.Minor != 1000 {
 		t.Fatalf("absolute change mismatch: %v", got.Change.AbsoluteChange)
 	}
 	if got.Change.PercentageChange == nil {
@@ -289,13 +307,13 @@ func TestGetPeriodComparisonNilPercentageWhenPreviousZero(t *testing.T) {
 		fn: func(q dto.TransactionQuery) ([]*models.Transaction, error) {
 			if helpers.Value(q.DateFrom) == "2025-02-01" {
 				return []*models.Transaction{
-					{Amount: 30, Currency: "USD"},
+					{AmountMinor: 3000, Currency: "USD"},
 				}, nil
 			}
 			return nil, nil
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	got, err := svc.GetPeriodComparison(context.Background(), "user", dto.AnalyticsPeriodComparisonArgs{
 		CurrentFrom:  "2025-02-01",
@@ -310,27 +328,58 @@ func TestGetPeriodComparisonNilPercentageWhenPreviousZero(t *testing.T) {
 	if got.Change.PercentageChange != nil {
 		t.Fatalf("expected nil percentage change when previous=0, got %v", *got.Change.PercentageChange)
 	}
-	if got.Change.AbsoluteChange != 30 {
+	if got.Change.AbsoluteChange.Minor != 3000 {
 		t.Fatalf("absolute change mismatch: %v", got.Change.AbsoluteChange)
 	}
 }
 
+func TestGetPeriodComparisonOmitsChangeAcrossCurrencies(t *testing.T) {
+	store := &funcAnalyticsStore{
+		fn: func(q dto.TransactionQuery) ([]*models.Transaction, error) {
+			if helpers.Value(q.DateFrom) == "2025-02-01" {
+				return []*models.Transaction{{AmountMinor: 3000, Currency: "EUR"}}, nil
+			}
+			return []*models.Transaction{{AmountMinor: 4000, Currency: "USD"}}, nil
+		},
+	}
+	svc := NewAnalyticsService(store, nil)
+
+	got, err := svc.GetPeriodComparison(context.Background(), "user", dto.AnalyticsPeriodComparisonArgs{
+		CurrentFrom:  "2025-02-01",
+		CurrentTo:    "2025-02-28",
+		PreviousFrom: "2025-01-01",
+		PreviousTo:   "2025-01-31",
+	})
+	if err != nil {
+		t.Fatalf("GetPeriodComparison error: %v", err)
+	}
+	if got.Current.Currency != "EUR" || got.Previous.Currency != "USD" {
+		t.Fatalf("unexpected headline currencies: %q, %q", got.Current.Currency, got.Previous.Currency)
+	}
+	if got.Change.AbsoluteChange != nil || got.Change.PercentageChange != nil {
+		t.Fatalf("expected no change across currencies, got %v / %v", got.Change.AbsoluteChange, got.Change.PercentageChange)
+	}
+	if got.Change.CountChange != 0 {
+		t.Fatalf("count change mismatch: %d", got.Change.CountChange)
+	}
+}
+
 func TestGetPeriodComparisonWithGroupBy(t *testing.T) {
 	store := &funcAnalyticsStore{
 		fn: func(q dto.TransactionQuery) ([]*models.Transaction, error) {
 			if helpers.Value(q.DateFrom) == "2025-02-01" {
 				return []*models.Transaction{
-					{Name: "Coffee", Amount: 5, Currency: "USD"},
-					{Name: "Lunch", Amount: 10, Currency: "USD"},
+					{Name: "Coffee", AmountMinor: 500, Currency: "USD"},
+					{Name: "Lunch", AmountMinor: 1000, Currency: "USD"},
 				}, nil
 			}
 			return []*models.Transaction{
-				{Name: "Coffee", Amount: 4, Currency: "USD"},
-				{Name: "Dinner", Amount: 8, Currency: "USD"},
+				{Name: "Coffee", AmountMinor: 400, Currency: "USD"},
+				{Name: "Dinner", AmountMinor: 800, Currency: "USD"},
 			}, nil
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	got, err := svc.GetPeriodComparison(context.Background(), "user", dto.AnalyticsPeriodComparisonArgs{
 		CurrentFrom:  "2025-02-01",
@@ -360,7 +409,7 @@ func TestGetPeriodComparisonWithGroupBy(t *testing.T) {
 	}
 
 	coffee := changeByKey["Coffee"]
-	if coffee.AbsoluteChange != 1 {
+	if coffee.AbsoluteChange.Minor != 100 {
 		t.Fatalf("Coffee absolute change mismatch: %v", coffee.AbsoluteChange)
 	}
 	if coffee.PercentageChange == nil || helpers.Value(coffee.PercentageChange) != 25 {
@@ -368,7 +417,7 @@ func TestGetPeriodComparisonWithGroupBy(t *testing.T) {
 	}
 
 	lunch := changeByKey["Lunch"]
-	if lunch.AbsoluteChange != 10 {
+	if lunch.AbsoluteChange.Minor != 1000 {
 		t.Fatalf("Lunch absolute change mismatch: %v", lunch.AbsoluteChange)
 	}
 	if lunch.PercentageChange != nil {
@@ -376,14 +425,14 @@ func TestGetPeriodComparisonWithGroupBy(t *testing.T) {
 	}
 
 	dinner := changeByKey["Dinner"]
-	if dinner.AbsoluteChange != -8 {
+	if dinner.AbsoluteChange.Minor != -800 {
 		t.Fatalf("Dinner absolute change mismatch: %v", dinner.AbsoluteChange)
 	}
 }
 
 func TestGetPeriodComparisonInvalidGroupBy(t *testing.T) {
 	store := &funcAnalyticsStore{fn: func(_ dto.TransactionQuery) ([]*models.Transaction, error) { return nil, nil }}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	_, err := svc.GetPeriodComparison(context.Background(), "user", dto.AnalyticsPeriodComparisonArgs{
 		CurrentFrom:  "2025-02-01",
@@ -408,7 +457,7 @@ func TestGetPeriodComparisonStoreErrorPropagates(t *testing.T) {
 			return nil, storeErr
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	_, err := svc.GetPeriodComparison(context.Background(), "user", dto.AnalyticsPeriodComparisonArgs{
 		CurrentFrom:  "2025-02-01",
@@ -424,12 +473,12 @@ func TestGetPeriodComparisonStoreErrorPropagates(t *testing.T) {
 func TestGetRecurringTransactionsMonthly(t *testing.T) {
 	store := &fakeAnalyticsStore{
 		txs: []*models.Transaction{
-			{Name: "Netflix", Amount: 15.99, Currency: "USD", Date: "2025-01-15"},
-			{Name: "Netflix", Amount: 15.99, Currency: "USD", Date: "2025-02-14"},
-			{Name: "Netflix", Amount: 15.99, Currency: "USD", Date: "2025-03-15"},
+			{Name: "Netflix", AmountMinor: 1599, Currency: "USD", Date: "2025-01-15"},
+			{Name: "Netflix", AmountMinor: 1599, Currency: "USD", Date: "2025-02-14"},
+			{Name: "Netflix", AmountMinor: 1599, Currency: "USD", Date: "2025-03-15"},
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	got, err := svc.GetRecurringTransactions(context.Background(), "user", dto.AnalyticsRecurringArgs{
 		DateFrom: "2025-01-01",
@@ -448,7 +497,7 @@ func TestGetRecurringTransactionsMonthly(t *testing.T) {
 	if item.Frequency != "monthly" {
 		t.Fatalf("frequency mismatch: %q", item.Frequency)
 	}
-	if item.TypicalAmount != 15.99 {
+	if item.TypicalAmount.Minor != 1599 {
 		t.Fatalf("typical amount mismatch: %v", item.TypicalAmount)
 	}
 	if item.AmountIsVariable {
@@ -460,10 +509,10 @@ func TestGetRecurringTransactionsMonthly(t *testing.T) {
 	if item.LastDate != "2025-03-15" {
 		t.Fatalf("last date mismatch: %q", item.LastDate)
 	}
-	if item.MonthlyEquivalent != 15.99 {
+	if item.MonthlyEquivalent.Minor != 1599 {
 		t.Fatalf("monthly equivalent mismatch: %v", item.MonthlyEquivalent)
 	}
-	if got.TotalMonthlyEquivalent != 15.99 {
+	if got.TotalMonthlyEquivalent.Minor != 1599 {
 		t.Fatalf("total monthly equivalent mismatch: %v", got.TotalMonthlyEquivalent)
 	}
 	if got.Currency != "USD" {
@@ -474,12 +523,12 @@ func TestGetRecurringTransactionsMonthly(t *testing.T) {
 func TestGetRecurringTransactionsWeekly(t *testing.T) {
 	store := &fakeAnalyticsStore{
 		txs: []*models.Transaction{
-			{Name: "Gym", Amount: 10, Currency: "USD", Date: "2025-01-06"},
-			{Name: "Gym", Amount: 10, Currency: "USD", Date: "2025-01-13"},
-			{Name: "Gym", Amount: 10, Currency: "USD", Date: "2025-01-20"},
+			{Name: "Gym", AmountMinor: 1000, Currency: "USD", Date: "2025-01-06"},
+			{Name: "Gym", AmountMinor: 1000, Currency: "USD", Date: "2025-01-13"},
+			{Name: "Gym", AmountMinor: 1000, Currency: "USD", Date: "2025-01-20"},
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	got, err := svc.GetRecurringTransactions(context.Background(), "user", dto.AnalyticsRecurringArgs{
 		DateFrom: "2025-01-01",
@@ -495,7 +544,7 @@ func TestGetRecurringTransactionsWeekly(t *testing.T) {
 		t.Fatalf("frequency mismatch: %q", got.Items[0].Frequency)
 	}
 	// 10 * 4.33 = 43.3
-	if got.TotalMonthlyEquivalent != 43.3 {
+	if got.TotalMonthlyEquivalent.Minor != 4330 {
 		t.Fatalf("total monthly equivalent mismatch: %v", got.TotalMonthlyEquivalent)
 	}
 }
@@ -503,10 +552,10 @@ func TestGetRecurringTransactionsWeekly(t *testing.T) {
 func TestGetRecurringTransactionsDropsInsufficientOccurrences(t *testing.T) {
 	store := &fakeAnalyticsStore{
 		txs: []*models.Transaction{
-			{Name: "One-off", Amount: 50, Currency: "USD", Date: "2025-01-10"},
+			{Name: "One-off", AmountMinor: 5000, Currency: "USD", Date: "2025-01-10"},
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	got, err := svc.GetRecurringTransactions(context.Background(), "user", dto.AnalyticsRecurringArgs{
 		DateFrom: "2025-01-01",
@@ -525,11 +574,11 @@ func TestGetRecurringTransactionsDropsIrregular(t *testing.T) {
 	// gaps that don't fit any bucket: 60 days between two transactions.
 	store := &fakeAnalyticsStore{
 		txs: []*models.Transaction{
-			{Name: "Irregular", Amount: 20, Currency: "USD", Date: "2025-01-01"},
-			{Name: "Irregular", Amount: 20, Currency: "USD", Date: "2025-03-02"}, // 60 day gap
+			{Name: "Irregular", AmountMinor: 2000, Currency: "USD", Date: "2025-01-01"},
+			{Name: "Irregular", AmountMinor: 2000, Currency: "USD", Date: "2025-03-02"}, // 60 day gap
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	got, err := svc.GetRecurringTransactions(context.Background(), "user", dto.AnalyticsRecurringArgs{
 		DateFrom: "2025-01-01",
@@ -546,12 +595,12 @@ func TestGetRecurringTransactionsDropsIrregular(t *testing.T) {
 func TestGetRecurringTransactionsVariableAmount(t *testing.T) {
 	store := &fakeAnalyticsStore{
 		txs: []*models.Transaction{
-			{Name: "Utility", Amount: 80, Currency: "USD", Date: "2025-01-15"},
-			{Name: "Utility", Amount: 110, Currency: "USD", Date: "2025-02-15"},
-			{Name: "Utility", Amount: 95, Currency: "USD", Date: "2025-03-15"},
+			{Name: "Utility", AmountMinor: 8000, Currency: "USD", Date: "2025-01-15"},
+			{Name: "Utility", AmountMinor: 11000, Currency: "USD", Date: "2025-02-15"},
+			{Name: "Utility", AmountMinor: 9500, Currency: "USD", Date: "2025-03-15"},
 		},
 	}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	got, err := svc.GetRecurringTransactions(context.Background(), "user", dto.AnalyticsRecurringArgs{
 		DateFrom: "2025-01-01",
@@ -570,7 +619,7 @@ func TestGetRecurringTransactionsVariableAmount(t *testing.T) {
 
 func TestGetRecurringTransactionsStoreErrorPropagates(t *testing.T) {
 	store := &fakeAnalyticsStore{err: errors.New("store down")}
-	svc := NewAnalyticsService(store)
+	svc := NewAnalyticsService(store, nil)
 
 	_, err := svc.GetRecurringTransactions(context.Background(), "user", dto.AnalyticsRecurringArgs{
 		DateFrom: "2025-01-01",
@@ -580,3 +629,191 @@ func TestGetRecurringTransactionsStoreErrorPropagates(t *testing.T) {
 		t.Fatal("expected error from store")
 	}
 }
+
+func TestAnalyticsSpendTotalMixedCurrencies(t *testing.T) {
+	store := &fakeAnalyticsStore{
+		txs: []*models.Transaction{
+			{AmountMinor: 1000, Currency: "USD"},
+			{AmountMinor: 2000, Currency: "EUR"},
+			{AmountMinor: 500, Currency: "USD"},
+		},
+	}
+	svc := NewAnalyticsService(store, nil)
+
+	got, err := svc.GetSpendTotal(context.Background(), "user", dto.AnalyticsSpendTotalArgs{})
+	if err != nil {
+		t.Fatalf("GetSpendTotal error: %v", err)
+	}
+	if got.Total.Minor != 0 || got.Currency != "" {
+		t.Fatalf("expected no headline total for mixed currencies, got %v %q", got.Total, got.Currency)
+	}
+	want := []dto.CurrencyTotal{
+		{Currency: "EUR", Total: money.New(2000, "EUR"), Count: 1},
+		{Currency: "USD", Total: money.New(1500, "USD"), Count: 2},
+	}
+	if len(got.Totals) != 2 {
+		t.Fatalf("totals mismatch: %+v", got.Totals)
+	}
+	for i := range want {
+		if got.Totals[i].Currency != want[i].Currency || got.Totals[i].Total != want[i].Total || got.Totals[i].Count != want[i].Count {
+			t.Fatalf("totals mismatch: %+v", got.Totals)
+		}
+	}
+}
+
+func TestAnalyticsSpendTotalConvertsToBaseCurrency(t *testing.T) {
+	store := &fakeAnalyticsStore{
+		txs: []*models.Transaction{
+			{AmountMinor: 1000, Currency: "USD"},
+			{AmountMinor: 2000, Currency: "EUR"},
+		},
+	}
+	fx := fxclient.NewStaticProvider("USD", map[string]float64{"EUR": 0.5})
+	svc := NewAnalyticsService(store, fx)
+
+	got, err := svc.GetSpendTotal(context.Background(), "user", dto.AnalyticsSpendTotalArgs{BaseCurrency: helpers.Ptr("usd")})
+	if err != nil {
+		t.Fatalf("GetSpendTotal error: %v", err)
+	}
+	if got.Total.Minor != 5000 || got.Currency != "USD" {
+		t.Fatalf("expected 50 USD, got %v %q", got.Total, got.Currency)
+	}
+	if len(got.Totals) != 2 {
+		t.Fatalf("expected unconverted per-currency totals, got %+v", got.Totals)
+	}
+}
+
+func TestAnalyticsBaseCurrencyErrors(t *testing.T) {
+	store := &fakeAnalyticsStore{txs: []*models.Transaction{{AmountMinor: 1000, Currency: "JPY"}}}
+	args := dto.AnalyticsSpendTotalArgs{BaseCurrency: helpers.Ptr("USD")}
+
+	var validationErr *errs.ValidationError
+	_, err := NewAnalyticsService(store, nil).GetSpendTotal(context.Background(), "user", args)
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError without a provider, got %v", err)
+	}
+
+	fx := fxclient.NewStaticProvider("USD", map[string]float64{"EUR": 0.5})
+	var rateErr *errs.MissingExchangeRateError
+	_, err = NewAnalyticsService(store, fx).GetSpendTotal(context.Background(), "user", args)
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("expected MissingExchangeRateError for a missing rate, got %v", err)
+	}
+}
+
+func TestAnalyticsSpendBreakdownSplitsItemsByCurrency(t *testing.T) {
+	store := &fakeAnalyticsStore{
+		txs: []*models.Transaction{
+			{Name: "Coffee", AmountMinor: 300, Currency: "USD"},
+			{Name: "Coffee", AmountMinor: 400, Currency: "EUR"},
+			{Name: "Coffee", AmountMinor: 200, Currency: "USD"},
+		},
+	}
+	svc := NewAnalyticsService(store, nil)
+
+	got, err := svc.GetSpendBreakdown(context.Background(), "user", dto.AnalyticsSpendBreakdownArgs{GroupBy: "merchant"})
+	if err != nil {
+		t.Fatalf("GetSpendBreakdown error: %v", err)
+	}
+	items := map[string]dto.AnalyticsBreakdownItem{}
+	for _, item := range got.Items {
+		items[item.Key+":"+item.Currency] = item
+	}
+	if len(items) != 2 || items["Coffee:USD"].Total.Minor != 500 || items["Coffee:EUR"].Total.Minor != 400 {
+		t.Fatalf("unexpected items: %+v", got.Items)
+	}
+
+	fx := fxclient.NewStaticProvider("USD", map[string]float64{"EUR": 0.5})
+	svc = NewAnalyticsService(store, fx)
+	got, err = svc.GetSpendBreakdown(context.Background(), "user", dto.AnalyticsSpendBreakdownArgs{GroupBy: "merchant", BaseCurrency: helpers.Ptr("USD")})
+	if err != nil {
+		t.Fatalf("GetSpendBreakdown error: %v", err)
+	}
+	if len(got.Items) != 1 || got.Items[0].Total.Minor != 1300 || got.Items[0].Currency != "USD" || got.Currency != "USD" {
+		t.Fatalf("unexpected converted breakdown: %+v", got)
+	}
+}
+
+func flowTestStore() *fakeAnalyticsStore {
+	return &fakeAnalyticsStore{
+		txs: []*models.Transaction{
+			{Name: "Grocer", AmountMinor: 5000, Currency: "USD", PFCPrimary: "FOOD_RETAIL"},
+			{Name: "Grocer", AmountMinor: -1000, Currency: "USD", PFCPrimary: "FOOD_RETAIL"}, // refund
+			{Name: "Employer", AmountMinor: -200000, Currency: "USD", PFCPrimary: "INCOME"},
+			{Name: "Savings", AmountMinor: 30000, Currency: "USD", PFCPrimary: "TRANSFER_OUT"},
+		},
+	}
+}
+
+func TestAnalyticsSpendTotalSeparatesFlows(t *testing.T) {
+	svc := NewAnalyticsService(flowTestStore(), nil)
+
+	cases := []struct {
+		direction string
+		total     int64
+		count     int
+	}{
+		{"", 5000, 1},
+		{dto.FlowInflow, 201000, 2},
+		{dto.FlowNet, 196000, 3},
+	}
+	for _, tc := range cases {
+		got, err := svc.GetSpendTotal(context.Background(), "user", dto.AnalyticsSpendTotalArgs{Direction: tc.direction})
+		if err != nil {
+			t.Fatalf("%q: GetSpendTotal error: %v", tc.direction, err)
+		}
+		if got.Total.Minor != tc.total || got.Totals[0].Count != tc.count {
+			t.Fatalf("%q: total=%v count=%d, want %d/%d", tc.direction, got.Total, got.Totals[0].Count, tc.total, tc.count)
+		}
+		if got.Spend.Minor != 5000 || got.Income.Minor != 201000 || got.Net.Minor != 196000 {
+			t.Fatalf("%q: unexpected flows: %+v", tc.direction, got.FlowTotals)
+		}
+	}
+}
+
+func TestAnalyticsTransfersExcludedByDefault(t *testing.T) {
+	svc := NewAnalyticsService(flowTestStore(), nil)
+
+	got, err := svc.GetSpendTotal(context.Background(), "user", dto.AnalyticsSpendTotalArgs{IncludeTransfers: true})
+	if err != nil {
+		t.Fatalf("GetSpendTotal error: %v", err)
+	}
+	if got.Total.Minor != 35000 {
+		t.Fatalf("expected transfers to be included, got %v", got.Total)
+	}
+
+	got, err = svc.GetSpendTotal(context.Background(), "user", dto.AnalyticsSpendTotalArgs{PFCPrimary: helpers.Ptr("TRANSFER_OUT")})
+	if err != nil {
+		t.Fatalf("GetSpendTotal error: %v", err)
+	}
+	// The fake store ignores filters, so only the transfer inclusion is under test here.
+	if got.Total.Minor != 35000 {
+		t.Fatalf("expected an explicit transfer category to include transfers, got %v", got.Total)
+	}
+}
+
+func TestAnalyticsSpendBreakdownOmitsGroupsOutsideDirection(t *testing.T) {
+	svc := NewAnalyticsService(flowTestStore(), nil)
+
+	got, err := svc.GetSpendBreakdown(context.Background(), "user", dto.AnalyticsSpendBreakdownArgs{GroupBy: "merchant"})
+	if err != nil {
+		t.Fatalf("GetSpendBreakdown error: %v", err)
+	}
+	if len(got.Items) != 1 || got.Items[0].Key != "Grocer" {
+		t.Fatalf("expected only the Grocer outflow group, got %+v", got.Items)
+	}
+	item := got.Items[0]
+	if item.Total.Minor != 5000 || item.Count != 1 || item.Income.Minor != 1000 || item.Net.Minor != -4000 {
+		t.Fatalf("unexpected item: %+v", item)
+	}
+}
+
+func TestAnalyticsRejectsInvalidDirection(t *testing.T) {
+	svc := NewAnalyticsService(flowTestStore(), nil)
+
+	_, err := svc.GetPeriodComparison(context.Background(), "user", dto.AnalyticsPeriodComparisonArgs{Direction: "sideways"})
+	var validationErr *errs.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+}
@@ -7,8 +7,14 @@ import (
 	"github.com/GregMSThompson/finance-backend/pkg/logger"
 )
 
+const (
+	defaultSyncHistoryLimit = 20
+	maxSyncHistoryLimit     = 100
+)
+
 type bankBSStore interface {
 	List(ctx context.Context, uid string) ([]*models.Bank, error)
+	Get(ctx context.Context, uid, bankID string) (*models.Bank, error)
 	Delete(ctx context.Context, uid, bankID string) error
 }
 
@@ -17,15 +23,28 @@ type transactionBSStore interface {
 	DeleteCursor(ctx context.Context, uid, bankID string) error
 }
 
+type accountBSStore interface {
+	DeleteByBank(ctx context.Context, uid, bankID string) error
+}
+
+type syncRunBSStore interface {
+	List(ctx context.Context, uid, bankID string, limit int) ([]*models.SyncRun, error)
+	DeleteByBank(ctx context.Context, uid, bankID string) error
+}
+
 type bankService struct {
-	banks   bankBSStore
-	txs     transactionBSStore
+	banks    bankBSStore
+	txs      transactionBSStore
+	accounts accountBSStore
+	runs     syncRunBSStore
 }
 
-func NewBankService(banks bankBSStore, txs transactionBSStore) *bankService {
+func NewBankService(banks bankBSStore, txs transactionBSStore, accounts accountBSStore, runs syncRunBSStore) *bankService {
 	return &bankService{
-		banks:   banks,
-		txs:     txs,
+		banks:    banks,
+		txs:      txs,
+		accounts: accounts,
+		runs:     runs,
 	}
 }
 
@@ -41,6 +60,12 @@ func (s *bankService) DeleteBank(ctx context.Context, uid, bankID string) error
 	if err := s.txs.DeleteCursor(ctx, uid, bankID); err != nil {
 		return err
 	}
+	if err := s.accounts.DeleteByBank(ctx, uid, bankID); err != nil {
+		return err
+	}
+	if err := s.runs.DeleteByBank(ctx, uid, bankID); err != nil {
+		return err
+	}
 	if err := s.banks.Delete(ctx, uid, bankID); err != nil {
 		return err
 	}
@@ -49,3 +74,20 @@ func (s *bankService) DeleteBank(ctx context.Context, uid, bankID string) error
 	log.Info("bank deleted", "bank_id", bankID)
 	return nil
 }
+
+// ListSyncHistory returns the most recent sync runs for a bank, newest first.
+// A non-positive limit uses the default; larger limits are capped.
+func (s *bankService) ListSyncHistory(ctx context.Context, uid, bankID string, limit int) ([]*models.SyncRun, error) {
+	// Confirm the bank belongs to the user so unknown ids surface as not found.
+	if _, err := s.banks.Get(ctx, uid, bankID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultSyncHistoryLimit
+	}
+	if limit > maxSyncHistoryLimit {
+		limit = maxSyncHistoryLimit
+	}
+	return s.runs.List(ctx, uid, bankID, limit)
+}
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/models"
 	"github.com/GregMSThompson/finance-backend/pkg/helpers"
 	"github.com/GregMSThompson/finance-backend/pkg/logger"
@@ -26,6 +27,15 @@ func (f *bankFakeBankStore) List(ctx context.Context, uid string) ([]*models.Ban
 	return f.list, nil
 }
 
+func (f *bankFakeBankStore) Get(ctx context.Context, uid, bankID string) (*models.Bank, error) {
+	for _, b := range f.list {
+		if b.BankID == bankID {
+			return b, nil
+		}
+	}
+	return nil, errs.NewNotFoundError("bank not found")
+}
+
 func (f *bankFakeBankStore) Delete(ctx context.Context, uid, bankID string) error {
 	f.deleted = append(f.deleted, uid+":"+bankID)
 	return f.deleteErr
@@ -47,9 +57,36 @@ func (f *bankFakeTxStore) DeleteCursor(ctx context.Context, uid, bankID string)
 	return f.deleteCursorErr
 }
 
+type bankFakeAccountStore struct {
+	deleteErr error
+	deleted   []string
+}
+
+func (f *bankFakeAccountStore) DeleteByBank(ctx context.Context, uid, bankID string) error {
+	f.deleted = append(f.deleted, uid+":"+bankID)
+	return f.deleteErr
+}
+
+type bankFakeSyncRunStore struct {
+	runs      []*models.SyncRun
+	gotLimit  int
+	deleteErr error
+	deleted   []string
+}
+
+func (f *bankFakeSyncRunStore) List(ctx context.Context, uid, bankID string, limit int) ([]*models.SyncRun, error) {
+	f.gotLimit = limit
+	return f.runs, nil
+}
+
+func (f *bankFakeSyncRunStore) DeleteByBank(ctx context.Context, uid, bankID string) error {
+	f.deleted = append(f.deleted, uid+":"+bankID)
+	return f.deleteErr
+}
+
 func TestBankServiceListBanks(t *testing.T) {
 	expected := []*models.Bank{{BankID: "b1"}, {BankID: "b2"}}
-	svc := NewBankService(&bankFakeBankStore{list: expected}, &bankFakeTxStore{})
+	svc := NewBankService(&bankFakeBankStore{list: expected}, &bankFakeTxStore{}, &bankFakeAccountStore{}, &bankFakeSyncRunStore{})
 
 	ctx := helpers.TestCtx()
 	got, err := svc.ListBanks(ctx, "uid-1")
@@ -64,7 +101,7 @@ func TestBankServiceListBanks(t *testing.T) {
 func TestBankServiceDeleteBankSuccess(t *testing.T) {
 	banks := &bankFakeBankStore{}
 	txs := &bankFakeTxStore{}
-	svc := NewBankService(banks, txs)
+	svc := NewBankService(banks, txs, &bankFakeAccountStore{}, &bankFakeSyncRunStore{})
 
 	ctx := helpers.TestCtx()
 	if err := svc.DeleteBank(ctx, "uid-1", "bank-1"); err != nil {
@@ -85,7 +122,7 @@ func TestBankServiceDeleteBankStopsOnDeleteByBankError(t *testing.T) {
 	expectedErr := errors.New("delete txs failed")
 	banks := &bankFakeBankStore{}
 	txs := &bankFakeTxStore{deleteByBankErr: expectedErr}
-	svc := NewBankService(banks, txs)
+	svc := NewBankService(banks, txs, &bankFakeAccountStore{}, &bankFakeSyncRunStore{})
 
 	ctx := helpers.TestCtx()
 	if err := svc.DeleteBank(ctx, "uid-1", "bank-1"); err != expectedErr {
@@ -103,7 +140,7 @@ func TestBankServiceDeleteBankStopsOnDeleteCursorError(t *testing.T) {
 	expectedErr := errors.New("delete cursor failed")
 	banks := &bankFakeBankStore{}
 	txs := &bankFakeTxStore{deleteCursorErr: expectedErr}
-	svc := NewBankService(banks, txs)
+	svc := NewBankService(banks, txs, &bankFakeAccountStore{}, &bankFakeSyncRunStore{})
 
 	ctx := helpers.TestCtx()
 	if err := svc.DeleteBank(ctx, "uid-1", "bank-1"); err != expectedErr {
@@ -120,6 +157,85 @@ func TestBankServiceDeleteBankStopsOnDeleteCursorError(t *testing.T) {
 	}
 }
 
+func TestBankServiceDeleteBankDeletesAccounts(t *testing.T) {
+	banks := &bankFakeBankStore{}
+	accounts := &bankFakeAccountStore{}
+	svc := NewBankService(banks, &bankFakeTxStore{}, accounts, &bankFakeSyncRunStore{})
+
+	ctx := helpers.TestCtx()
+	if err := svc.DeleteBank(ctx, "uid-1", "bank-1"); err != nil {
+		t.Fatalf("DeleteBank returned error: %v", err)
+	}
+	if len(accounts.deleted) != 1 || accounts.deleted[0] != "uid-1:bank-1" {
+		t.Fatalf("unexpected account delete calls: %#v", accounts.deleted)
+	}
+}
+
+func TestBankServiceDeleteBankStopsOnDeleteAccountsError(t *testing.T) {
+	expectedErr := errors.New("delete accounts failed")
+	banks := &bankFakeBankStore{}
+	svc := NewBankService(banks, &bankFakeTxStore{}, &bankFakeAccountStore{deleteErr: expectedErr}, &bankFakeSyncRunStore{})
+
+	ctx := helpers.TestCtx()
+	if err := svc.DeleteBank(ctx, "uid-1", "bank-1"); err != expectedErr {
+		t.Fatalf("DeleteBank error = %v, want %v", err, expectedErr)
+	}
+	if len(banks.deleted) != 0 {
+		t.Fatalf("expected no bank delete calls, got %#v", banks.deleted)
+	}
+}
+
+func TestBankServiceDeleteBankDeletesSyncRuns(t *testing.T) {
+	banks := &bankFakeBankStore{}
+	runs := &bankFakeSyncRunStore{}
+	svc := NewBankService(banks, &bankFakeTxStore{}, &bankFakeAccountStore{}, runs)
+
+	ctx := helpers.TestCtx()
+	if err := svc.DeleteBank(ctx, "uid-1", "bank-1"); err != nil {
+		t.Fatalf("DeleteBank returned error: %v", err)
+	}
+	if len(runs.deleted) != 1 || runs.deleted[0] != "uid-1:bank-1" {
+		t.Fatalf("unexpected sync run delete calls: %#v", runs.deleted)
+	}
+}
+
+func TestBankServiceListSyncHistory(t *testing.T) {
+	expected := []*models.SyncRun{{RunID: "r2"}, {RunID: "r1"}}
+	banks := &bankFakeBankStore{list: []*models.Bank{{BankID: "bank-1"}}}
+	runs := &bankFakeSyncRunStore{runs: expected}
+	svc := NewBankService(banks, &bankFakeTxStore{}, &bankFakeAccountStore{}, runs)
+
+	ctx := helpers.TestCtx()
+	got, err := svc.ListSyncHistory(ctx, "uid-1", "bank-1", 0)
+	if err != nil {
+		t.Fatalf("ListSyncHistory returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("ListSyncHistory = %#v, want %#v", got, expected)
+	}
+	if runs.gotLimit != defaultSyncHistoryLimit {
+		t.Fatalf("limit = %d, want %d", runs.gotLimit, defaultSyncHistoryLimit)
+	}
+
+	if _, err := svc.ListSyncHistory(ctx, "uid-1", "bank-1", 1000); err != nil {
+		t.Fatalf("ListSyncHistory returned error: %v", err)
+	}
+	if runs.gotLimit != maxSyncHistoryLimit {
+		t.Fatalf("limit = %d, want %d", runs.gotLimit, maxSyncHistoryLimit)
+	}
+}
+
+func TestBankServiceListSyncHistoryUnknownBank(t *testing.T) {
+	svc := NewBankService(&bankFakeBankStore{}, &bankFakeTxStore{}, &bankFakeAccountStore{}, &bankFakeSyncRunStore{})
+
+	ctx := helpers.TestCtx()
+	_, err := svc.ListSyncHistory(ctx, "uid-1", "missing", 10)
+	var notFound *errs.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected NotFoundError, got %v", err)
+	}
+}
+
 func testLogger() *slog.Logger {
 	return slog.New(logger.NewTestHandler(slog.LevelInfo))
 }
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/money"
+)
+
+type budgetBgStore interface {
+	Create(ctx context.Context, uid string, budget *models.Budget) error
+	List(ctx context.Context, uid string) ([]*models.Budget, error)
+	Get(ctx context.Context, uid, budgetID string) (*models.Budget, error)
+	Update(ctx context.Context, uid string, budget *models.Budget) error
+	Delete(ctx context.Context, uid, budgetID string) error
+}
+
+// budgetAnalytics is the slice of the analytics service used to measure spend.
+type budgetAnalytics interface {
+	GetSpendTotal(ctx context.Context, uid string, args dto.AnalyticsSpendTotalArgs) (dto.AnalyticsSpendTotalResult, error)
+}
+
+type budgetService struct {
+	budgets   budgetBgStore
+	analytics budgetAnalytics
+	clockNow  func() time.Time
+}
+
+func NewBudgetService(budgets budgetBgStore, analytics budgetAnalytics) *budgetService {
+	return &budgetService{
+		budgets:   budgets,
+		analytics: analytics,
+		clockNow:  time.Now,
+	}
+}
+
+func (s *budgetService) CreateBudget(ctx context.Context, uid string, in dto.BudgetInput) (*models.Budget, error) {
+	budget, err := budgetFromInput(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.budgets.Create(ctx, uid, budget); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (s *budgetService) ListBudgets(ctx context.Context, uid string) ([]*models.Budget, error) {
+	return s.budgets.List(ctx, uid)
+}
+
+func (s *budgetService) GetBudget(ctx context.Context, uid, budgetID string) (*models.Budget, error) {
+	return s.budgets.Get(ctx, uid, budgetID)
+}
+
+func (s *budgetService) UpdateBudget(ctx context.Context, uid, budgetID string, in dto.BudgetInput) (*models.Budget, error) {
+	existing, err := s.budgets.Get(ctx, uid, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := budgetFromInput(in)
+	if err != nil {
+		return nil, err
+	}
+	budget.BudgetID = existing.BudgetID
+	budget.CreatedAt = existing.CreatedAt
+	if err := s.budgets.Update(ctx, uid, budget); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (s *budgetService) DeleteBudget(ctx context.Context, uid, budgetID string) error {
+	return s.budgets.Delete(ctx, uid, budgetID)
+}
+
+// BudgetStatus reports how far each budget is through its current period.
+func (s *budgetService) BudgetStatus(ctx context.Context, uid string) ([]dto.BudgetStatus, error) {
+	budgets, err := s.budgets.List(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	today := s.clockNow()
+	statuses := make([]dto.BudgetStatus, 0, len(budgets))
+	for _, b := range budgets {
+		st, err := s.budgetStatus(ctx, uid, b, today)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+func (s *budgetService) budgetStatus(ctx context.Context, uid string, b *models.Budget, now time.Time) (dto.BudgetStatus, error) {
+	start, end := budgetPeriod(b.Period, now)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := start.Format("2006-01-02")
+	to := today.Format("2006-01-02")
+
+	args := dto.AnalyticsSpendTotalArgs{
+		DateFrom:  &from,
+		DateTo:    &to,
+		Direction: dto.FlowOutflow,
+	}
+	// Pending transactions are included so a budget reacts to purchases as they happen.
+	if b.PFCPrimary != "" {
+		args.PFCPrimary = &b.PFCPrimary
+	}
+	if b.Merchant != "" {
+		args.Merchant = &b.Merchant
+	}
+	spent, excluded, err := s.spendInCurrency(ctx, uid, args, b.Currency)
+	if err != nil {
+		return dto.BudgetStatus{}, err
+	}
+
+	limit := b.Amount()
+	elapsedDays := today.Sub(start).Hours()/24 + 1
+	periodDays := end.Sub(start).Hours()/24 + 1
+	projected := spent.Mul(periodDays / elapsedDays)
+
+	return dto.BudgetStatus{
+		Budget:              b,
+		PeriodStart:         from,
+		PeriodEnd:           end.Format("2006-01-02"),
+		Spent:               spent,
+		Remaining:           limit.Sub(spent),
+		Projected:           projected,
+		OverBudget:          spent.Minor > limit.Minor,
+		ProjectedOverBudget: projected.Minor > limit.Minor,
+		ExcludedCurrencies:  excluded,
+	}, nil
+}
+
+// spendInCurrency totals spending converted into currency. When a transaction's
+// currency has no exchange rate, only spending already in currency is counted and
+// the other currencies are returned as excluded, so one foreign purchase can't
+// break the budget.
+func (s *budgetService) spendInCurrency(ctx context.Context, uid string, args dto.AnalyticsSpendTotalArgs, currency string) (money.Money, []string, error) {
+	args.BaseCurrency = &currency
+	total, err := s.analytics.GetSpendTotal(ctx, uid, args)
+	if err == nil {
+		return money.New(total.Total.Minor, currency), nil, nil
+	}
+	var rateErr *errs.MissingExchangeRateError
+	if !errors.As(err, &rateErr) {
+		return money.Money{}, nil, err
+	}
+
+	args.BaseCurrency = nil
+	total, err = s.analytics.GetSpendTotal(ctx, uid, args)
+	if err != nil {
+		return money.Money{}, nil, err
+	}
+	spent := money.New(0, currency)
+	var excluded []string
+	for _, t := range total.Totals {
+		if t.Currency == currency {
+			spent = money.New(t.Total.Minor, currency)
+			continue
+		}
+		excluded = append(excluded, t.Currency)
+	}
+	return spent, excluded, nil
+}
+
+// budgetPeriod returns the first and last day of the period containing now. Weeks
+// run Monday to Sunday.
+func budgetPeriod(period string, now time.Time) (time.Time, time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if period == models.BudgetPeriodWeekly {
+		start := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 6)
+	}
+	start := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, -1)
+}
+
+func budgetFromInput(in dto.BudgetInput) (*models.Budget, error) {
+	currency := strings.ToUpper(strings.TrimSpace(in.Currency))
+	merchant := strings.TrimSpace(in.Merchant)
+
+	switch in.Period {
+	case models.BudgetPeriodMonthly, models.BudgetPeriodWeekly:
+	default:
+		return nil, errs.NewValidationError(fmt.Sprintf("invalid period: %q (expected monthly or weekly)", in.Period))
+	}
+	if in.PFCPrimary != "" && merchant != "" {
+		return nil, errs.NewValidationError("a budget can cover a pfcPrimary or a merchant, not both")
+	}
+	if err := validatePrimary(&in.PFCPrimary); err != nil {
+		return nil, err
+	}
+	if len(currency) != 3 {
+		return nil, errs.NewValidationError("currency must be a 3-letter ISO code")
+	}
+	amount := money.FromFloat(in.Amount, currency)
+	if amount.Minor <= 0 {
+		return nil, errs.NewValidationError("amount must be greater than zero")
+	}
+
+	return &models.Budget{
+		Name:        strings.TrimSpace(in.Name),
+		PFCPrimary:  in.PFCPrimary,
+		Merchant:    merchant,
+		Period:      in.Period,
+		AmountMinor: amount.Minor,
+		Currency:    currency,
+	}, nil
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/internal/money"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type budgetFakeStore struct {
+	budgets []*models.Budget
+	updated *models.Budget
+}
+
+func (f *budgetFakeStore) Create(ctx context.Context, uid string, budget *models.Budget) error {
+	budget.BudgetID = "budget-new"
+	f.budgets = append(f.budgets, budget)
+	return nil
+}
+
+func (f *budgetFakeStore) List(ctx context.Context, uid string) ([]*models.Budget, error) {
+	return f.budgets, nil
+}
+
+func (f *budgetFakeStore) Get(ctx context.Context, uid, budgetID string) (*models.Budget, error) {
+	for _, b := range f.budgets {
+		if b.BudgetID == budgetID {
+			return b, nil
+		}
+	}
+	return nil, errs.NewNotFoundError("budget not found")
+}
+
+func (f *budgetFakeStore) Update(ctx context.Context, uid string, budget *models.Budget) error {
+	f.updated = budget
+	return nil
+}
+
+func (f *budgetFakeStore) Delete(ctx context.Context, uid, budgetID string) error {
+	return nil
+}
+
+type budgetFakeAnalytics struct {
+	spend      money.Money
+	totals     []dto.CurrencyTotal // per-currency totals for unconverted queries
+	args       []dto.AnalyticsSpendTotalArgs
+	err        error
+	convertErr error // returned when a base currency is requested
+}
+
+func (f *budgetFakeAnalytics) GetSpendTotal(ctx context.Context, uid string, args dto.AnalyticsSpendTotalArgs) (dto.AnalyticsSpendTotalResult, error) {
+	f.args = append(f.args, args)
+	if args.BaseCurrency != nil && f.convertErr != nil {
+		return dto.AnalyticsSpendTotalResult{}, f.convertErr
+	}
+	return dto.AnalyticsSpendTotalResult{Total: f.spend, Totals: f.totals}, f.err
+}
+
+func TestCreateBudgetValidates(t *testing.T) {
+	cases := map[string]dto.BudgetInput{
+		"period":   {Period: "daily", Amount: 10, Currency: "USD"},
+		"amount":   {Period: "monthly", Amount: 0, Currency: "USD"},
+		"currency": {Period: "monthly", Amount: 10, Currency: "dollars"},
+		"primary":  {Period: "monthly", Amount: 10, Currency: "USD", PFCPrimary: "NOT_A_CATEGORY"},
+		"both":     {Period: "monthly", Amount: 10, Currency: "USD", PFCPrimary: "DINING", Merchant: "Cafe"},
+	}
+	for name, in := range cases {
+		svc := NewBudgetService(&budgetFakeStore{}, &budgetFakeAnalytics{})
+		_, err := svc.CreateBudget(helpers.TestCtx(), "uid", in)
+		var ve *errs.ValidationError
+		if !errors.As(err, &ve) {
+			t.Fatalf("%s: expected validation error, got %v", name, err)
+		}
+	}
+}
+
+func TestCreateBudgetStoresMinorUnits(t *testing.T) {
+	store := &budgetFakeStore{}
+	svc := NewBudgetService(store, &budgetFakeAnalytics{})
+
+	b, err := svc.CreateBudget(helpers.TestCtx(), "uid", dto.BudgetInput{
+		Name:       " Eating out ",
+		PFCPrimary: "DINING",
+		Period:     "monthly",
+		Amount:     250.5,
+		Currency:   "usd",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.BudgetID != "budget-new" || b.AmountMinor != 25050 || b.Currency != "USD" || b.Name != "Eating out" {
+		t.Fatalf("unexpected budget: %+v", b)
+	}
+}
+
+func TestUpdateBudgetKeepsIdentity(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &budgetFakeStore{budgets: []*models.Budget{{BudgetID: "b1", CreatedAt: created}}}
+	svc := NewBudgetService(store, &budgetFakeAnalytics{})
+
+	_, err := svc.UpdateBudget(helpers.TestCtx(), "uid", "b1", dto.BudgetInput{Period: "weekly", Amount: 40, Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.updated == nil || store.updated.BudgetID != "b1" || !store.updated.CreatedAt.Equal(created) || store.updated.Period != "weekly" {
+		t.Fatalf("unexpected update: %+v", store.updated)
+	}
+
+	_, err = svc.UpdateBudget(helpers.TestCtx(), "uid", "missing", dto.BudgetInput{Period: "weekly", Amount: 40, Currency: "EUR"})
+	var nf *errs.NotFoundError
+	if !errors.As(err, &nf) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestBudgetStatusMonthly(t *testing.T) {
+	store := &budgetFakeStore{budgets: []*models.Budget{
+		{BudgetID: "b1", PFCPrimary: "DINING", Period: "monthly", AmountMinor: 30000, Currency: "USD"},
+	}}
+	analytics := &budgetFakeAnalytics{spend: money.New(10000, "USD")}
+	svc := NewBudgetService(store, analytics)
+	svc.clockNow = func() time.Time { return time.Date(2025, 4, 10, 15, 0, 0, 0, time.UTC) }
+
+	statuses, err := svc.BudgetStatus(helpers.TestCtx(), "uid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 1 {
+		t.Fatalf("expected one status, got %d", len(statuses))
+	}
+	st := statuses[0]
+	if st.PeriodStart != "2025-04-01" || st.PeriodEnd != "2025-04-30" {
+		t.Fatalf("unexpected period: %s to %s", st.PeriodStart, st.PeriodEnd)
+	}
+	// 100.00 over 10 of 30 days projects to 300.00.
+	if st.Spent.Minor != 10000 || st.Remaining.Minor != 20000 || st.Projected.Minor != 30000 {
+		t.Fatalf("unexpected amounts: spent %v remaining %v projected %v", st.Spent, st.Remaining, st.Projected)
+	}
+	if st.OverBudget || st.ProjectedOverBudget {
+		t.Fatalf("budget should not be over: %+v", st)
+	}
+
+	args := analytics.args[0]
+	if helpers.Value(args.PFCPrimary) != "DINING" || args.Merchant != nil || helpers.Value(args.BaseCurrency) != "USD" {
+		t.Fatalf("unexpected analytics args: %+v", args)
+	}
+	if helpers.Value(args.DateFrom) != "2025-04-01" || helpers.Value(args.DateTo) != "2025-04-10" || args.Direction != dto.FlowOutflow {
+		t.Fatalf("unexpected analytics window: %+v", args)
+	}
+}
+
+func TestBudgetStatusWeeklyOverBudget(t *testing.T) {
+	store := &budgetFakeStore{budgets: []*models.Budget{
+		{BudgetID: "b1", Merchant: "Cafe", Period: "weekly", AmountMinor: 5000, Currency: "USD"},
+	}}
+	analytics := &budgetFakeAnalytics{spend: money.New(6000, "USD")}
+	svc := NewBudgetService(store, analytics)
+	// Wednesday, so the week began on Monday 2025-04-07.
+	svc.clockNow = func() time.Time { return time.Date(2025, 4, 9, 9, 0, 0, 0, time.UTC) }
+
+	statuses, err := svc.BudgetStatus(helpers.TestCtx(), "uid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	st := statuses[0]
+	if st.PeriodStart != "2025-04-07" || st.PeriodEnd != "2025-04-13" {
+		t.Fatalf("unexpected period: %s to %s", st.PeriodStart, st.PeriodEnd)
+	}
+	if st.Remaining.Minor != -1000 || st.Projected.Minor != 14000 || !st.OverBudget || !st.ProjectedOverBudget {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if helpers.Value(analytics.args[0].Merchant) != "Cafe" || analytics.args[0].PFCPrimary != nil {
+		t.Fatalf("unexpected analytics args: %+v", analytics.args[0])
+	}
+}
+
+func TestBudgetStatusAnalyticsError(t *testing.T) {
+	store := &budgetFakeStore{budgets: []*models.Budget{{BudgetID: "b1", Period: "monthly", AmountMinor: 100, Currency: "USD"}}}
+	svc := NewBudgetService(store, &budgetFakeAnalytics{err: errors.New("boom")})
+
+	if _, err := svc.BudgetStatus(helpers.TestCtx(), "uid"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestBudgetStatusWithoutExchangeRateCountsBudgetCurrency(t *testing.T) {
+	store := &budgetFakeStore{budgets: []*models.Budget{
+		{BudgetID: "b1", Period: "monthly", AmountMinor: 10000, Currency: "USD"},
+		{BudgetID: "b2", Period: "monthly", AmountMinor: 10000, Currency: "EUR"},
+	}}
+	analytics := &budgetFakeAnalytics{
+		convertErr: errs.NewMissingExchangeRateError("EUR", "USD"),
+		totals: []dto.CurrencyTotal{
+			{Currency: "EUR", Total: money.New(2500, "EUR")},
+			{Currency: "USD", Total: money.New(4000, "USD")},
+		},
+	}
+	svc := NewBudgetService(store, analytics)
+
+	statuses, err := svc.BudgetStatus(helpers.TestCtx(), "uid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("expected a status for every budget, got %+v", statuses)
+	}
+	usd, eur := statuses[0], statuses[1]
+	if usd.Spent.Minor != 4000 || len(usd.ExcludedCurrencies) != 1 || usd.ExcludedCurrencies[0] != "EUR" {
+		t.Fatalf("unexpected USD status: %+v", usd)
+	}
+	if eur.Spent.Minor != 2500 || eur.Spent.Currency != "EUR" || len(eur.ExcludedCurrencies) != 1 || eur.ExcludedCurrencies[0] != "USD" {
+		t.Fatalf("unexpected EUR status: %+v", eur)
+	}
+}
+
+func TestBudgetStatusPropagatesOtherValidationErrors(t *testing.T) {
+	store := &budgetFakeStore{budgets: []*models.Budget{
+		{BudgetID: "b1", Period: "monthly", AmountMinor: 10000, Currency: "USD"},
+	}}
+	analytics := &budgetFakeAnalytics{convertErr: errs.NewValidationError("invalid pfcPrimary")}
+	svc := NewBudgetService(store, analytics)
+
+	_, err := svc.BudgetStatus(helpers.TestCtx(), "uid")
+	var validationErr *errs.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if len(analytics.args) != 1 {
+		t.Fatalf("expected no retry without conversion, got %d queries", len(analytics.args))
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/logger"
+)
+
+type deviceNSStore interface {
+	Upsert(ctx context.Context, uid string, device *models.Device) error
+	List(ctx context.Context, uid string) ([]*models.Device, error)
+	Delete(ctx context.Context, uid string, tokens []string) error
+}
+
+// pushSender delivers a notification to device tokens and returns the tokens the
+// push provider no longer accepts.
+type pushSender interface {
+	Send(ctx context.Context, tokens []string, n dto.Notification) ([]string, error)
+}
+
+// notifier is how other services tell a user something happened.
+type notifier interface {
+	Notify(ctx context.Context, uid string, n dto.Notification) error
+}
+
+type notificationService struct {
+	devices deviceNSStore
+	sender  pushSender
+}
+
+func NewNotificationService(devices deviceNSStore, sender pushSender) *notificationService {
+	return &notificationService{
+		devices: devices,
+		sender:  sender,
+	}
+}
+
+func (s *notificationService) RegisterDevice(ctx context.Context, uid, token, platform string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return errs.NewValidationError("token is required")
+	}
+	switch platform {
+	case models.DevicePlatformIOS, models.DevicePlatformAndroid, models.DevicePlatformWeb:
+	default:
+		return errs.NewValidationError(fmt.Sprintf("invalid platform: %q (expected ios, android or web)", platform))
+	}
+	return s.devices.Upsert(ctx, uid, &models.Device{
+		Token:    token,
+		Platform: platform,
+	})
+}
+
+func (s *notificationService) UnregisterDevice(ctx context.Context, uid, token string) error {
+	return s.devices.Delete(ctx, uid, []string{token})
+}
+
+// Notify pushes n to every registered device. Tokens the provider rejects are
+// removed so they aren't tried again.
+func (s *notificationService) Notify(ctx context.Context, uid string, n dto.Notification) error {
+	devices, err := s.devices.List(ctx, uid)
+	if err != nil || len(devices) == 0 {
+		return err
+	}
+	tokens := make([]string, 0, len(devices))
+	for _, d := range devices {
+		tokens = append(tokens, d.Token)
+	}
+
+	invalid, err := s.sender.Send(ctx, tokens, n)
+	if len(invalid) > 0 {
+		if delErr := s.devices.Delete(ctx, uid, invalid); delErr != nil {
+			log := logger.FromContext(ctx)
+			log.Warn("failed to remove invalid device tokens", "count", len(invalid), "error", delErr)
+		}
+	}
+	return err
+}
+
+// sendNotification delivers n on a best-effort basis; a failed push never fails the
+// operation that raised it.
+func sendNotification(ctx context.Context, nt notifier, uid string, n dto.Notification) {
+	if nt == nil {
+		return
+	}
+	if err := nt.Notify(ctx, uid, n); err != nil {
+		log := logger.FromContext(ctx)
+		log.Warn("notification failed", "type", n.Data["type"], "error", err)
+	}
+}
+
+func syncCompletedNotification(inserted int) dto.Notification {
+	body := "1 new transaction"
+	if inserted != 1 {
+		body = fmt.Sprintf("%d new transactions", inserted)
+	}
+	return dto.Notification{
+		Title: "Accounts updated",
+		Body:  body,
+		Data:  map[string]string{"type": dto.NotificationSyncCompleted},
+	}
+}
+
+func loginRequiredNotification(bankID, institution string) dto.Notification {
+	name := institution
+	if name == "" {
+		name = "One of your banks"
+	}
+	return dto.Notification{
+		Title: "Reconnect your bank",
+		Body:  fmt.Sprintf("%s needs you to sign in again to keep syncing.", name),
+		Data:  map[string]string{"type": dto.NotificationLoginRequired, "bankId": bankID},
+	}
+}
+
+// alertNotification summarizes newly raised alerts in one notification.
+func alertNotification(alerts []models.Alert) dto.Notification {
+	if len(alerts) == 1 {
+		a := alerts[0]
+		title := a.RuleName
+		if title == "" {
+			title = "Transaction alert"
+		}
+		return dto.Notification{
+			Title: title,
+			Body:  fmt.Sprintf("%s %s %s", a.Name, a.Amount(), a.Currency),
+			Data:  map[string]string{"type": dto.NotificationAlert, "alertId": a.AlertID, "transactionId": a.TransactionID},
+		}
+	}
+	return dto.Notification{
+		Title: "Transaction alerts",
+		Body:  fmt.Sprintf("%d transactions matched your alert rules", len(alerts)),
+		Data:  map[string]string{"type": dto.NotificationAlert},
+	}
+}
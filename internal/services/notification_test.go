@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+type fakeDeviceStore struct {
+	devices []*models.Device
+	deleted []string
+}
+
+func (f *fakeDeviceStore) Upsert(ctx context.Context, uid string, device *models.Device) error {
+	f.devices = append(f.devices, device)
+	return nil
+}
+
+func (f *fakeDeviceStore) List(ctx context.Context, uid string) ([]*models.Device, error) {
+	return f.devices, nil
+}
+
+func (f *fakeDeviceStore) Delete(ctx context.Context, uid string, tokens []string) error {
+	f.deleted = append(f.deleted, tokens...)
+	return nil
+}
+
+// fakePushSender records what would have been pushed and rejects the tokens in invalid.
+type fakePushSender struct {
+	sent    []dto.Notification
+	tokens  []string
+	invalid map[string]bool
+	err     error
+}
+
+func (f *fakePushSender) Send(ctx context.Context, tokens []string, n dto.Notification) ([]string, error) {
+	f.sent = append(f.sent, n)
+	f.tokens = append(f.tokens, tokens...)
+	var rejected []string
+	for _, t := range tokens {
+		if f.invalid[t] {
+			rejected = append(rejected, t)
+		}
+	}
+	return rejected, f.err
+}
+
+// fakeNotifier is an in-memory notifier for services that raise notifications.
+type fakeNotifier struct {
+	uids []string
+	sent []dto.Notification
+	err  error
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, uid string, n dto.Notification) error {
+	f.uids = append(f.uids, uid)
+	f.sent = append(f.sent, n)
+	return f.err
+}
+
+func TestRegisterDeviceValidates(t *testing.T) {
+	devices := &fakeDeviceStore{}
+	svc := NewNotificationService(devices, &fakePushSender{})
+	ctx := helpers.TestCtx()
+
+	for _, tc := range []struct{ token, platform string }{
+		{"", models.DevicePlatformIOS},
+		{"  ", models.DevicePlatformIOS},
+		{"tok-1", "blackberry"},
+	} {
+		err := svc.RegisterDevice(ctx, "uid-1", tc.token, tc.platform)
+		var vErr *errs.ValidationError
+		if !errors.As(err, &vErr) {
+			t.Fatalf("expected validation error for %+v, got %v", tc, err)
+		}
+	}
+
+	if err := svc.RegisterDevice(ctx, "uid-1", " tok-1 ", models.DevicePlatformAndroid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(devices.devices) != 1 || devices.devices[0].Token != "tok-1" || devices.devices[0].Platform != models.DevicePlatformAndroid {
+		t.Fatalf("unexpected stored devices: %+v", devices.devices)
+	}
+}
+
+func TestNotifyPrunesInvalidTokens(t *testing.T) {
+	devices := &fakeDeviceStore{devices: []*models.Device{{Token: "good"}, {Token: "stale"}}}
+	sender := &fakePushSender{invalid: map[string]bool{"stale": true}}
+	svc := NewNotificationService(devices, sender)
+
+	n := syncCompletedNotification(3)
+	if err := svc.Notify(helpers.TestCtx(), "uid-1", n); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Body != "3 new transactions" || len(sender.tokens) != 2 {
+		t.Fatalf("unexpected push: %+v to %v", sender.sent, sender.tokens)
+	}
+	if len(devices.deleted) != 1 || devices.deleted[0] != "stale" {
+		t.Fatalf("expected stale token to be removed, got %v", devices.deleted)
+	}
+}
+
+func TestNotifyWithoutDevicesSkipsSend(t *testing.T) {
+	sender := &fakePushSender{}
+	svc := NewNotificationService(&fakeDeviceStore{}, sender)
+
+	if err := svc.Notify(helpers.TestCtx(), "uid-1", syncCompletedNotification(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sender.sent) != 0 {
+		t.Fatalf("expected no push without devices, got %+v", sender.sent)
+	}
+}
+
+func TestSyncTransactionsNotifiesNewTransactions(t *testing.T) {
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{
+			{Transactions: []models.Transaction{{TransactionID: "t1"}, {TransactionID: "t2"}}, Cursor: "c1"},
+		},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-123"}}}
+	notify := &fakeNotifier{err: errors.New("fcm down")}
+
+	svc := NewPlaidService(pl, banks, &fakeTxStore{}, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, notify)
+	if _, err := svc.SyncTransactions(helpers.TestCtx(), "uid-1", nil); err != nil {
+		t.Fatalf("notification failure should not fail the sync: %v", err)
+	}
+	if len(notify.sent) != 1 || notify.uids[0] != "uid-1" {
+		t.Fatalf("expected one notification for uid-1, got %+v", notify.sent)
+	}
+	if n := notify.sent[0]; n.Data["type"] != dto.NotificationSyncCompleted || n.Body != "2 new transactions" {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+}
+
+func TestSyncTransactionsLoginRequiredNotifies(t *testing.T) {
+	loginErr := errs.NewExternalServiceError("plaid", "failed to sync transactions", false, nil)
+	loginErr.Code = "ITEM_LOGIN_REQUIRED"
+	pl := &fakePlaid{syncErr: loginErr}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Institution: "Chase", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+	notify := &fakeNotifier{}
+
+	svc := NewPlaidService(pl, banks, &fakeTxStore{}, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, notify)
+	if _, err := svc.SyncTransactions(helpers.TestCtx(), "uid-1", helpers.Ptr("item-1")); err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(notify.sent) != 1 {
+		t.Fatalf("expected one notification, got %+v", notify.sent)
+	}
+	n := notify.sent[0]
+	if n.Data["type"] != dto.NotificationLoginRequired || n.Data["bankId"] != "item-1" || n.Body != "Chase needs you to sign in again to keep syncing." {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+}
+
+func TestSyncTransactionsLoginRequiredNotifiesOnce(t *testing.T) {
+	loginErr := errs.NewExternalServiceError("plaid", "failed to sync transactions", false, nil)
+	loginErr.Code = "ITEM_LOGIN_REQUIRED"
+	pl := &fakePlaid{syncErr: loginErr}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusLoginRequired, PlaidPublicToken: "at-123"}}}
+	notify := &fakeNotifier{}
+
+	svc := NewPlaidService(pl, banks, &fakeTxStore{}, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, notify)
+	if _, err := svc.SyncTransactions(helpers.TestCtx(), "uid-1", helpers.Ptr("item-1")); err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(notify.sent) != 0 {
+		t.Fatalf("bank was already flagged, expected no notification, got %+v", notify.sent)
+	}
+}
+
+func TestHandlePlaidWebhookLoginRequiredNotifies(t *testing.T) {
+	svc, priv, _, _, _ := newTestWebhookService(t)
+	notify := &fakeNotifier{}
+	svc.notify = notify
+	body := []byte(`{"webhook_type":"ITEM","webhook_code":"ERROR","item_id":"item-1","error":{"error_code":"ITEM_LOGIN_REQUIRED"}}`)
+
+	// Plaid repeats the ERROR webhook; only the change to login_required notifies.
+	for i := 0; i < 2; i++ {
+		token := signWebhook(t, priv, "kid-1", body, time.Now())
+		if err := svc.HandlePlaidWebhook(helpers.TestCtx(), token, body); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(notify.sent) != 1 || notify.uids[0] != "uid-1" || notify.sent[0].Data["bankId"] != "item-1" {
+		t.Fatalf("unexpected notifications: %+v", notify.sent)
+	}
+	if notify.sent[0].Body != "Chase needs you to sign in again to keep syncing." {
+		t.Fatalf("unexpected notification body: %q", notify.sent[0].Body)
+	}
+}
+
+func TestEvaluateTransactionsNotifiesCreatedAlerts(t *testing.T) {
+	store := &alertFakeStore{
+		rules:    []*models.AlertRule{{RuleID: "big", Name: "Big charge", MinAmountMinor: 50000, Currency: "USD"}},
+		existing: map[string]bool{"big_t1": true},
+	}
+	notify := &fakeNotifier{}
+	svc := NewAlertService(store, &alertFakeTxStore{}, notify)
+
+	txs := []models.Transaction{
+		{TransactionID: "t1", Name: "Airline", AmountMinor: 60000, Currency: "USD"},
+		{TransactionID: "t2", Name: "Hotel", AmountMinor: 75000, Currency: "USD"},
+	}
+	if _, err := svc.EvaluateTransactions(helpers.TestCtx(), "uid-1", txs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(notify.sent) != 1 {
+		t.Fatalf("expected one notification, got %+v", notify.sent)
+	}
+	n := notify.sent[0]
+	if n.Title != "Big charge" || n.Data["type"] != dto.NotificationAlert || n.Data["alertId"] != "big_t2" {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+}
@@ -2,50 +2,94 @@ package services
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/models"
 	"github.com/GregMSThompson/finance-backend/pkg/helpers"
 	"github.com/GregMSThompson/finance-backend/pkg/logger"
 )
 
+const (
+	// Bounds how often a single sync restarts pagination after upstream mutations.
+	maxPaginationRestarts = 3
+	// Limits how many of a user's banks are synced against Plaid at once.
+	maxConcurrentBankSyncs = 4
+)
+
 // --- Dependencies (minimal interfaces scoped to this service) ---
 
 // bankPSStore keeps the service decoupled from the concrete storage implementation.
 type bankPSStore interface {
 	Create(ctx context.Context, uid string, bank *models.Bank) error
 	List(ctx context.Context, uid string) ([]*models.Bank, error)
+	Get(ctx context.Context, uid, bankID string) (*models.Bank, error)
+	UpdateStatus(ctx context.Context, uid, bankID, status, errorCode string) error
 }
 
 // transactionPSStore is the minimal surface required for sync operations.
 type transactionPSStore interface {
-	UpsertBatch(ctx context.Context, uid string, txs []models.Transaction) error
-	GetCursor(ctx context.Context, uid, bankID string) (string, error)
-	SetCursor(ctx context.Context, uid, bankID, cursor string) error
+	UpsertBatch(ctx context.Context, uid string, txs []models.Transaction) (dto.TransactionUpsertResult, error)
+	DeleteBatch(ctx context.Context, uid string, transactionIDs []string) error
+	GetCursor(ctx context.Context, uid, bankID string) (dto.PlaidSyncCursor, error)
+	SetCursor(ctx context.Context, uid, bankID, cursor string, paginationStart *string) error
+}
+
+// accountPSStore persists account metadata and balances refreshed from Plaid.
+type accountPSStore interface {
+	UpsertBatch(ctx context.Context, uid string, accounts []models.Account) error
+}
+
+// syncRunPSStore records the history of each bank sync.
+type syncRunPSStore interface {
+	Create(ctx context.Context, uid string, run *models.SyncRun) error
+}
+
+// alertEvaluator raises alerts for transactions stored by a sync.
+type alertEvaluator interface {
+	EvaluateTransactions(ctx context.Context, uid string, txs []models.Transaction) (int, error)
 }
 
 // plaidClient is the Plaid SDK adapter surface used by this service.
 type plaidClient interface {
 	CreateLinkToken(ctx context.Context, uid string) (linkToken string, err error)
+	CreateUpdateLinkToken(ctx context.Context, uid, accessToken string) (linkToken string, err error)
 	ExchangePublicToken(ctx context.Context, publicToken string) (itemID string, accessToken string, err error)
 	SyncTransactions(ctx context.Context, bankID string, accessToken string, cursor *string) (dto.PlaidSyncPage, error)
+	GetAccounts(ctx context.Context, bankID, accessToken string) ([]models.Account, error)
+	GetAccountBalances(ctx context.Context, bankID, accessToken string) ([]models.Account, error)
 }
 
 type plaidService struct {
 	plaid    plaidClient
 	banks    bankPSStore
 	txs      transactionPSStore
+	accounts accountPSStore
+	runs     syncRunPSStore
+	alerts   alertEvaluator
+	notify   notifier
 	clockNow func() time.Time
+
+	syncConcurrency int
 }
 
-func NewPlaidService(plaid plaidClient, banks bankPSStore, txs transactionPSStore) *plaidService {
+// NewPlaidService builds the sync service. alerts and notify may be nil to skip
+// alert evaluation and push notifications.
+func NewPlaidService(plaid plaidClient, banks bankPSStore, txs transactionPSStore, accounts accountPSStore, runs syncRunPSStore, alerts alertEvaluator, notify notifier) *plaidService {
 	return &plaidService{
 		plaid:    plaid,
 		banks:    banks,
 		txs:      txs,
+		accounts: accounts,
+		runs:     runs,
+		alerts:   alerts,
+		notify:   notify,
 		clockNow: time.Now,
+
+		syncConcurrency: maxConcurrentBankSyncs,
 	}
 }
 
@@ -57,6 +101,27 @@ func (s *plaidService) CreateLinkToken(ctx context.Context, uid string) (string,
 	return linkToken, nil
 }
 
+// CreateUpdateLinkToken returns a Link token in update mode for an existing bank,
+// letting the user re-authenticate without deleting and re-linking it.
+func (s *plaidService) CreateUpdateLinkToken(ctx context.Context, uid, bankID string) (string, error) {
+	bank, err := s.banks.Get(ctx, uid, bankID)
+	if err != nil {
+		return "", err
+	}
+	if bank.PlaidPublicToken == "" {
+		return "", errs.NewValidationError("bank has no Plaid access token; link it again")
+	}
+
+	linkToken, err := s.plaid.CreateUpdateLinkToken(ctx, uid, bank.PlaidPublicToken)
+	if err != nil {
+		return "", err
+	}
+
+	log := logger.FromContext(ctx)
+	log.Info("update link token created", "bank_id", bankID, "bank_status", bank.Status)
+	return linkToken, nil
+}
+
 func (s *plaidService) ExchangePublicToken(ctx context.Context, uid, publicToken, institutionName string) (string, error) {
 	itemID, accessToken, err := s.plaid.ExchangePublicToken(ctx, publicToken)
 	if err != nil {
@@ -66,7 +131,7 @@ func (s *plaidService) ExchangePublicToken(ctx context.Context, uid, publicToken
 	bank := &models.Bank{
 		BankID:           itemID,
 		Institution:      institutionName,
-		Status:           "active",
+		Status:           models.BankStatusActive,
 		PlaidPublicToken: accessToken,
 		CreatedAt:        s.clockNow(),
 		UpdatedAt:        s.clockNow(),
@@ -76,10 +141,22 @@ func (s *plaidService) ExchangePublicToken(ctx context.Context, uid, publicToken
 	}
 
 	log := logger.FromContext(ctx)
+	// Accounts are refreshed again on every sync, so a failure here is not fatal to linking.
+	accounts, err := s.plaid.GetAccounts(ctx, itemID, accessToken)
+	if err == nil {
+		err = s.accounts.UpsertBatch(ctx, uid, accounts)
+	}
+	if err != nil {
+		log.Warn("account refresh after link failed", "bank_id", itemID, "error", err)
+	}
+
 	log.Info("bank linked", "bank_id", itemID, "institution", institutionName)
 	return itemID, nil
 }
 
+// SyncTransactions syncs every bank for the user, or only bankID when set. Banks are
+// synced concurrently and a failing bank is reported in the per-bank results without
+// affecting the others. A single-bank sync also returns that bank's error.
 func (s *plaidService) SyncTransactions(ctx context.Context, uid string, bankID *string) (dto.PlaidServiceSyncResult, error) {
 	result := dto.PlaidServiceSyncResult{}
 	log := logger.FromContext(ctx)
@@ -89,66 +166,285 @@ func (s *plaidService) SyncTransactions(ctx context.Context, uid string, bankID
 		return result, err
 	}
 
-	banksToSync := len(banks)
-	if bankID != nil {
-		banksToSync = 1
-	}
-	log.Info("transaction sync started", "bank_count", banksToSync)
-
+	targets := make([]*models.Bank, 0, len(banks))
 	for _, b := range banks {
 		if bankID != nil && *bankID != b.BankID {
 			continue
 		}
+		targets = append(targets, b)
+	}
+	log.Info("transaction sync started", "bank_count", len(targets))
+
+	bankResults := make([]dto.PlaidServiceSyncResult, len(targets))
+	syncErrs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s.syncConcurrency)
+	for i, b := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, b *models.Bank) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			bankResults[i], syncErrs[i] = s.syncBank(ctx, uid, b)
+		}(i, b)
+	}
+	wg.Wait()
+
+	result.Banks = make([]dto.PlaidBankSyncResult, 0, len(targets))
+	inserted := make([]models.Transaction, 0)
+	for i, b := range targets {
+		inserted = append(inserted, bankResults[i].NewTransactions...)
+		bankResult := toBankSyncResult(b.BankID, bankResults[i], syncErrs[i])
+		result.Banks = append(result.Banks, bankResult)
+		result.TransactionsInserted += bankResult.TransactionsInserted
+		result.TransactionsUpdated += bankResult.TransactionsUpdated
+		result.TransactionsRemoved += bankResult.TransactionsRemoved
+		if bankResult.Success {
+			result.BanksSynced++
+		} else {
+			result.BanksFailed++
+		}
+	}
+
+	result.AlertsCreated = s.evaluateAlerts(ctx, uid, inserted)
+	if result.TransactionsInserted > 0 {
+		sendNotification(ctx, s.notify, uid, syncCompletedNotification(result.TransactionsInserted))
+	}
+
+	if bankID != nil && len(targets) == 1 {
+		if syncErrs[0] != nil {
+			return result, syncErrs[0]
+		}
+		result.Cursor = bankResults[0].Cursor
+	}
 
-		token := b.PlaidPublicToken
-		if token == "" {
-			return result, fmt.Errorf("plaid access token missing for bank %s", b.BankID)
+	log.Info("transaction sync completed", "banks_synced", result.BanksSynced, "banks_failed", result.BanksFailed, "transactions_inserted", result.TransactionsInserted, "transactions_updated", result.TransactionsUpdated, "transactions_removed", result.TransactionsRemoved)
+	return result, nil
+}
+
+// evaluateAlerts runs the user's alert rules over the transactions a sync inserted.
+// Alerts are a side effect of syncing, so a failure is logged rather than returned.
+func (s *plaidService) evaluateAlerts(ctx context.Context, uid string, inserted []models.Transaction) int {
+	if s.alerts == nil || len(inserted) == 0 {
+		return 0
+	}
+	created, err := s.alerts.EvaluateTransactions(ctx, uid, inserted)
+	if err != nil {
+		log := logger.FromContext(ctx)
+		log.Error("alert evaluation failed", "transactions", len(inserted), "error", err)
+		return 0
+	}
+	return created
+}
+
+// toBankSyncResult summarizes one bank's sync, surfacing the Plaid error code and
+// transient flag so clients can tell a broken item from a temporary outage.
+func toBankSyncResult(bankID string, res dto.PlaidServiceSyncResult, err error) dto.PlaidBankSyncResult {
+	bankResult := dto.PlaidBankSyncResult{
+		BankID:               bankID,
+		Success:              err == nil,
+		TransactionsInserted: res.TransactionsInserted,
+		TransactionsUpdated:  res.TransactionsUpdated,
+		TransactionsRemoved:  res.TransactionsRemoved,
+	}
+	if err != nil {
+		bankResult.Error = err.Error()
+		var extErr *errs.ExternalServiceError
+		if errors.As(err, &extErr) {
+			bankResult.ErrorCode = extErr.Code
+			bankResult.Transient = extErr.Transient
 		}
+	}
+	return bankResult
+}
 
-		storedCursor, err := s.txs.GetCursor(ctx, uid, b.BankID)
+// syncBank syncs a single bank and records the run in the bank's sync history.
+func (s *plaidService) syncBank(ctx context.Context, uid string, b *models.Bank) (dto.PlaidServiceSyncResult, error) {
+	run := &models.SyncRun{
+		BankID:    b.BankID,
+		StartedAt: s.clockNow(),
+	}
+
+	result, err := s.drainBank(ctx, uid, b, run)
+	s.recordRun(ctx, uid, run, result, err)
+	return result, err
+}
+
+// drainBank pages through /transactions/sync for one bank, applying every page.
+// The run's cursors are filled in as they become known.
+func (s *plaidService) drainBank(ctx context.Context, uid string, b *models.Bank, run *models.SyncRun) (dto.PlaidServiceSyncResult, error) {
+	result := dto.PlaidServiceSyncResult{}
+	log := logger.FromContext(ctx)
+
+	token := b.PlaidPublicToken
+	if token == "" {
+		return result, errs.NewValidationError("bank has no Plaid access token; link it again")
+	}
+
+	stored, err := s.txs.GetCursor(ctx, uid, b.BankID)
+	if err != nil {
+		return result, err
+	}
+	run.CursorBefore = stored.Cursor
+	run.CursorAfter = stored.Cursor
+	var cursor *string
+	if stored.Cursor != "" {
+		cursor = &stored.Cursor
+	}
+
+	// Each page's cursor is checkpointed once the page is applied, so an interrupted
+	// sync resumes from the last complete page. Upserts and deletes are idempotent,
+	// which makes replaying a page after a failed checkpoint harmless. The cursor the
+	// loop began from is stored alongside, so a resumed loop still knows where to
+	// restart.
+	paginationStart := helpers.Value(stored.PaginationStart)
+	if stored.PaginationStart == nil {
+		paginationStart = stored.Cursor
+	}
+	restarts := 0
+	hasMore := true
+	for hasMore {
+		page, err := s.plaid.SyncTransactions(ctx, b.BankID, token, cursor)
 		if err != nil {
+			// Plaid asks callers to restart from the cursor the pagination loop began with.
+			if isMutationDuringPagination(err) && restarts < maxPaginationRestarts {
+				restarts++
+				log.Warn("transactions changed during pagination, restarting", "bank_id", b.BankID, "restart", restarts)
+				if err := s.txs.SetCursor(ctx, uid, b.BankID, paginationStart, nil); err != nil {
+					return result, err
+				}
+				run.CursorAfter = paginationStart
+				cursor = nil
+				if paginationStart != "" {
+					cursor = &paginationStart
+				}
+				continue
+			}
+			log.Warn("bank sync failed", "bank_id", b.BankID)
+			s.markLoginRequired(ctx, uid, b, err)
 			return result, err
 		}
-		var cursor *string
-		if storedCursor != "" {
-			cursor = &storedCursor
-		}
 
-		latestCursor := storedCursor
-		hasMore := true
-		for hasMore {
-			page, err := s.plaid.SyncTransactions(ctx, b.BankID, token, cursor)
+		if len(page.Transactions) > 0 {
+			upserted, err := s.txs.UpsertBatch(ctx, uid, page.Transactions)
 			if err != nil {
-				log.Warn("bank sync failed", "bank_id", b.BankID)
 				return result, err
 			}
+			result.TransactionsInserted += upserted.Inserted
+			result.TransactionsUpdated += upserted.Updated
+			result.NewTransactions = append(result.NewTransactions, insertedTransactions(page.Transactions, upserted.InsertedIDs)...)
+		}
 
-			if len(page.Transactions) > 0 {
-				if err := s.txs.UpsertBatch(ctx, uid, page.Transactions); err != nil {
-					return result, err
-				}
-				// Optionally compute inserted/updated counts inside UpsertBatch and return them instead.
-				result.TransactionsInserted += len(page.Transactions)
+		// Removed transactions were reversed or merged upstream and must not
+		// linger in analytics.
+		if len(page.RemovedTransactionIDs) > 0 {
+			if err := s.txs.DeleteBatch(ctx, uid, page.RemovedTransactionIDs); err != nil {
+				return result, err
 			}
-
-			latestCursor = page.Cursor
-			cursor = &latestCursor
-			hasMore = page.HasMore
+			result.TransactionsRemoved += len(page.RemovedTransactionIDs)
 		}
 
-		if latestCursor != "" {
-			if err := s.txs.SetCursor(ctx, uid, b.BankID, latestCursor); err != nil {
+		if page.Cursor != "" {
+			var start *string
+			if page.HasMore {
+				start = &paginationStart
+			}
+			if err := s.txs.SetCursor(ctx, uid, b.BankID, page.Cursor, start); err != nil {
 				return result, err
 			}
+			run.CursorAfter = page.Cursor
 		}
 
-		result.BanksSynced++
-		if bankID != nil {
-			result.Cursor = helpers.Value(cursor)
-			break
+		nextCursor := page.Cursor
+		cursor = &nextCursor
+		hasMore = page.HasMore
+	}
+	result.Cursor = helpers.Value(cursor)
+
+	// Balances are best-effort: stale balances should not fail a transaction sync.
+	accounts, err := s.plaid.GetAccountBalances(ctx, b.BankID, token)
+	if err == nil {
+		err = s.accounts.UpsertBatch(ctx, uid, accounts)
+	}
+	if err != nil {
+		log.Warn("account balance refresh failed", "bank_id", b.BankID, "error", err)
+	}
+
+	// A successful sync proves a broken item is healthy again (e.g. after update
+	// mode). Pending expiration and revocation still stand until Plaid says otherwise.
+	if b.Status == models.BankStatusLoginRequired || b.Status == models.BankStatusError {
+		if err := s.banks.UpdateStatus(ctx, uid, b.BankID, models.BankStatusActive, ""); err != nil {
+			return result, err
 		}
 	}
 
-	log.Info("transaction sync completed", "banks_synced", result.BanksSynced, "transactions_inserted", result.TransactionsInserted)
 	return result, nil
 }
+
+// insertedTransactions picks the transactions whose ids were newly inserted.
+func insertedTransactions(txs []models.Transaction, ids []string) []models.Transaction {
+	if len(ids) == 0 {
+		return nil
+	}
+	inserted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		inserted[id] = true
+	}
+	out := make([]models.Transaction, 0, len(ids))
+	for _, tx := range txs {
+		if inserted[tx.TransactionID] {
+			out = append(out, tx)
+		}
+	}
+	return out
+}
+
+// recordRun persists the outcome of a bank sync. History is diagnostic only, so a
+// failure to write it is logged rather than failing the sync.
+func (s *plaidService) recordRun(ctx context.Context, uid string, run *models.SyncRun, result dto.PlaidServiceSyncResult, syncErr error) {
+	run.EndedAt = s.clockNow()
+	run.TransactionsInserted = result.TransactionsInserted
+	run.TransactionsUpdated = result.TransactionsUpdated
+	run.TransactionsRemoved = result.TransactionsRemoved
+	run.Status = models.SyncRunStatusSuccess
+	if syncErr != nil {
+		run.Status = models.SyncRunStatusFailed
+		run.ErrorMessage = syncErr.Error()
+		var extErr *errs.ExternalServiceError
+		if errors.As(syncErr, &extErr) {
+			run.ErrorCode = extErr.Code
+		}
+	}
+
+	if err := s.runs.Create(ctx, uid, run); err != nil {
+		log := logger.FromContext(ctx)
+		log.Error("failed to record sync run", "bank_id", run.BankID, "error", err)
+	}
+}
+
+// isMutationDuringPagination reports whether Plaid rejected a page because the
+// item's transactions changed while the caller was paginating.
+func isMutationDuringPagination(err error) bool {
+	var extErr *errs.ExternalServiceError
+	return errors.As(err, &extErr) && extErr.Code == "TRANSACTIONS_SYNC_MUTATION_DURING_PAGINATION"
+}
+
+// markLoginRequired flags a bank whose Plaid item needs the user to re-authenticate
+// through Link update mode. A bank already flagged is left alone, so the user is
+// notified once rather than on every failed sync. Failures are logged so the
+// original sync error wins.
+func (s *plaidService) markLoginRequired(ctx context.Context, uid string, bank *models.Bank, err error) {
+	var extErr *errs.ExternalServiceError
+	if !errors.As(err, &extErr) || extErr.Code != "ITEM_LOGIN_REQUIRED" || bank.Status == models.BankStatusLoginRequired {
+		return
+	}
+
+	log := logger.FromContext(ctx)
+	if err := s.banks.UpdateStatus(ctx, uid, bank.BankID, models.BankStatusLoginRequired, extErr.Code); err != nil {
+		log.Error("failed to mark bank login required", "bank_id", bank.BankID, "error", err)
+		return
+	}
+	bank.Status = models.BankStatusLoginRequired
+	log.Warn("bank requires re-authentication", "bank_id", bank.BankID)
+	sendNotification(ctx, s.notify, uid, loginRequiredNotification(bank.BankID, bank.Institution))
+}
@@ -3,10 +3,13 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/models"
 	"github.com/GregMSThompson/finance-backend/pkg/helpers"
 )
@@ -14,13 +17,23 @@ import (
 // --- fakes ---
 
 type fakePlaid struct {
+	mu             sync.Mutex
+	syncErrByBank  map[string]error
+	accounts       []models.Account
+	accountsErr    error
+	balanceCalls   int
 	linkToken      string
+	updateToken    string
+	updateAccess   string
 	itemID         string
 	accessToken    string
 	syncPages      []dto.PlaidSyncPage
 	createLinkErr  error
 	exchangeErr    error
 	syncErr        error
+	syncErrAt      map[int]error // errors keyed by SyncTransactions attempt number
+	syncAttempts   int
+	syncCursors    []string
 	syncCalls      int
 	exchangeCalled bool
 }
@@ -29,15 +42,42 @@ func (f *fakePlaid) CreateLinkToken(ctx context.Context, uid string) (string, er
 	return f.linkToken, f.createLinkErr
 }
 
+func (f *fakePlaid) CreateUpdateLinkToken(ctx context.Context, uid, accessToken string) (string, error) {
+	f.updateAccess = accessToken
+	return f.updateToken, f.createLinkErr
+}
+
+func (f *fakePlaid) GetAccounts(ctx context.Context, bankID, accessToken string) ([]models.Account, error) {
+	return f.accounts, f.accountsErr
+}
+
+func (f *fakePlaid) GetAccountBalances(ctx context.Context, bankID, accessToken string) ([]models.Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balanceCalls++
+	return f.accounts, f.accountsErr
+}
+
 func (f *fakePlaid) ExchangePublicToken(ctx context.Context, publicToken string) (string, string, error) {
 	f.exchangeCalled = true
 	return f.itemID, f.accessToken, f.exchangeErr
 }
 
 func (f *fakePlaid) SyncTransactions(ctx context.Context, bankID string, accessToken string, cursor *string) (dto.PlaidSyncPage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err, ok := f.syncErrByBank[bankID]; ok {
+		return dto.PlaidSyncPage{}, err
+	}
+	attempt := f.syncAttempts
+	f.syncAttempts++
+	f.syncCursors = append(f.syncCursors, helpers.Value(cursor))
 	if f.syncErr != nil {
 		return dto.PlaidSyncPage{}, f.syncErr
 	}
+	if err, ok := f.syncErrAt[attempt]; ok {
+		return dto.PlaidSyncPage{}, err
+	}
 	if f.syncCalls >= len(f.syncPages) {
 		return dto.PlaidSyncPage{}, nil
 	}
@@ -47,9 +87,11 @@ func (f *fakePlaid) SyncTransactions(ctx context.Context, bankID string, accessT
 }
 
 type fakeBankStore struct {
-	created []*models.Bank
-	list    []*models.Bank
-	err     error
+	mu       sync.Mutex
+	created  []*models.Bank
+	list     []*models.Bank
+	statuses map[string]string
+	err      error
 }
 
 func (f *fakeBankStore) Create(ctx context.Context, uid string, bank *models.Bank) error {
@@ -62,34 +104,139 @@ func (f *fakeBankStore) Create(ctx context.Context, uid string, bank *models.Ban
 func (f *fakeBankStore) List(ctx context.Context, uid string) ([]*models.Bank, error) {
 	return f.list, f.err
 }
+func (f *fakeBankStore) Get(ctx context.Context, uid, bankID string) (*models.Bank, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, b := range f.list {
+		if b.BankID == bankID {
+			return b, nil
+		}
+	}
+	return nil, errs.NewNotFoundError("bank not found")
+}
+func (f *fakeBankStore) UpdateStatus(ctx context.Context, uid, bankID, status, errorCode string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.statuses == nil {
+		f.statuses = map[string]string{}
+	}
+	f.statuses[bankID] = status
+	return nil
+}
 
 type fakeTxStore struct {
+	mu         sync.Mutex
 	cursor     string
+	pageStart  *string // stored pagination start
 	upserted   [][]models.Transaction
+	deleted    [][]string
+	seen       map[string]bool
 	setCursor  string
+	setCursors []string
 	getErr     error
 	upsertErr  error
+	deleteErr  error
 	setCurErr  error
 }
 
-func (f *fakeTxStore) UpsertBatch(ctx context.Context, uid string, txs []models.Transaction) error {
+func (f *fakeTxStore) UpsertBatch(ctx context.Context, uid string, txs []models.Transaction) (dto.TransactionUpsertResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if f.upsertErr != nil {
-		return f.upsertErr
+		return dto.TransactionUpsertResult{}, f.upsertErr
 	}
 	f.upserted = append(f.upserted, txs)
+	// Treat transactions already seen by this fake as updates.
+	result := dto.TransactionUpsertResult{}
+	for _, tx := range txs {
+		if f.seen[tx.TransactionID] {
+			result.Updated++
+			continue
+		}
+		if f.seen == nil {
+			f.seen = map[string]bool{}
+		}
+		f.seen[tx.TransactionID] = true
+		result.Inserted++
+		result.InsertedIDs = append(result.InsertedIDs, tx.TransactionID)
+	}
+	return result, nil
+}
+func (f *fakeTxStore) DeleteBatch(ctx context.Context, uid string, transactionIDs []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.deleteErr != nil {
+		return f.deleteErr
+	}
+	f.deleted = append(f.deleted, transactionIDs)
 	return nil
 }
-func (f *fakeTxStore) GetCursor(ctx context.Context, uid, bankID string) (string, error) {
-	return f.cursor, f.getErr
+func (f *fakeTxStore) GetCursor(ctx context.Context, uid, bankID string) (dto.PlaidSyncCursor, error) {
+	return dto.PlaidSyncCursor{Cursor: f.cursor, PaginationStart: f.pageStart}, f.getErr
 }
-func (f *fakeTxStore) SetCursor(ctx context.Context, uid, bankID, cursor string) error {
+func (f *fakeTxStore) SetCursor(ctx context.Context, uid, bankID, cursor string, paginationStart *string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if f.setCurErr != nil {
 		return f.setCurErr
 	}
 	f.setCursor = cursor
+	f.pageStart = paginationStart
+	f.setCursors = append(f.setCursors, cursor)
 	return nil
 }
 
+type fakeAccountStore struct {
+	mu       sync.Mutex
+	upserted [][]models.Account
+	err      error
+}
+
+func (f *fakeAccountStore) UpsertBatch(ctx context.Context, uid string, accounts []models.Account) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.upserted = append(f.upserted, accounts)
+	return nil
+}
+
+type fakeSyncRunStore struct {
+	mu   sync.Mutex
+	runs []*models.SyncRun
+	err  error
+}
+
+func (f *fakeSyncRunStore) Create(ctx context.Context, uid string, run *models.SyncRun) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.runs = append(f.runs, run)
+	return nil
+}
+
+type fakeAlertEvaluator struct {
+	mu    sync.Mutex
+	txs   []models.Transaction
+	calls int
+	err   error
+}
+
+func (f *fakeAlertEvaluator) EvaluateTransactions(ctx context.Context, uid string, txs []models.Transaction) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	f.txs = append(f.txs, txs...)
+	if f.err != nil {
+		return 0, f.err
+	}
+	return len(txs), nil
+}
+
 // --- tests ---
 
 func TestExchangePublicTokenStoresBank(t *testing.T) {
@@ -97,7 +244,7 @@ func TestExchangePublicTokenStoresBank(t *testing.T) {
 	banks := &fakeBankStore{}
 	txs := &fakeTxStore{}
 
-	svc := NewPlaidService(pl, banks, txs)
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
 
 	ctx := helpers.TestCtx()
 	_, err := svc.ExchangePublicToken(ctx, "uid-1", "public-xyz", "Chase")
@@ -126,7 +273,7 @@ func TestSyncTransactionsUsesCursorAndSetsNewCursor(t *testing.T) {
 	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-123"}}}
 	txs := &fakeTxStore{cursor: "prev-cursor"}
 
-	svc := NewPlaidService(pl, banks, txs)
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
 	now := time.Unix(1000, 0)
 	svc.clockNow = func() time.Time { return now }
 
@@ -147,14 +294,57 @@ func TestSyncTransactionsUsesCursorAndSetsNewCursor(t *testing.T) {
 	}
 }
 
+func TestSyncTransactionsEvaluatesAlertsForInsertedTransactions(t *testing.T) {
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{
+			{Transactions: []models.Transaction{{TransactionID: "t1"}, {TransactionID: "t2"}}, Cursor: "c1"},
+		},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-123"}}}
+	// t1 is already stored, so only t2 is new.
+	txs := &fakeTxStore{seen: map[string]bool{"t1": true}}
+	alerts := &fakeAlertEvaluator{}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, alerts, nil)
+	res, err := svc.SyncTransactions(helpers.TestCtx(), "uid-1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if alerts.calls != 1 || len(alerts.txs) != 1 || alerts.txs[0].TransactionID != "t2" {
+		t.Fatalf("expected alerts evaluated once for t2, got %d calls: %+v", alerts.calls, alerts.txs)
+	}
+	if res.AlertsCreated != 1 {
+		t.Fatalf("expected 1 alert created, got %d", res.AlertsCreated)
+	}
+}
+
+func TestSyncTransactionsIgnoresAlertFailures(t *testing.T) {
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{
+			{Transactions: []models.Transaction{{TransactionID: "t1"}}, Cursor: "c1"},
+		},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-123"}}}
+	alerts := &fakeAlertEvaluator{err: errors.New("db down")}
+
+	svc := NewPlaidService(pl, banks, &fakeTxStore{}, &fakeAccountStore{}, &fakeSyncRunStore{}, alerts, nil)
+	res, err := svc.SyncTransactions(helpers.TestCtx(), "uid-1", helpers.Ptr("item-1"))
+	if err != nil {
+		t.Fatalf("alert failure should not fail the sync: %v", err)
+	}
+	if res.BanksSynced != 1 || res.AlertsCreated != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
 func TestSyncTransactionsPropagatesErrors(t *testing.T) {
 	pl := &fakePlaid{}
 	banks := &fakeBankStore{err: errors.New("boom")}
 	txs := &fakeTxStore{}
 
-	svc := NewPlaidService(pl, banks, txs)
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
 	ctx := helpers.TestCtx()
-	_, err := svc.SyncTransactions(ctx, "uid-1", nil)
+	_, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-1"))
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
@@ -165,7 +355,7 @@ func TestExchangePublicTokenPropagatesExchangeError(t *testing.T) {
 	banks := &fakeBankStore{}
 	txs := &fakeTxStore{}
 
-	svc := NewPlaidService(pl, banks, txs)
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
 	ctx := helpers.TestCtx()
 	_, err := svc.ExchangePublicToken(ctx, "uid-1", "public-xyz", "Chase")
 	if err == nil {
@@ -181,7 +371,7 @@ func TestExchangePublicTokenPropagatesCreateError(t *testing.T) {
 	banks := &fakeBankStore{err: errors.New("create failed")}
 	txs := &fakeTxStore{}
 
-	svc := NewPlaidService(pl, banks, txs)
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
 	ctx := helpers.TestCtx()
 	_, err := svc.ExchangePublicToken(ctx, "uid-1", "public-xyz", "Chase")
 	if err == nil {
@@ -194,11 +384,12 @@ func TestSyncTransactionsMissingAccessToken(t *testing.T) {
 	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: ""}}}
 	txs := &fakeTxStore{}
 
-	svc := NewPlaidService(pl, banks, txs)
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
 	ctx := helpers.TestCtx()
-	_, err := svc.SyncTransactions(ctx, "uid-1", nil)
-	if err == nil {
-		t.Fatalf("expected error")
+	_, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-1"))
+	var vErr *errs.ValidationError
+	if !errors.As(err, &vErr) {
+		t.Fatalf("expected validation error, got %v", err)
 	}
 }
 
@@ -207,9 +398,9 @@ func TestSyncTransactionsGetCursorError(t *testing.T) {
 	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-123"}}}
 	txs := &fakeTxStore{getErr: errors.New("get cursor failed")}
 
-	svc := NewPlaidService(pl, banks, txs)
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
 	ctx := helpers.TestCtx()
-	_, err := svc.SyncTransactions(ctx, "uid-1", nil)
+	_, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-1"))
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -220,9 +411,9 @@ func TestSyncTransactionsPlaidError(t *testing.T) {
 	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-123"}}}
 	txs := &fakeTxStore{}
 
-	svc := NewPlaidService(pl, banks, txs)
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
 	ctx := helpers.TestCtx()
-	_, err := svc.SyncTransactions(ctx, "uid-1", nil)
+	_, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-1"))
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -237,9 +428,9 @@ func TestSyncTransactionsUpsertError(t *testing.T) {
 	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-123"}}}
 	txs := &fakeTxStore{upsertErr: errors.New("upsert failed")}
 
-	svc := NewPlaidService(pl, banks, txs)
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
 	ctx := helpers.TestCtx()
-	_, err := svc.SyncTransactions(ctx, "uid-1", nil)
+	_, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-1"))
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -254,10 +445,528 @@ func TestSyncTransactionsSetCursorError(t *testing.T) {
 	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-123"}}}
 	txs := &fakeTxStore{setCurErr: errors.New("set cursor failed")}
 
-	svc := NewPlaidService(pl, banks, txs)
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	_, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-1"))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestSyncTransactionsDeletesRemovedTransactions(t *testing.T) {
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{
+			{Transactions: []models.Transaction{{TransactionID: "t1"}}, RemovedTransactionIDs: []string{"old-1", "old-2"}, Cursor: "c1", HasMore: false},
+		},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	res, err := svc.SyncTransactions(ctx, "uid-1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res.TransactionsRemoved != 2 {
+		t.Fatalf("expected 2 transactions removed, got %d", res.TransactionsRemoved)
+	}
+	if len(txs.deleted) != 1 || len(txs.deleted[0]) != 2 || txs.deleted[0][0] != "old-1" {
+		t.Fatalf("unexpected deletes: %+v", txs.deleted)
+	}
+}
+
+func TestSyncTransactionsDeleteError(t *testing.T) {
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{
+			{RemovedTransactionIDs: []string{"old-1"}, Cursor: "c1", HasMore: false},
+		},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{deleteErr: errors.New("delete failed")}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	_, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-1"))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if txs.setCursor != "" {
+		t.Fatalf("cursor should not advance when deletes fail, got %q", txs.setCursor)
+	}
+}
+
+func TestSyncTransactionsMarksLoginRequired(t *testing.T) {
+	loginErr := errs.NewExternalServiceError("plaid", "failed to sync transactions", false, nil)
+	loginErr.Code = "ITEM_LOGIN_REQUIRED"
+	pl := &fakePlaid{syncErr: loginErr}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	_, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-1"))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if banks.statuses["item-1"] != models.BankStatusLoginRequired {
+		t.Fatalf("expected bank to be marked login_required, got %q", banks.statuses["item-1"])
+	}
+}
+
+func TestSyncTransactionsRestoresActiveStatus(t *testing.T) {
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{{Cursor: "c1", HasMore: false}},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusLoginRequired, PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	if _, err := svc.SyncTransactions(ctx, "uid-1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if banks.statuses["item-1"] != models.BankStatusActive {
+		t.Fatalf("expected bank to be marked active, got %q", banks.statuses["item-1"])
+	}
+}
+
+func TestSyncTransactionsKeepsPendingExpirationStatus(t *testing.T) {
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{{Cursor: "c1", HasMore: false}},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusPendingExpiration, PlaidPublicToken: "at-123"}}}
+
+	svc := NewPlaidService(pl, banks, &fakeTxStore{}, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	if _, err := svc.SyncTransactions(helpers.TestCtx(), "uid-1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status, ok := banks.statuses["item-1"]; ok {
+		t.Fatalf("pending expiration should survive a successful sync, got %q", status)
+	}
+}
+
+func TestCreateUpdateLinkTokenUsesStoredAccessToken(t *testing.T) {
+	pl := &fakePlaid{updateToken: "link-update"}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	token, err := svc.CreateUpdateLinkToken(ctx, "uid-1", "item-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "link-update" {
+		t.Fatalf("unexpected link token: %q", token)
+	}
+	if pl.updateAccess != "at-123" {
+		t.Fatalf("expected stored access token to be used, got %q", pl.updateAccess)
+	}
+}
+
+func TestCreateUpdateLinkTokenBankNotFound(t *testing.T) {
+	pl := &fakePlaid{}
+	banks := &fakeBankStore{}
+	txs := &fakeTxStore{}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	_, err := svc.CreateUpdateLinkToken(ctx, "uid-1", "missing")
+
+	var notFound *errs.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected NotFoundError, got %T", err)
+	}
+}
+
+func TestExchangePublicTokenStoresAccounts(t *testing.T) {
+	pl := &fakePlaid{
+		itemID:      "item-1",
+		accessToken: "at-123",
+		accounts:    []models.Account{{AccountID: "acc-1", BankID: "item-1", Type: "depository"}},
+	}
+	accounts := &fakeAccountStore{}
+
+	svc := NewPlaidService(pl, &fakeBankStore{}, &fakeTxStore{}, accounts, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	if _, err := svc.ExchangePublicToken(ctx, "uid-1", "public-xyz", "Chase"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(accounts.upserted) != 1 || accounts.upserted[0][0].AccountID != "acc-1" {
+		t.Fatalf("unexpected account upserts: %+v", accounts.upserted)
+	}
+}
+
+func TestExchangePublicTokenIgnoresAccountErrors(t *testing.T) {
+	pl := &fakePlaid{itemID: "item-1", accessToken: "at-123", accountsErr: errors.New("accounts down")}
+	banks := &fakeBankStore{}
+
+	svc := NewPlaidService(pl, banks, &fakeTxStore{}, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	if _, err := svc.ExchangePublicToken(ctx, "uid-1", "public-xyz", "Chase"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(banks.created) != 1 {
+		t.Fatalf("bank should still be created when accounts fail")
+	}
+}
+
+func TestSyncTransactionsRefreshesBalances(t *testing.T) {
+	balance := int64(12550)
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{{Cursor: "c1", HasMore: false}},
+		accounts:  []models.Account{{AccountID: "acc-1", BankID: "item-1", CurrentBalanceMinor: &balance, Currency: "USD"}},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+	accounts := &fakeAccountStore{}
+
+	svc := NewPlaidService(pl, banks, &fakeTxStore{}, accounts, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	if _, err := svc.SyncTransactions(ctx, "uid-1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pl.balanceCalls != 1 {
+		t.Fatalf("expected 1 balance call, got %d", pl.balanceCalls)
+	}
+	if len(accounts.upserted) != 1 || *accounts.upserted[0][0].CurrentBalanceMinor != 12550 {
+		t.Fatalf("unexpected account upserts: %+v", accounts.upserted)
+	}
+}
+
+func TestSyncTransactionsReportsInsertedAndUpdated(t *testing.T) {
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{
+			{Transactions: []models.Transaction{{TransactionID: "t1"}, {TransactionID: "t2"}}, Cursor: "c1", HasMore: true},
+			{Transactions: []models.Transaction{{TransactionID: "t1", Pending: true}}, Cursor: "c2", HasMore: false},
+		},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
 	ctx := helpers.TestCtx()
-	_, err := svc.SyncTransactions(ctx, "uid-1", nil)
+	res, err := svc.SyncTransactions(ctx, "uid-1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.TransactionsInserted != 2 || res.TransactionsUpdated != 1 {
+		t.Fatalf("unexpected counts: inserted=%d updated=%d", res.TransactionsInserted, res.TransactionsUpdated)
+	}
+}
+
+func TestSyncTransactionsRecordsSuccessfulRun(t *testing.T) {
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{
+			{Transactions: []models.Transaction{{TransactionID: "t1"}}, RemovedTransactionIDs: []string{"t0"}, Cursor: "c1", HasMore: false},
+		},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{cursor: "prev-cursor"}
+	runs := &fakeSyncRunStore{}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, runs, nil, nil)
+	now := time.Unix(1000, 0)
+	svc.clockNow = func() time.Time { return now }
+
+	ctx := helpers.TestCtx()
+	if _, err := svc.SyncTransactions(ctx, "uid-1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runs.runs) != 1 {
+		t.Fatalf("expected 1 run recorded, got %d", len(runs.runs))
+	}
+	run := runs.runs[0]
+	if run.BankID != "item-1" || run.Status != models.SyncRunStatusSuccess {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	if run.CursorBefore != "prev-cursor" || run.CursorAfter != "c1" {
+		t.Fatalf("unexpected cursors: before=%q after=%q", run.CursorBefore, run.CursorAfter)
+	}
+	if run.TransactionsInserted != 1 || run.TransactionsRemoved != 1 {
+		t.Fatalf("unexpected counts: %+v", run)
+	}
+	if !run.StartedAt.Equal(now) || !run.EndedAt.Equal(now) {
+		t.Fatalf("unexpected timestamps: %+v", run)
+	}
+}
+
+func TestSyncTransactionsRecordsFailedRun(t *testing.T) {
+	loginErr := errs.NewExternalServiceError("plaid", "failed to sync transactions", false, nil)
+	loginErr.Code = "ITEM_LOGIN_REQUIRED"
+	pl := &fakePlaid{syncErr: loginErr}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{cursor: "prev-cursor"}
+	runs := &fakeSyncRunStore{}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, runs, nil, nil)
+	ctx := helpers.TestCtx()
+	if _, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-1")); err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(runs.runs) != 1 {
+		t.Fatalf("expected 1 run recorded, got %d", len(runs.runs))
+	}
+	run := runs.runs[0]
+	if run.Status != models.SyncRunStatusFailed || run.ErrorCode != "ITEM_LOGIN_REQUIRED" || run.ErrorMessage == "" {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	if run.CursorAfter != "prev-cursor" {
+		t.Fatalf("expected cursor to be unchanged, got %q", run.CursorAfter)
+	}
+}
+
+func TestSyncTransactionsIgnoresRunRecordingErrors(t *testing.T) {
+	pl := &fakePlaid{syncPages: []dto.PlaidSyncPage{{Cursor: "c1"}}}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+
+	svc := NewPlaidService(pl, banks, &fakeTxStore{}, &fakeAccountStore{}, &fakeSyncRunStore{err: errors.New("db down")}, nil, nil)
+	ctx := helpers.TestCtx()
+	res, err := svc.SyncTransactions(ctx, "uid-1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.BanksSynced != 1 {
+		t.Fatalf("expected 1 bank synced, got %d", res.BanksSynced)
+	}
+}
+
+func TestSyncTransactionsCheckpointsCursorPerPage(t *testing.T) {
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{
+			{Transactions: []models.Transaction{{TransactionID: "t1"}}, Cursor: "c1", HasMore: true},
+			{Transactions: []models.Transaction{{TransactionID: "t2"}}, Cursor: "c2", HasMore: true},
+		},
+		syncErrAt: map[int]error{2: errs.NewExternalServiceError("plaid", "failed to sync transactions", true, nil)},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	if _, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-1")); err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(txs.setCursors) != 2 || txs.setCursors[0] != "c1" || txs.setCursors[1] != "c2" {
+		t.Fatalf("expected cursor checkpoints c1, c2, got %v", txs.setCursors)
+	}
+}
+
+func TestSyncTransactionsResumesFromCheckpoint(t *testing.T) {
+	pl := &fakePlaid{syncPages: []dto.PlaidSyncPage{{Cursor: "c3", HasMore: false}}}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{cursor: "c2"}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	if _, err := svc.SyncTransactions(ctx, "uid-1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pl.syncCursors) != 1 || pl.syncCursors[0] != "c2" {
+		t.Fatalf("expected sync to resume from c2, got %v", pl.syncCursors)
+	}
+}
+
+func TestSyncTransactionsRestartsOnMutationDuringPagination(t *testing.T) {
+	mutationErr := errs.NewExternalServiceError("plaid", "failed to sync transactions", false, nil)
+	mutationErr.Code = "TRANSACTIONS_SYNC_MUTATION_DURING_PAGINATION"
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{
+			{Transactions: []models.Transaction{{TransactionID: "t1"}}, Cursor: "c1", HasMore: true},
+			{Transactions: []models.Transaction{{TransactionID: "t1"}}, Cursor: "c1b", HasMore: true},
+			{Transactions: []models.Transaction{{TransactionID: "t2"}}, Cursor: "c2", HasMore: false},
+		},
+		syncErrAt: map[int]error{1: mutationErr},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{cursor: "c0"}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	if _, err := svc.SyncTransactions(ctx, "uid-1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"c0", "c1", "c0", "c1b"}
+	if len(pl.syncCursors) != len(want) {
+		t.Fatalf("sync cursors = %v, want %v", pl.syncCursors, want)
+	}
+	for i := range want {
+		if pl.syncCursors[i] != want[i] {
+			t.Fatalf("sync cursors = %v, want %v", pl.syncCursors, want)
+		}
+	}
+	if txs.setCursor != "c2" {
+		t.Fatalf("expected final cursor c2, got %q", txs.setCursor)
+	}
+}
+
+func TestSyncTransactionsResumedLoopRestartsFromLoopStart(t *testing.T) {
+	mutationErr := errs.NewExternalServiceError("plaid", "failed to sync transactions", false, nil)
+	mutationErr.Code = "TRANSACTIONS_SYNC_MUTATION_DURING_PAGINATION"
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{
+			{Transactions: []models.Transaction{{TransactionID: "t1"}}, Cursor: "c1", HasMore: true},
+			{Transactions: []models.Transaction{{TransactionID: "t2"}}, Cursor: "c2", HasMore: false},
+		},
+		syncErrAt: map[int]error{0: mutationErr},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+	// An earlier run stopped halfway through a loop that began at c0.
+	txs := &fakeTxStore{cursor: "c-half", pageStart: helpers.Ptr("c0")}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	if _, err := svc.SyncTransactions(helpers.TestCtx(), "uid-1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"c-half", "c0", "c1"}
+	if strings.Join(pl.syncCursors, ",") != strings.Join(want, ",") {
+		t.Fatalf("sync cursors = %v, want %v", pl.syncCursors, want)
+	}
+	// The loop start is written back before restarting, then the loop checkpoints
+	// each page with c0 as its start until it finishes.
+	wantSet := []string{"c0", "c1", "c2"}
+	if strings.Join(txs.setCursors, ",") != strings.Join(wantSet, ",") {
+		t.Fatalf("stored cursors = %v, want %v", txs.setCursors, wantSet)
+	}
+	if txs.setCursor != "c2" || txs.pageStart != nil {
+		t.Fatalf("expected a finished loop at c2, got %q start %v", txs.setCursor, txs.pageStart)
+	}
+}
+
+func TestSyncTransactionsCheckpointsLoopStart(t *testing.T) {
+	pl := &fakePlaid{
+		syncPages: []dto.PlaidSyncPage{
+			{Transactions: []models.Transaction{{TransactionID: "t1"}}, Cursor: "c1", HasMore: true},
+			{Transactions: []models.Transaction{{TransactionID: "t2"}}, Cursor: "c2", HasMore: true},
+		},
+		syncErrAt: map[int]error{2: errors.New("plaid down")},
+	}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+	txs := &fakeTxStore{cursor: "c0"}
+
+	svc := NewPlaidService(pl, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	if _, err := svc.SyncTransactions(helpers.TestCtx(), "uid-1", helpers.Ptr("item-1")); err == nil {
+		t.Fatalf("expected error")
+	}
+	if txs.setCursor != "c2" || helpers.Value(txs.pageStart) != "c0" {
+		t.Fatalf("expected c2 with loop start c0, got %q start %v", txs.setCursor, txs.pageStart)
+	}
+}
+
+func TestSyncTransactionsGivesUpAfterRepeatedMutations(t *testing.T) {
+	mutationErr := errs.NewExternalServiceError("plaid", "failed to sync transactions", false, nil)
+	mutationErr.Code = "TRANSACTIONS_SYNC_MUTATION_DURING_PAGINATION"
+	pl := &fakePlaid{syncErr: mutationErr}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-123"}}}
+
+	svc := NewPlaidService(pl, banks, &fakeTxStore{}, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	if _, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-1")); err == nil {
+		t.Fatalf("expected error")
+	}
+	if pl.syncAttempts != maxPaginationRestarts+1 {
+		t.Fatalf("expected %d attempts, got %d", maxPaginationRestarts+1, pl.syncAttempts)
+	}
+}
+
+func TestSyncTransactionsReportsPartialFailures(t *testing.T) {
+	transientErr := errs.NewExternalServiceError("plaid", "failed to sync transactions", true, nil)
+	transientErr.Code = "INSTITUTION_DOWN"
+	pl := &fakePlaid{syncErrByBank: map[string]error{"item-2": transientErr}}
+	banks := &fakeBankStore{list: []*models.Bank{
+		{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-1"},
+		{BankID: "item-2", Status: models.BankStatusActive, PlaidPublicToken: "at-2"},
+		{BankID: "item-3", Status: models.BankStatusActive, PlaidPublicToken: "at-3"},
+	}}
+
+	svc := NewPlaidService(pl, banks, &fakeTxStore{}, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	res, err := svc.SyncTransactions(ctx, "uid-1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.BanksSynced != 2 || res.BanksFailed != 1 {
+		t.Fatalf("unexpected counts: synced=%d failed=%d", res.BanksSynced, res.BanksFailed)
+	}
+	if len(res.Banks) != 3 {
+		t.Fatalf("expected 3 bank results, got %d", len(res.Banks))
+	}
+	for i, want := range []string{"item-1", "item-2", "item-3"} {
+		if res.Banks[i].BankID != want {
+			t.Fatalf("bank result %d = %q, want %q", i, res.Banks[i].BankID, want)
+		}
+	}
+	failed := res.Banks[1]
+	if failed.Success || failed.ErrorCode != "INSTITUTION_DOWN" || !failed.Transient || failed.Error == "" {
+		t.Fatalf("unexpected failed bank result: %+v", failed)
+	}
+	if !res.Banks[0].Success || !res.Banks[2].Success {
+		t.Fatalf("expected other banks to succeed: %+v", res.Banks)
+	}
+}
+
+func TestSyncTransactionsSingleBankReturnsError(t *testing.T) {
+	pl := &fakePlaid{syncErrByBank: map[string]error{"item-2": errors.New("plaid sync failed")}}
+	banks := &fakeBankStore{list: []*models.Bank{
+		{BankID: "item-1", Status: models.BankStatusActive, PlaidPublicToken: "at-1"},
+		{BankID: "item-2", Status: models.BankStatusActive, PlaidPublicToken: "at-2"},
+	}}
+
+	svc := NewPlaidService(pl, banks, &fakeTxStore{}, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	ctx := helpers.TestCtx()
+	res, err := svc.SyncTransactions(ctx, "uid-1", helpers.Ptr("item-2"))
 	if err == nil {
 		t.Fatalf("expected error")
 	}
+	if len(res.Banks) != 1 || res.Banks[0].BankID != "item-2" || res.Banks[0].Success {
+		t.Fatalf("unexpected bank results: %+v", res.Banks)
+	}
+}
+
+func TestSyncTransactionsLimitsConcurrency(t *testing.T) {
+	banks := &fakeBankStore{}
+	for i := 0; i < 6; i++ {
+		banks.list = append(banks.list, &models.Bank{BankID: "item-" + string(rune('a'+i)), Status: models.BankStatusActive, PlaidPublicToken: "at"})
+	}
+	txs := &blockingCursorStore{release: make(chan struct{})}
+
+	svc := NewPlaidService(&fakePlaid{}, banks, txs, &fakeAccountStore{}, &fakeSyncRunStore{}, nil, nil)
+	svc.syncConcurrency = 2
+
+	done := make(chan dto.PlaidServiceSyncResult)
+	go func() {
+		res, _ := svc.SyncTransactions(helpers.TestCtx(), "uid-1", nil)
+		done <- res
+	}()
+
+	// Let the first wave block, then confirm no more than the limit are in flight.
+	time.Sleep(20 * time.Millisecond)
+	txs.mu.Lock()
+	inFlight := txs.inFlight
+	txs.mu.Unlock()
+	close(txs.release)
+	res := <-done
+
+	if inFlight != 2 {
+		t.Fatalf("expected 2 syncs in flight, got %d", inFlight)
+	}
+	if res.BanksSynced != 6 {
+		t.Fatalf("expected 6 banks synced, got %d", res.BanksSynced)
+	}
+}
+
+// blockingCursorStore holds every sync at GetCursor until released.
+type blockingCursorStore struct {
+	fakeTxStore
+	release  chan struct{}
+	inFlight int
+}
+
+func (f *blockingCursorStore) GetCursor(ctx context.Context, uid, bankID string) (dto.PlaidSyncCursor, error) {
+	f.mu.Lock()
+	f.inFlight++
+	f.mu.Unlock()
+	<-f.release
+	return dto.PlaidSyncCursor{}, nil
 }
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/logger"
+)
+
+const (
+	defaultSyncConcurrency = 4
+	syncBackoffBase        = 15 * time.Minute
+	syncBackoffMax         = 24 * time.Hour
+)
+
+// bankSchedStore lists banks across users and records the outcome of each sync.
+type bankSchedStore interface {
+	ListAll(ctx context.Context) ([]dto.UserBank, error)
+	UpdateSyncState(ctx context.Context, uid, bankID string, state dto.BankSyncState) error
+}
+
+type schedulerService struct {
+	banks       bankSchedStore
+	syncer      transactionSyncer
+	concurrency int
+	clockNow    func() time.Time
+}
+
+func NewSchedulerService(banks bankSchedStore, syncer transactionSyncer, concurrency int) *schedulerService {
+	if concurrency <= 0 {
+		concurrency = defaultSyncConcurrency
+	}
+	return &schedulerService{
+		banks:       banks,
+		syncer:      syncer,
+		concurrency: concurrency,
+		clockNow:    time.Now,
+	}
+}
+
+// Run syncs every due bank immediately and then once per interval until ctx is cancelled.
+func (s *schedulerService) Run(ctx context.Context, interval time.Duration) error {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SyncAll(ctx); err != nil {
+			log.Error("scheduled sync failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncAll performs one pass over every linked bank, syncing those that are due with
+// bounded concurrency. A failing bank is backed off without affecting the others.
+func (s *schedulerService) SyncAll(ctx context.Context) (dto.ScheduledSyncResult, error) {
+	result := dto.ScheduledSyncResult{}
+	log := logger.FromContext(ctx)
+
+	banks, err := s.banks.ListAll(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	now := s.clockNow()
+	due := make([]dto.UserBank, 0, len(banks))
+	for _, ub := range banks {
+		if !s.isDue(ub.Bank, now) {
+			result.BanksSkipped++
+			continue
+		}
+		due = append(due, ub)
+	}
+	result.BanksDue = len(due)
+	log.Info("scheduled sync started", "bank_count", len(banks), "banks_due", result.BanksDue)
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, s.concurrency)
+	)
+	for _, ub := range due {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return result, ctx.Err()
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(ub dto.UserBank) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ok := s.syncBank(ctx, ub)
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				result.BanksSynced++
+			} else {
+				result.BanksFailed++
+			}
+		}(ub)
+	}
+	wg.Wait()
+
+	log.Info("scheduled sync completed", "banks_synced", result.BanksSynced, "banks_failed", result.BanksFailed, "banks_skipped", result.BanksSkipped)
+	return result, nil
+}
+
+// isDue reports whether a bank should be synced now. Banks waiting on the user to
+// re-authenticate or reconnect are left alone until a webhook or update mode repairs them.
+func (s *schedulerService) isDue(bank *models.Bank, now time.Time) bool {
+	switch bank.Status {
+	case models.BankStatusLoginRequired, models.BankStatusRevoked:
+		return false
+	}
+	return bank.NextSyncAt == nil || !now.Before(*bank.NextSyncAt)
+}
+
+// syncBank syncs a single bank and records the outcome, returning whether the sync succeeded.
+func (s *schedulerService) syncBank(ctx context.Context, ub dto.UserBank) bool {
+	log, ctx := logger.With(ctx, "uid", ub.UID, "bank_id", ub.Bank.BankID)
+
+	bankID := ub.Bank.BankID
+	_, syncErr := s.syncer.SyncTransactions(ctx, ub.UID, &bankID)
+
+	now := s.clockNow()
+	state := dto.BankSyncState{LastSyncAt: now, NextSyncAt: now}
+	if syncErr != nil {
+		state.LastSyncError = syncErr.Error()
+		state.SyncFailures = ub.Bank.SyncFailures + 1
+		state.NextSyncAt = now.Add(syncBackoff(syncErr, state.SyncFailures))
+		log.Warn("scheduled bank sync failed", "error", syncErr, "sync_failures", state.SyncFailures, "next_sync_at", state.NextSyncAt)
+	}
+
+	if err := s.banks.UpdateSyncState(ctx, ub.UID, bankID, state); err != nil {
+		log.Error("failed to record bank sync state", "error", err)
+	}
+	return syncErr == nil
+}
+
+// syncBackoff doubles the delay for each consecutive transient failure. Permanent
+// failures are unlikely to clear on their own, so they wait the maximum delay.
+func syncBackoff(err error, failures int) time.Duration {
+	var extErr *errs.ExternalServiceError
+	if errors.As(err, &extErr) && !extErr.Transient {
+		return syncBackoffMax
+	}
+
+	delay := syncBackoffBase
+	for i := 1; i < failures && delay < syncBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > syncBackoffMax {
+		delay = syncBackoffMax
+	}
+	return delay
+}
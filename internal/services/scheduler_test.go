@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+// --- fakes ---
+
+type fakeSchedBankStore struct {
+	banks   []dto.UserBank
+	listErr error
+
+	mu     sync.Mutex
+	states map[string]dto.BankSyncState
+}
+
+func (f *fakeSchedBankStore) ListAll(ctx context.Context) ([]dto.UserBank, error) {
+	return f.banks, f.listErr
+}
+
+func (f *fakeSchedBankStore) UpdateSyncState(ctx context.Context, uid, bankID string, state dto.BankSyncState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.states == nil {
+		f.states = map[string]dto.BankSyncState{}
+	}
+	f.states[uid+":"+bankID] = state
+	return nil
+}
+
+type fakeSchedSyncer struct {
+	errs map[string]error // keyed by bank id
+	wait time.Duration
+
+	mu        sync.Mutex
+	calls     []string
+	active    int
+	maxActive int
+}
+
+func (f *fakeSchedSyncer) SyncTransactions(ctx context.Context, uid string, bankID *string) (dto.PlaidServiceSyncResult, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, helpers.Value(bankID))
+	f.active++
+	if f.active > f.maxActive {
+		f.maxActive = f.active
+	}
+	f.mu.Unlock()
+
+	time.Sleep(f.wait)
+
+	f.mu.Lock()
+	f.active--
+	f.mu.Unlock()
+	return dto.PlaidServiceSyncResult{BanksSynced: 1}, f.errs[helpers.Value(bankID)]
+}
+
+func userBank(uid, bankID, status string) dto.UserBank {
+	return dto.UserBank{UID: uid, Bank: &models.Bank{BankID: bankID, Status: status}}
+}
+
+// --- tests ---
+
+func TestSyncAllSkipsBanksNotDue(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	backedOff := userBank("uid-1", "b-backoff", models.BankStatusActive)
+	backedOff.Bank.NextSyncAt = helpers.Ptr(now.Add(time.Hour))
+	banks := &fakeSchedBankStore{banks: []dto.UserBank{
+		userBank("uid-1", "b-ok", models.BankStatusActive),
+		userBank("uid-1", "b-login", models.BankStatusLoginRequired),
+		userBank("uid-2", "b-revoked", models.BankStatusRevoked),
+		backedOff,
+	}}
+	syncer := &fakeSchedSyncer{}
+
+	svc := NewSchedulerService(banks, syncer, 2)
+	svc.clockNow = func() time.Time { return now }
+
+	res, err := svc.SyncAll(helpers.TestCtx())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.BanksDue != 1 || res.BanksSkipped != 3 || res.BanksSynced != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if len(syncer.calls) != 1 || syncer.calls[0] != "b-ok" {
+		t.Fatalf("unexpected syncs: %v", syncer.calls)
+	}
+	state := banks.states["uid-1:b-ok"]
+	if !state.LastSyncAt.Equal(now) || state.LastSyncError != "" || state.SyncFailures != 0 {
+		t.Fatalf("unexpected sync state: %+v", state)
+	}
+}
+
+func TestSyncAllBacksOffTransientFailures(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	failing := userBank("uid-1", "b-fail", models.BankStatusActive)
+	failing.Bank.SyncFailures = 2
+	banks := &fakeSchedBankStore{banks: []dto.UserBank{
+		failing,
+		userBank("uid-2", "b-ok", models.BankStatusActive),
+	}}
+	syncer := &fakeSchedSyncer{errs: map[string]error{
+		"b-fail": errs.NewExternalServiceError("plaid", "sync failed", true, errors.New("boom")),
+	}}
+
+	svc := NewSchedulerService(banks, syncer, 2)
+	svc.clockNow = func() time.Time { return now }
+
+	res, err := svc.SyncAll(helpers.TestCtx())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.BanksSynced != 1 || res.BanksFailed != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	state := banks.states["uid-1:b-fail"]
+	if state.SyncFailures != 3 || state.LastSyncError == "" {
+		t.Fatalf("unexpected sync state: %+v", state)
+	}
+	if want := now.Add(4 * syncBackoffBase); !state.NextSyncAt.Equal(want) {
+		t.Fatalf("next sync = %v, want %v", state.NextSyncAt, want)
+	}
+}
+
+func TestSyncAllPermanentFailureUsesMaxBackoff(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	banks := &fakeSchedBankStore{banks: []dto.UserBank{userBank("uid-1", "b-fail", models.BankStatusActive)}}
+	syncer := &fakeSchedSyncer{errs: map[string]error{
+		"b-fail": errs.NewExternalServiceError("plaid", "sync failed", false, errors.New("boom")),
+	}}
+
+	svc := NewSchedulerService(banks, syncer, 1)
+	svc.clockNow = func() time.Time { return now }
+
+	if _, err := svc.SyncAll(helpers.TestCtx()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state := banks.states["uid-1:b-fail"]; !state.NextSyncAt.Equal(now.Add(syncBackoffMax)) {
+		t.Fatalf("unexpected next sync: %v", state.NextSyncAt)
+	}
+}
+
+func TestSyncAllRespectsConcurrencyLimit(t *testing.T) {
+	banks := &fakeSchedBankStore{}
+	for _, id := range []string{"b1", "b2", "b3", "b4", "b5"} {
+		banks.banks = append(banks.banks, userBank("uid-1", id, models.BankStatusActive))
+	}
+	syncer := &fakeSchedSyncer{wait: 10 * time.Millisecond}
+
+	svc := NewSchedulerService(banks, syncer, 2)
+	res, err := svc.SyncAll(helpers.TestCtx())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.BanksSynced != 5 {
+		t.Fatalf("expected 5 banks synced, got %+v", res)
+	}
+	if syncer.maxActive > 2 {
+		t.Fatalf("expected at most 2 concurrent syncs, got %d", syncer.maxActive)
+	}
+}
+
+func TestSyncAllListError(t *testing.T) {
+	banks := &fakeSchedBankStore{listErr: errors.New("db down")}
+	svc := NewSchedulerService(banks, &fakeSchedSyncer{}, 1)
+
+	if _, err := svc.SyncAll(helpers.TestCtx()); err == nil {
+		t.Fatalf("expected error")
+	}
+}
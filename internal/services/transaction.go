@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+const (
+	defaultTransactionPageSize = 50
+	maxTransactionPageSize     = 200
+)
+
+type transactionTSStore interface {
+	Query(ctx context.Context, uid string, q dto.TransactionQuery, handle func(*models.Transaction) error) error
+}
+
+// pageToken is the decoded form of the opaque nextPageToken. It carries the ordering
+// it was issued for so a token can't be replayed against a different sort.
+type pageToken struct {
+	OrderBy       string `json:"o"`
+	Desc          bool   `json:"d,omitempty"`
+	OrderValue    any    `json:"v"`
+	TransactionID string `json:"id"`
+}
+
+type transactionService struct {
+	txs transactionTSStore
+}
+
+func NewTransactionService(txs transactionTSStore) *transactionService {
+	return &transactionService{txs: txs}
+}
+
+// ListTransactions returns one page of transactions matching q. q.Limit is the page
+// size; token is the nextPageToken from the previous page, or empty for the first.
+func (s *transactionService) ListTransactions(ctx context.Context, uid string, q dto.TransactionQuery, token string) (dto.TransactionPage, error) {
+	page := dto.TransactionPage{Transactions: []*models.Transaction{}}
+
+	if q.OrderBy == "" {
+		q.OrderBy = "date"
+	}
+	if err := validateTransactionQuery(q); err != nil {
+		return page, err
+	}
+
+	pageSize := q.Limit
+	if pageSize <= 0 {
+		pageSize = defaultTransactionPageSize
+	}
+	if pageSize > maxTransactionPageSize {
+		pageSize = maxTransactionPageSize
+	}
+
+	if token != "" {
+		cursor, err := decodePageToken(token, q.OrderBy, q.Desc)
+		if err != nil {
+			return page, err
+		}
+		q.StartAfter = cursor
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	q.Limit = pageSize + 1
+	if err := s.txs.Query(ctx, uid, q, func(tx *models.Transaction) error {
+		page.Transactions = append(page.Transactions, tx)
+		return nil
+	}); err != nil {
+		return page, err
+	}
+
+	if len(page.Transactions) > pageSize {
+		page.Transactions = page.Transactions[:pageSize]
+		last := page.Transactions[pageSize-1]
+		next, err := encodePageToken(pageToken{
+			OrderBy:       q.OrderBy,
+			Desc:          q.Desc,
+			OrderValue:    transactionOrderValue(last, q.OrderBy),
+			TransactionID: last.TransactionID,
+		})
+		if err != nil {
+			return page, err
+		}
+		page.NextPageToken = next
+	}
+
+	return page, nil
+}
+
+func validateTransactionQuery(q dto.TransactionQuery) error {
+	switch q.OrderBy {
+	case "date", "amount", "name":
+	default:
+		return errs.NewValidationError(fmt.Sprintf("invalid orderBy: %s", q.OrderBy))
+	}
+	if err := validatePrimary(q.PFCPrimary); err != nil {
+		return err
+	}
+	for name, value := range map[string]*string{"dateFrom": q.DateFrom, "dateTo": q.DateTo} {
+		if v := helpers.Value(value); v != "" {
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				return errs.NewValidationError(fmt.Sprintf("invalid %s: expected YYYY-MM-DD", name))
+			}
+		}
+	}
+	return nil
+}
+
+// transactionOrderValue returns the value of the ordered field, matching the
+// firestore field named by orderBy.
+func transactionOrderValue(tx *models.Transaction, orderBy string) any {
+	switch orderBy {
+	case "amount":
+		return tx.AmountMinor
+	case "name":
+		return tx.Name
+	default:
+		return tx.Date
+	}
+}
+
+func encodePageToken(t pageToken) (string, error) {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("encode page token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodePageToken(token, orderBy string, desc bool) (*dto.TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errs.NewValidationError("invalid pageToken")
+	}
+	var t pageToken
+	if err := json.Unmarshal(raw, &t); err != nil || t.TransactionID == "" || t.OrderValue == nil {
+		return nil, errs.NewValidationError("invalid pageToken")
+	}
+	if t.OrderBy != orderBy || t.Desc != desc {
+		return nil, errs.NewValidationError("pageToken does not match the requested ordering")
+	}
+	// JSON decodes every number as float64; amounts are stored as integer minor units.
+	if v, ok := t.OrderValue.(float64); ok && orderBy == "amount" {
+		t.OrderValue = int64(v)
+	}
+	return &dto.TransactionCursor{OrderValue: t.OrderValue, TransactionID: t.TransactionID}, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+// fakeTxQueryStore serves transactions in slice order, honouring StartAfter and Limit.
+type fakeTxQueryStore struct {
+	txs     []*models.Transaction
+	err     error
+	queries []dto.TransactionQuery
+}
+
+func (f *fakeTxQueryStore) Query(ctx context.Context, uid string, q dto.TransactionQuery, handle func(*models.Transaction) error) error {
+	f.queries = append(f.queries, q)
+	if f.err != nil {
+		return f.err
+	}
+	started := q.StartAfter == nil
+	sent := 0
+	for _, tx := range f.txs {
+		if !started {
+			started = tx.TransactionID == q.StartAfter.TransactionID
+			continue
+		}
+		if q.Limit > 0 && sent >= q.Limit {
+			break
+		}
+		if err := handle(tx); err != nil {
+			return err
+		}
+		sent++
+	}
+	return nil
+}
+
+func testTransactions(n int) []*models.Transaction {
+	txs := make([]*models.Transaction, 0, n)
+	for i := 0; i < n; i++ {
+		txs = append(txs, &models.Transaction{TransactionID: string(rune('a' + i)), Date: "2025-01-0" + string(rune('1'+i))})
+	}
+	return txs
+}
+
+func TestListTransactionsPaginates(t *testing.T) {
+	store := &fakeTxQueryStore{txs: testTransactions(5)}
+	svc := NewTransactionService(store)
+	ctx := helpers.TestCtx()
+
+	first, err := svc.ListTransactions(ctx, "uid-1", dto.TransactionQuery{Limit: 2}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first.Transactions) != 2 || first.Transactions[1].TransactionID != "b" || first.NextPageToken == "" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+	if store.queries[0].Limit != 3 || store.queries[0].OrderBy != "date" {
+		t.Fatalf("unexpected query: %+v", store.queries[0])
+	}
+
+	second, err := svc.ListTransactions(ctx, "uid-1", dto.TransactionQuery{Limit: 2}, first.NextPageToken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cursor := store.queries[1].StartAfter
+	if cursor == nil || cursor.TransactionID != "b" || cursor.OrderValue != "2025-01-02" {
+		t.Fatalf("unexpected cursor: %+v", cursor)
+	}
+	if len(second.Transactions) != 2 || second.Transactions[0].TransactionID != "c" {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+
+	last, err := svc.ListTransactions(ctx, "uid-1", dto.TransactionQuery{Limit: 2}, second.NextPageToken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(last.Transactions) != 1 || last.NextPageToken != "" {
+		t.Fatalf("unexpected last page: %+v", last)
+	}
+}
+
+func TestListTransactionsClampsPageSize(t *testing.T) {
+	store := &fakeTxQueryStore{}
+	svc := NewTransactionService(store)
+	ctx := helpers.TestCtx()
+
+	if _, err := svc.ListTransactions(ctx, "uid-1", dto.TransactionQuery{}, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.ListTransactions(ctx, "uid-1", dto.TransactionQuery{Limit: 10000}, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.queries[0].Limit != defaultTransactionPageSize+1 || store.queries[1].Limit != maxTransactionPageSize+1 {
+		t.Fatalf("unexpected limits: %d, %d", store.queries[0].Limit, store.queries[1].Limit)
+	}
+}
+
+func TestListTransactionsRejectsMismatchedToken(t *testing.T) {
+	store := &fakeTxQueryStore{txs: testTransactions(3)}
+	svc := NewTransactionService(store)
+	ctx := helpers.TestCtx()
+
+	first, err := svc.ListTransactions(ctx, "uid-1", dto.TransactionQuery{Limit: 1}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = svc.ListTransactions(ctx, "uid-1", dto.TransactionQuery{Limit: 1, Desc: true}, first.NextPageToken)
+	var validation *errs.ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+}
+
+func TestListTransactionsValidation(t *testing.T) {
+	svc := NewTransactionService(&fakeTxQueryStore{})
+	ctx := helpers.TestCtx()
+
+	cases := []struct {
+		name  string
+		q     dto.TransactionQuery
+		token string
+	}{
+		{name: "orderBy", q: dto.TransactionQuery{OrderBy: "pfcPrimary"}},
+		{name: "pfcPrimary", q: dto.TransactionQuery{PFCPrimary: helpers.Ptr("NOT_A_CATEGORY")}},
+		{name: "dateFrom", q: dto.TransactionQuery{DateFrom: helpers.Ptr("01/02/2025")}},
+		{name: "token", token: "not-a-token"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := svc.ListTransactions(ctx, "uid-1", tc.q, tc.token)
+			var validation *errs.ValidationError
+			if !errors.As(err, &validation) {
+				t.Fatalf("expected ValidationError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestListTransactionsStoreError(t *testing.T) {
+	svc := NewTransactionService(&fakeTxQueryStore{err: errors.New("db down")})
+	if _, err := svc.ListTransactions(helpers.TestCtx(), "uid-1", dto.TransactionQuery{}, ""); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -2,25 +2,45 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/models"
 	"github.com/GregMSThompson/finance-backend/pkg/logger"
 )
 
+const maxUserPreferences = 50
+
 type userUSStore interface {
 	CreateUser(ctx context.Context, user *models.User) error
 	UpdateUser(ctx context.Context, user *models.User) error
 	GetUser(ctx context.Context, uid string) (*models.User, error)
+	DeleteUser(ctx context.Context, uid string) error
+}
+
+type bankUSStore interface {
+	List(ctx context.Context, uid string) ([]*models.Bank, error)
+}
+
+type plaidItemClient interface {
+	RemoveItem(ctx context.Context, accessToken string) error
 }
 
 type userService struct {
 	Store userUSStore
+	banks bankUSStore
+	plaid plaidItemClient
 }
 
-func NewUserService(store userUSStore) *userService {
+func NewUserService(store userUSStore, banks bankUSStore, plaid plaidItemClient) *userService {
 	return &userService{
 		Store: store,
+		banks: banks,
+		plaid: plaid,
 	}
 }
 
@@ -49,3 +69,96 @@ func (s *userService) CreateUser(ctx context.Context, uid, email, first, last st
 
 	return nil
 }
+
+func (s *userService) GetUser(ctx context.Context, uid string) (*models.User, error) {
+	return s.Store.GetUser(ctx, uid)
+}
+
+// UpdateUser applies a partial profile update and returns the stored result.
+func (s *userService) UpdateUser(ctx context.Context, uid string, in dto.UserUpdate) (*models.User, error) {
+	user, err := s.Store.GetUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.FirstName != nil {
+		user.FirstName = strings.TrimSpace(*in.FirstName)
+	}
+	if in.LastName != nil {
+		user.LastName = strings.TrimSpace(*in.LastName)
+	}
+	if in.Timezone != nil {
+		tz := strings.TrimSpace(*in.Timezone)
+		if tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				return nil, errs.NewValidationError(fmt.Sprintf("invalid timezone: %q", tz))
+			}
+		}
+		user.Timezone = tz
+	}
+	if in.BaseCurrency != nil {
+		currency := strings.ToUpper(strings.TrimSpace(*in.BaseCurrency))
+		if currency != "" && len(currency) != 3 {
+			return nil, errs.NewValidationError("baseCurrency must be a 3-letter ISO code")
+		}
+		user.BaseCurrency = currency
+	}
+	if in.Preferences != nil {
+		if len(in.Preferences) > maxUserPreferences {
+			return nil, errs.NewValidationError(fmt.Sprintf("at most %d preferences are allowed", maxUserPreferences))
+		}
+		for key := range in.Preferences {
+			if strings.TrimSpace(key) == "" {
+				return nil, errs.NewValidationError("preference keys must not be empty")
+			}
+		}
+		user.Preferences = in.Preferences
+	}
+
+	if err := s.Store.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteUser removes the user's Plaid items and then all of their stored data.
+// Plaid items are removed first: once the banks are deleted their access tokens
+// are gone, so a failure there stops the deletion and it can be retried.
+func (s *userService) DeleteUser(ctx context.Context, uid string) error {
+	log := logger.FromContext(ctx)
+
+	banks, err := s.banks.List(ctx, uid)
+	if err != nil {
+		return err
+	}
+	for _, bank := range banks {
+		if bank.PlaidPublicToken == "" {
+			continue
+		}
+		if err := s.plaid.RemoveItem(ctx, bank.PlaidPublicToken); err != nil {
+			if !isPlaidItemGone(err) {
+				log.Error("failed to remove plaid item", "bank_id", bank.BankID, "error", err)
+				return err
+			}
+			log.Warn("plaid item already removed", "bank_id", bank.BankID)
+		}
+	}
+
+	if err := s.Store.DeleteUser(ctx, uid); err != nil {
+		log.Error("failed to delete user data", "error", err)
+		return err
+	}
+
+	log.Info("user deleted", "banks_removed", len(banks))
+	return nil
+}
+
+// isPlaidItemGone reports whether Plaid no longer knows the item, so there is
+// nothing left to remove.
+func isPlaidItemGone(err error) bool {
+	var extErr *errs.ExternalServiceError
+	if !errors.As(err, &extErr) {
+		return false
+	}
+	return extErr.Code == "ITEM_NOT_FOUND" || extErr.Code == "INVALID_ACCESS_TOKEN"
+}
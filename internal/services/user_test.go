@@ -7,6 +7,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/models"
 	"github.com/GregMSThompson/finance-backend/pkg/helpers"
 	"github.com/GregMSThompson/finance-backend/pkg/logger"
@@ -16,6 +18,10 @@ type stubUserStore struct {
 	user            *models.User
 	createUserCalls int
 	err             error
+
+	existing *models.User
+	updated  *models.User
+	deleted  bool
 }
 
 func (s *stubUserStore) CreateUser(_ context.Context, user *models.User) error {
@@ -24,9 +30,29 @@ func (s *stubUserStore) CreateUser(_ context.Context, user *models.User) error {
 	return s.err
 }
 
-func (s *stubUserStore) UpdateUser(_ context.Context, _ *models.User) error { return nil }
+func (s *stubUserStore) UpdateUser(_ context.Context, user *models.User) error {
+	s.updated = user
+	return nil
+}
 func (s *stubUserStore) GetUser(_ context.Context, _ string) (*models.User, error) {
-	return nil, nil
+	if s.existing == nil {
+		return nil, errs.NewNotFoundError("user not found")
+	}
+	return s.existing, nil
+}
+func (s *stubUserStore) DeleteUser(_ context.Context, _ string) error {
+	s.deleted = true
+	return s.err
+}
+
+type fakePlaidItemClient struct {
+	removed []string
+	errs    map[string]error
+}
+
+func (f *fakePlaidItemClient) RemoveItem(_ context.Context, accessToken string) error {
+	f.removed = append(f.removed, accessToken)
+	return f.errs[accessToken]
 }
 
 func newTestLogger() *slog.Logger {
@@ -35,7 +61,7 @@ func newTestLogger() *slog.Logger {
 
 func TestUserServiceCreateUser(t *testing.T) {
 	store := &stubUserStore{}
-	svc := NewUserService(store)
+	svc := NewUserService(store, &fakeBankStore{}, &fakePlaidItemClient{})
 
 	ctx := helpers.TestCtx()
 	now := time.Now()
@@ -72,7 +98,7 @@ func TestUserServiceCreateUser(t *testing.T) {
 
 func TestUserServiceCreateUserStoreError(t *testing.T) {
 	store := &stubUserStore{err: errors.New("store failure")}
-	svc := NewUserService(store)
+	svc := NewUserService(store, &fakeBankStore{}, &fakePlaidItemClient{})
 
 	ctx := helpers.TestCtx()
 	err := svc.CreateUser(ctx, "uid-456", "user2@example.com", "John", "Smith")
@@ -89,3 +115,92 @@ func TestUserServiceCreateUserStoreError(t *testing.T) {
 		t.Fatalf("store did not receive expected user payload: %+v", store.user)
 	}
 }
+
+func TestUserServiceUpdateUserAppliesPartialUpdate(t *testing.T) {
+	store := &stubUserStore{existing: &models.User{
+		UID:         "uid-1",
+		FirstName:   "Jane",
+		LastName:    "Doe",
+		Timezone:    "UTC",
+		Preferences: map[string]string{"theme": "dark"},
+	}}
+	svc := NewUserService(store, &fakeBankStore{}, &fakePlaidItemClient{})
+
+	user, err := svc.UpdateUser(helpers.TestCtx(), "uid-1", dto.UserUpdate{
+		LastName:     helpers.Ptr(" Smith "),
+		Timezone:     helpers.Ptr("Europe/London"),
+		BaseCurrency: helpers.Ptr("gbp"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.updated != user {
+		t.Fatalf("expected updated user to be stored")
+	}
+	if user.FirstName != "Jane" || user.LastName != "Smith" || user.Timezone != "Europe/London" || user.BaseCurrency != "GBP" {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+	if user.Preferences["theme"] != "dark" {
+		t.Fatalf("preferences should be kept when omitted, got %v", user.Preferences)
+	}
+}
+
+func TestUserServiceUpdateUserValidates(t *testing.T) {
+	cases := map[string]dto.UserUpdate{
+		"timezone":   {Timezone: helpers.Ptr("Mars/Olympus")},
+		"currency":   {BaseCurrency: helpers.Ptr("POUND")},
+		"preference": {Preferences: map[string]string{" ": "x"}},
+	}
+	for name, in := range cases {
+		store := &stubUserStore{existing: &models.User{UID: "uid-1"}}
+		svc := NewUserService(store, &fakeBankStore{}, &fakePlaidItemClient{})
+
+		_, err := svc.UpdateUser(helpers.TestCtx(), "uid-1", in)
+		var vErr *errs.ValidationError
+		if !errors.As(err, &vErr) {
+			t.Fatalf("%s: expected validation error, got %v", name, err)
+		}
+		if store.updated != nil {
+			t.Fatalf("%s: invalid update should not be stored", name)
+		}
+	}
+}
+
+func TestUserServiceDeleteUserRemovesPlaidItems(t *testing.T) {
+	store := &stubUserStore{}
+	banks := &fakeBankStore{list: []*models.Bank{
+		{BankID: "item-1", PlaidPublicToken: "at-1"},
+		{BankID: "item-2", PlaidPublicToken: "at-2"},
+		{BankID: "item-3"},
+	}}
+	gone := errs.NewExternalServiceError("plaid", "failed to remove item", false, nil)
+	gone.Code = "ITEM_NOT_FOUND"
+	plaid := &fakePlaidItemClient{errs: map[string]error{"at-2": gone}}
+	svc := NewUserService(store, banks, plaid)
+
+	if err := svc.DeleteUser(helpers.TestCtx(), "uid-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plaid.removed) != 2 || plaid.removed[0] != "at-1" || plaid.removed[1] != "at-2" {
+		t.Fatalf("unexpected removed items: %v", plaid.removed)
+	}
+	if !store.deleted {
+		t.Fatalf("expected user data to be deleted")
+	}
+}
+
+func TestUserServiceDeleteUserStopsOnPlaidError(t *testing.T) {
+	store := &stubUserStore{}
+	banks := &fakeBankStore{list: []*models.Bank{{BankID: "item-1", PlaidPublicToken: "at-1"}}}
+	plaidErr := errs.NewExternalServiceError("plaid", "failed to remove item", true, nil)
+	plaid := &fakePlaidItemClient{errs: map[string]error{"at-1": plaidErr}}
+	svc := NewUserService(store, banks, plaid)
+
+	err := svc.DeleteUser(helpers.TestCtx(), "uid-1")
+	if !errors.Is(err, plaidErr) {
+		t.Fatalf("expected plaid error, got %v", err)
+	}
+	if store.deleted {
+		t.Fatalf("user data should be kept so the deletion can be retried")
+	}
+}
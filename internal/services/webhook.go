@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/logger"
+)
+
+const (
+	// Plaid rejects webhooks whose token was issued more than five minutes ago.
+	maxWebhookAge = 5 * time.Minute
+	// Cached verification keys are fetched again after this long, so an expiry
+	// Plaid sets on a rotated key is noticed.
+	webhookKeyRefresh = time.Hour
+	// Upper bound on a webhook-triggered sync once the webhook has been answered.
+	webhookSyncTimeout = 5 * time.Minute
+)
+
+// webhookKeyClient fetches the public key Plaid used to sign a webhook.
+type webhookKeyClient interface {
+	GetWebhookVerificationKey(ctx context.Context, keyID string) (dto.PlaidWebhookKey, error)
+}
+
+// bankWHStore resolves item owners and records item health.
+type bankWHStore interface {
+	FindOwner(ctx context.Context, bankID string) (dto.UserBank, error)
+	UpdateStatus(ctx context.Context, uid, bankID, status, errorCode string) error
+}
+
+// transactionSyncer triggers a sync for a single bank.
+type transactionSyncer interface {
+	SyncTransactions(ctx context.Context, uid string, bankID *string) (dto.PlaidServiceSyncResult, error)
+}
+
+type plaidWebhookClaims struct {
+	RequestBodySHA256 string `json:"request_body_sha256"`
+	jwt.RegisteredClaims
+}
+
+type cachedWebhookKey struct {
+	dto.PlaidWebhookKey
+	fetchedAt time.Time
+}
+
+type webhookService struct {
+	keys     webhookKeyClient
+	banks    bankWHStore
+	syncer   transactionSyncer
+	notify   notifier
+	clockNow func() time.Time
+
+	mu        sync.Mutex
+	keysByKID map[string]cachedWebhookKey
+	// Banks with a webhook sync running. The value records that another webhook
+	// arrived during the run and the bank must be synced again.
+	syncing map[string]bool
+	wg      sync.WaitGroup // background syncs, waited on by tests
+}
+
+// NewWebhookService builds the webhook service. notify may be nil to skip push notifications.
+func NewWebhookService(keys webhookKeyClient, banks bankWHStore, syncer transactionSyncer, notify notifier) *webhookService {
+	return &webhookService{
+		keys:      keys,
+		banks:     banks,
+		syncer:    syncer,
+		notify:    notify,
+		clockNow:  time.Now,
+		keysByKID: map[string]cachedWebhookKey{},
+		syncing:   map[string]bool{},
+	}
+}
+
+// HandlePlaidWebhook verifies the Plaid-Verification JWT against the raw body and
+// dispatches the webhook. Webhooks for unknown items are acknowledged and ignored.
+func (s *webhookService) HandlePlaidWebhook(ctx context.Context, verification string, body []byte) error {
+	if err := s.verify(ctx, verification, body); err != nil {
+		return err
+	}
+
+	var hook dto.PlaidWebhook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		return errs.NewValidationError("invalid webhook payload")
+	}
+	if hook.ItemID == "" {
+		return errs.NewValidationError("item_id is required")
+	}
+
+	log, ctx := logger.With(ctx, "bank_id", hook.ItemID, "webhook_type", hook.WebhookType, "webhook_code", hook.WebhookCode)
+
+	owner, err := s.banks.FindOwner(ctx, hook.ItemID)
+	if err != nil {
+		var notFound *errs.NotFoundError
+		if errors.As(err, &notFound) {
+			log.Warn("webhook for unknown item ignored")
+			return nil
+		}
+		return err
+	}
+	log, ctx = logger.With(ctx, "uid", owner.UID)
+
+	switch hook.WebhookType {
+	case "TRANSACTIONS":
+		return s.handleTransactionsWebhook(ctx, owner.UID, hook)
+	case "ITEM":
+		return s.handleItemWebhook(ctx, owner, hook)
+	default:
+		log.Debug("webhook type not handled")
+		return nil
+	}
+}
+
+func (s *webhookService) handleTransactionsWebhook(ctx context.Context, uid string, hook dto.PlaidWebhook) error {
+	log := logger.FromContext(ctx)
+
+	if hook.WebhookCode != "SYNC_UPDATES_AVAILABLE" {
+		log.Debug("transactions webhook code not handled")
+		return nil
+	}
+
+	s.syncInBackground(ctx, uid, hook.ItemID)
+	return nil
+}
+
+// syncInBackground syncs the bank after the webhook has been answered, so a long
+// sync cannot run past Plaid's delivery timeout and trigger redelivery. A webhook
+// for a bank that is already syncing queues one more run instead of a concurrent
+// sync. Failures are only logged; the scheduled sync picks the bank up again. The
+// API service keeps CPU allocated between requests (see infra/cloudrun) so the
+// goroutine is not throttled once the response has gone out.
+func (s *webhookService) syncInBackground(ctx context.Context, uid, bankID string) {
+	log := logger.FromContext(ctx)
+
+	s.mu.Lock()
+	if _, running := s.syncing[bankID]; running {
+		s.syncing[bankID] = true
+		s.mu.Unlock()
+		log.Info("webhook sync already running, queued another run")
+		return
+	}
+	s.syncing[bankID] = false
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), webhookSyncTimeout)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		for {
+			if _, err := s.syncer.SyncTransactions(ctx, uid, &bankID); err != nil {
+				log.Error("webhook sync failed", "error", err)
+			} else {
+				log.Info("webhook sync completed")
+			}
+
+			s.mu.Lock()
+			again := s.syncing[bankID] && ctx.Err() == nil
+			if !again {
+				delete(s.syncing, bankID)
+			} else {
+				s.syncing[bankID] = false
+			}
+			s.mu.Unlock()
+			if !again {
+				return
+			}
+		}
+	}()
+}
+
+// handleItemWebhook records the item's new status. The user is notified only when
+// the item newly needs attention, since Plaid repeats ERROR webhooks for an item
+// that stays broken.
+func (s *webhookService) handleItemWebhook(ctx context.Context, owner dto.UserBank, hook dto.PlaidWebhook) error {
+	log := logger.FromContext(ctx)
+
+	var status, errorCode string
+	switch hook.WebhookCode {
+	case "ERROR":
+		status = models.BankStatusError
+		if hook.Error != nil {
+			errorCode = hook.Error.ErrorCode
+		}
+		if errorCode == "ITEM_LOGIN_REQUIRED" {
+			status = models.BankStatusLoginRequired
+		}
+	case "PENDING_EXPIRATION", "PENDING_DISCONNECT":
+		status = models.BankStatusPendingExpiration
+	case "USER_PERMISSION_REVOKED", "USER_ACCOUNT_REVOKED":
+		status = models.BankStatusRevoked
+	case "LOGIN_REPAIRED":
+		status = models.BankStatusActive
+	default:
+		log.Debug("item webhook code not handled")
+		return nil
+	}
+
+	if err := s.banks.UpdateStatus(ctx, owner.UID, hook.ItemID, status, errorCode); err != nil {
+		return err
+	}
+	log.Info("bank status updated from webhook", "status", status, "error_code", errorCode)
+	changed := owner.Bank.Status != status
+	if changed && (status == models.BankStatusLoginRequired || status == models.BankStatusPendingExpiration) {
+		sendNotification(ctx, s.notify, owner.UID, loginRequiredNotification(hook.ItemID, owner.Bank.Institution))
+	}
+	return nil
+}
+
+// verify checks the ES256 signature, token age and body hash as described in
+// Plaid's webhook verification guide.
+func (s *webhookService) verify(ctx context.Context, verification string, body []byte) error {
+	if verification == "" {
+		return errs.NewUnauthorizedError("missing Plaid-Verification header", nil)
+	}
+
+	claims := &plaidWebhookClaims{}
+	_, err := jwt.ParseWithClaims(verification, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("missing kid header")
+		}
+		return s.verificationKey(ctx, kid)
+	}, jwt.WithValidMethods([]string{"ES256"}))
+	if err != nil {
+		return errs.NewUnauthorizedError("invalid webhook signature", err)
+	}
+
+	if claims.IssuedAt == nil || s.clockNow().Sub(claims.IssuedAt.Time) > maxWebhookAge {
+		return errs.NewUnauthorizedError("webhook token expired", nil)
+	}
+
+	sum := sha256.Sum256(body)
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(claims.RequestBodySHA256)) != 1 {
+		return errs.NewUnauthorizedError("webhook body hash mismatch", nil)
+	}
+	return nil
+}
+
+// verificationKey returns the key for kid, fetching it from Plaid on a miss or once
+// the cached copy is older than webhookKeyRefresh. Expired keys are rejected.
+func (s *webhookService) verificationKey(ctx context.Context, kid string) (*ecdsa.PublicKey, error) {
+	now := s.clockNow()
+
+	s.mu.Lock()
+	key, ok := s.keysByKID[kid]
+	s.mu.Unlock()
+
+	if !ok || now.Sub(key.fetchedAt) > webhookKeyRefresh {
+		fetched, err := s.keys.GetWebhookVerificationKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		key = cachedWebhookKey{PlaidWebhookKey: fetched, fetchedAt: now}
+
+		s.mu.Lock()
+		s.keysByKID[kid] = key
+		s.mu.Unlock()
+	}
+
+	if !key.ExpiredAt.IsZero() && !now.Before(key.ExpiredAt) {
+		return nil, errors.New("webhook verification key expired")
+	}
+	return key.Key, nil
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/GregMSThompson/finance-backend/internal/dto"
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+	"github.com/GregMSThompson/finance-backend/pkg/helpers"
+)
+
+// --- fakes ---
+
+type fakeWebhookKeys struct {
+	keys  map[string]dto.PlaidWebhookKey
+	calls int
+}
+
+func (f *fakeWebhookKeys) GetWebhookVerificationKey(ctx context.Context, keyID string) (dto.PlaidWebhookKey, error) {
+	f.calls++
+	key, ok := f.keys[keyID]
+	if !ok {
+		return dto.PlaidWebhookKey{}, errors.New("unknown key")
+	}
+	return key, nil
+}
+
+type fakeWebhookBankStore struct {
+	owners   map[string]string
+	statuses map[string]string
+	codes    map[string]string
+}
+
+func (f *fakeWebhookBankStore) FindOwner(ctx context.Context, bankID string) (dto.UserBank, error) {
+	uid, ok := f.owners[bankID]
+	if !ok {
+		return dto.UserBank{}, errs.NewNotFoundError("bank not found")
+	}
+	status := f.statuses[uid+":"+bankID]
+	if status == "" {
+		status = models.BankStatusActive
+	}
+	return dto.UserBank{UID: uid, Bank: &models.Bank{BankID: bankID, Institution: "Chase", Status: status}}, nil
+}
+
+func (f *fakeWebhookBankStore) UpdateStatus(ctx context.Context, uid, bankID, status, errorCode string) error {
+	if f.statuses == nil {
+		f.statuses = map[string]string{}
+		f.codes = map[string]string{}
+	}
+	f.statuses[uid+":"+bankID] = status
+	f.codes[uid+":"+bankID] = errorCode
+	return nil
+}
+
+// fakeSyncer runs on the webhook service's background goroutine. When block is set,
+// each sync waits for a value on it after signalling started.
+type fakeSyncer struct {
+	mu      sync.Mutex
+	calls   int
+	uid     string
+	bankID  string
+	err     error
+	started chan struct{}
+	block   chan struct{}
+}
+
+func (f *fakeSyncer) SyncTransactions(ctx context.Context, uid string, bankID *string) (dto.PlaidServiceSyncResult, error) {
+	f.mu.Lock()
+	f.calls++
+	f.uid = uid
+	f.bankID = helpers.Value(bankID)
+	f.mu.Unlock()
+	if f.block != nil {
+		f.started <- struct{}{}
+		<-f.block
+	}
+	return dto.PlaidServiceSyncResult{BanksSynced: 1}, f.err
+}
+
+// signWebhook produces a Plaid-Verification token the same way Plaid does.
+func signWebhook(t *testing.T, key *ecdsa.PrivateKey, kid string, body []byte, issuedAt time.Time) string {
+	t.Helper()
+	sum := sha256.Sum256(body)
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, plaidWebhookClaims{
+		RequestBodySHA256: hex.EncodeToString(sum[:]),
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(issuedAt),
+		},
+	})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign webhook: %v", err)
+	}
+	return signed
+}
+
+func newTestWebhookService(t *testing.T) (*webhookService, *ecdsa.PrivateKey, *fakeWebhookKeys, *fakeWebhookBankStore, *fakeSyncer) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	keys := &fakeWebhookKeys{keys: map[string]dto.PlaidWebhookKey{"kid-1": {Key: &priv.PublicKey}}}
+	banks := &fakeWebhookBankStore{owners: map[string]string{"item-1": "uid-1"}}
+	syncer := &fakeSyncer{}
+	return NewWebhookService(keys, banks, syncer, nil), priv, keys, banks, syncer
+}
+
+// --- tests ---
+
+func TestHandlePlaidWebhookSyncUpdatesAvailable(t *testing.T) {
+	svc, priv, keys, _, syncer := newTestWebhookService(t)
+	body := []byte(`{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-1"}`)
+
+	ctx := helpers.TestCtx()
+	for i := 0; i < 2; i++ {
+		token := signWebhook(t, priv, "kid-1", body, time.Now())
+		if err := svc.HandlePlaidWebhook(ctx, token, body); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		svc.wg.Wait()
+	}
+
+	if syncer.calls != 2 || syncer.uid != "uid-1" || syncer.bankID != "item-1" {
+		t.Fatalf("unexpected sync calls: %+v", syncer)
+	}
+	if keys.calls != 1 {
+		t.Fatalf("expected verification key to be cached, fetched %d times", keys.calls)
+	}
+}
+
+func TestHandlePlaidWebhookItemErrorUpdatesStatus(t *testing.T) {
+	svc, priv, _, banks, syncer := newTestWebhookService(t)
+	body := []byte(`{"webhook_type":"ITEM","webhook_code":"ERROR","item_id":"item-1","error":{"error_code":"ITEM_LOGIN_REQUIRED"}}`)
+	token := signWebhook(t, priv, "kid-1", body, time.Now())
+
+	ctx := helpers.TestCtx()
+	if err := svc.HandlePlaidWebhook(ctx, token, body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if banks.statuses["uid-1:item-1"] != models.BankStatusLoginRequired {
+		t.Fatalf("unexpected status: %q", banks.statuses["uid-1:item-1"])
+	}
+	if banks.codes["uid-1:item-1"] != "ITEM_LOGIN_REQUIRED" {
+		t.Fatalf("unexpected error code: %q", banks.codes["uid-1:item-1"])
+	}
+	if syncer.calls != 0 {
+		t.Fatalf("item webhooks should not trigger a sync")
+	}
+}
+
+func TestHandlePlaidWebhookRejectsTamperedBody(t *testing.T) {
+	svc, priv, _, _, syncer := newTestWebhookService(t)
+	body := []byte(`{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-1"}`)
+	token := signWebhook(t, priv, "kid-1", body, time.Now())
+
+	ctx := helpers.TestCtx()
+	tampered := []byte(`{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-2"}`)
+	err := svc.HandlePlaidWebhook(ctx, token, tampered)
+
+	var unauthorized *errs.UnauthorizedError
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedError, got %T", err)
+	}
+	if syncer.calls != 0 {
+		t.Fatalf("sync should not run for unverified webhooks")
+	}
+}
+
+func TestHandlePlaidWebhookRejectsForeignSignature(t *testing.T) {
+	svc, _, _, _, syncer := newTestWebhookService(t)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	body := []byte(`{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-1"}`)
+	token := signWebhook(t, other, "kid-1", body, time.Now())
+
+	ctx := helpers.TestCtx()
+	err = svc.HandlePlaidWebhook(ctx, token, body)
+
+	var unauthorized *errs.UnauthorizedError
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedError, got %T", err)
+	}
+	if syncer.calls != 0 {
+		t.Fatalf("sync should not run for unverified webhooks")
+	}
+}
+
+func TestHandlePlaidWebhookRejectsStaleToken(t *testing.T) {
+	svc, priv, _, _, _ := newTestWebhookService(t)
+	body := []byte(`{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-1"}`)
+	token := signWebhook(t, priv, "kid-1", body, time.Now().Add(-10*time.Minute))
+
+	ctx := helpers.TestCtx()
+	err := svc.HandlePlaidWebhook(ctx, token, body)
+
+	var unauthorized *errs.UnauthorizedError
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedError, got %T", err)
+	}
+}
+
+func TestHandlePlaidWebhookMissingHeader(t *testing.T) {
+	svc, _, _, _, _ := newTestWebhookService(t)
+
+	ctx := helpers.TestCtx()
+	err := svc.HandlePlaidWebhook(ctx, "", []byte(`{}`))
+
+	var unauthorized *errs.UnauthorizedError
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedError, got %T", err)
+	}
+}
+
+func TestHandlePlaidWebhookUnknownItemIgnored(t *testing.T) {
+	svc, priv, _, _, syncer := newTestWebhookService(t)
+	body := []byte(`{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-unknown"}`)
+	token := signWebhook(t, priv, "kid-1", body, time.Now())
+
+	ctx := helpers.TestCtx()
+	if err := svc.HandlePlaidWebhook(ctx, token, body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svc.wg.Wait()
+	if syncer.calls != 0 {
+		t.Fatalf("sync should not run for unknown items")
+	}
+}
+
+func TestHandlePlaidWebhookSyncErrorIsNotReturned(t *testing.T) {
+	svc, priv, _, _, syncer := newTestWebhookService(t)
+	syncer.err = errors.New("sync failed")
+	body := []byte(`{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-1"}`)
+	token := signWebhook(t, priv, "kid-1", body, time.Now())
+
+	ctx := helpers.TestCtx()
+	if err := svc.HandlePlaidWebhook(ctx, token, body); err != nil {
+		t.Fatalf("webhook should be acknowledged before the sync runs, got %v", err)
+	}
+	svc.wg.Wait()
+	if syncer.calls != 1 {
+		t.Fatalf("expected one sync, got %d", syncer.calls)
+	}
+}
+
+func TestHandlePlaidWebhookSyncOutlivesRequest(t *testing.T) {
+	svc, priv, _, _, syncer := newTestWebhookService(t)
+	syncer.started = make(chan struct{}, 1)
+	syncer.block = make(chan struct{})
+	body := []byte(`{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-1"}`)
+
+	ctx, cancel := context.WithCancel(helpers.TestCtx())
+	token := signWebhook(t, priv, "kid-1", body, time.Now())
+	if err := svc.HandlePlaidWebhook(ctx, token, body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-syncer.started
+
+	// A second webhook mid-sync queues a rerun rather than a concurrent sync.
+	token = signWebhook(t, priv, "kid-1", body, time.Now())
+	if err := svc.HandlePlaidWebhook(ctx, token, body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cancel()
+
+	syncer.block <- struct{}{}
+	<-syncer.started
+	syncer.block <- struct{}{}
+	svc.wg.Wait()
+
+	if syncer.calls != 2 {
+		t.Fatalf("expected the queued webhook to sync once more, got %d syncs", syncer.calls)
+	}
+}
+
+func TestHandlePlaidWebhookRejectsExpiredCachedKey(t *testing.T) {
+	svc, priv, keys, _, syncer := newTestWebhookService(t)
+	now := time.Now()
+	svc.clockNow = func() time.Time { return now }
+	body := []byte(`{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-1"}`)
+
+	ctx := helpers.TestCtx()
+	if err := svc.HandlePlaidWebhook(ctx, signWebhook(t, priv, "kid-1", body, now), body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svc.wg.Wait()
+
+	// Plaid rotates the key; the cached copy is refreshed and its expiry enforced.
+	keys.keys["kid-1"] = dto.PlaidWebhookKey{Key: &priv.PublicKey, ExpiredAt: now.Add(30 * time.Minute)}
+	now = now.Add(2 * time.Hour)
+	err := svc.HandlePlaidWebhook(ctx, signWebhook(t, priv, "kid-1", body, now), body)
+
+	var unauthorized *errs.UnauthorizedError
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedError, got %v", err)
+	}
+	if keys.calls != 2 {
+		t.Fatalf("expected the cached key to be refetched, fetched %d times", keys.calls)
+	}
+
+	// Within the refresh interval the cached expiry alone rejects the key.
+	now = now.Add(time.Minute)
+	err = svc.HandlePlaidWebhook(ctx, signWebhook(t, priv, "kid-1", body, now), body)
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedError, got %v", err)
+	}
+	if keys.calls != 2 || syncer.calls != 1 {
+		t.Fatalf("unexpected calls: keys %d, syncs %d", keys.calls, syncer.calls)
+	}
+}
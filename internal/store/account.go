@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+)
+
+type accountStore struct {
+	client *firestore.Client
+}
+
+func NewAccountStore(client *firestore.Client) *accountStore {
+	return &accountStore{client: client}
+}
+
+func (s *accountStore) collection(uid string) *firestore.CollectionRef {
+	return s.client.Collection("users").Doc(uid).Collection("accounts")
+}
+
+// UpsertBatch writes the accounts, keeping the CreatedAt of accounts already stored.
+func (s *accountStore) UpsertBatch(ctx context.Context, uid string, accounts []models.Account) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+
+	refs := make([]*firestore.DocumentRef, 0, len(accounts))
+	for _, a := range accounts {
+		refs = append(refs, s.collection(uid).Doc(a.AccountID))
+	}
+	now := time.Now()
+
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snaps, err := tx.GetAll(refs)
+		if err != nil {
+			return err
+		}
+		for i, a := range accounts {
+			a.UpdatedAt = now
+			a.CreatedAt = now
+			if snaps[i].Exists() {
+				var existing models.Account
+				if err := snaps[i].DataTo(&existing); err != nil {
+					return err
+				}
+				if !existing.CreatedAt.IsZero() {
+					a.CreatedAt = existing.CreatedAt
+				}
+			}
+			if err := tx.Set(refs[i], a); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errs.NewDatabaseError("update", "failed to upsert accounts", err)
+	}
+	return nil
+}
+
+func (s *accountStore) List(ctx context.Context, uid string) ([]*models.Account, error) {
+	docs, err := s.collection(uid).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to list accounts", err)
+	}
+	accounts := make([]*models.Account, 0, len(docs))
+	for _, d := range docs {
+		var a models.Account
+		if err := d.DataTo(&a); err != nil {
+			return nil, errs.NewDatabaseError("read", "failed to parse account data", err)
+		}
+		a.NormalizeBalances()
+		accounts = append(accounts, &a)
+	}
+	return accounts, nil
+}
+
+func (s *accountStore) DeleteByBank(ctx context.Context, uid, bankID string) error {
+	iter := s.collection(uid).Where("bankId", "==", bankID).Documents(ctx)
+	bw := s.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0)
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bw.End()
+			return errs.NewDatabaseError("delete", "failed to query accounts for deletion", err)
+		}
+		job, err := bw.Delete(doc.Ref)
+		if err != nil {
+			bw.End()
+			return errs.NewDatabaseError("delete", "failed to delete account", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return errs.NewDatabaseError("delete", "failed to commit account deletion batch", err)
+		}
+	}
+
+	return nil
+}
+
+// MigrateLegacyBalances rewrites float balances as minor units on every account still
+// carrying them, returning how many accounts were updated. Migrated accounts are
+// skipped, so it is safe to re-run.
+func (s *accountStore) MigrateLegacyBalances(ctx context.Context) (int, error) {
+	iter := s.client.CollectionGroup("accounts").
+		Select("currency", "currentBalance", "availableBalance", "creditLimit", "currentBalanceMinor", "availableBalanceMinor", "creditLimitMinor").
+		Documents(ctx)
+	defer iter.Stop()
+	bw := s.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0)
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bw.End()
+			return 0, errs.NewDatabaseError("read", "failed to query accounts for migration", err)
+		}
+
+		var a models.Account
+		if err := doc.DataTo(&a); err != nil {
+			bw.End()
+			return 0, errs.NewDatabaseError("read", "failed to parse account data", err)
+		}
+		if !a.HasLegacyBalances() {
+			continue
+		}
+		a.NormalizeBalances()
+
+		updates := []firestore.Update{
+			{Path: "currentBalance", Value: firestore.Delete},
+			{Path: "availableBalance", Value: firestore.Delete},
+			{Path: "creditLimit", Value: firestore.Delete},
+		}
+		for path, minor := range map[string]*int64{
+			"currentBalanceMinor":   a.CurrentBalanceMinor,
+			"availableBalanceMinor": a.AvailableBalanceMinor,
+			"creditLimitMinor":      a.CreditLimitMinor,
+		} {
+			if minor != nil {
+				updates = append(updates, firestore.Update{Path: path, Value: *minor})
+			}
+		}
+		job, err := bw.Update(doc.Ref, updates)
+		if err != nil {
+			bw.End()
+			return 0, errs.NewDatabaseError("update", "failed to migrate account balances", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return 0, errs.NewDatabaseError("update", "failed to commit account migration batch", err)
+		}
+	}
+
+	return len(jobs), nil
+}
@@ -6,7 +6,10 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/GregMSThompson/finance-backend/internal/dto"
 	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/models"
 )
@@ -19,8 +22,12 @@ func NewAIStore(client *firestore.Client) *aiStore {
 	return &aiStore{client: client}
 }
 
+func (s *aiStore) sessionsCollection(uid string) *firestore.CollectionRef {
+	return s.client.Collection("users").Doc(uid).Collection("ai_sessions")
+}
+
 func (s *aiStore) messagesCollection(uid, sessionID string) *firestore.CollectionRef {
-	return s.client.Collection("users").Doc(uid).Collection("ai_sessions").Doc(sessionID).Collection("messages")
+	return s.sessionsCollection(uid).Doc(sessionID).Collection("messages")
 }
 
 func (s *aiStore) SaveMessage(ctx context.Context, uid, sessionID string, msg models.AIMessage) error {
@@ -57,6 +64,39 @@ func (s *aiStore) ListMessages(ctx context.Context, uid, sessionID string, limit
 		if err := doc.DataTo(&msg); err != nil {
 			return nil, errs.NewDatabaseError("read", "failed to parse AI message data", err)
 		}
+		msg.MessageID = doc.Ref.ID
+		out = append(out, msg)
+	}
+
+	reverseMessages(out)
+	return out, nil
+}
+
+// ListMessagesBefore returns up to limit messages older than before (or the newest
+// when before is nil), oldest first.
+func (s *aiStore) ListMessagesBefore(ctx context.Context, uid, sessionID string, limit int, before *dto.AIMessageCursor) ([]models.AIMessage, error) {
+	// Ordering by document id as well keeps pages stable when timestamps tie.
+	query := s.messagesCollection(uid, sessionID).Query.
+		OrderBy("createdAt", firestore.Desc).
+		OrderBy(firestore.DocumentID, firestore.Desc)
+	if before != nil {
+		query = query.StartAfter(before.CreatedAt, before.MessageID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to list AI messages", err)
+	}
+	out := make([]models.AIMessage, 0, len(docs))
+	for _, doc := range docs {
+		var msg models.AIMessage
+		if err := doc.DataTo(&msg); err != nil {
+			return nil, errs.NewDatabaseError("read", "failed to parse AI message data", err)
+		}
+		msg.MessageID = doc.Ref.ID
 		out = append(out, msg)
 	}
 
@@ -64,8 +104,232 @@ func (s *aiStore) ListMessages(ctx context.Context, uid, sessionID string, limit
 	return out, nil
 }
 
+func (s *aiStore) CreateSession(ctx context.Context, uid string, session *models.AISession) error {
+	now := time.Now()
+	ref := s.sessionsCollection(uid).NewDoc()
+	session.SessionID = ref.ID
+	session.CreatedAt = now
+	session.UpdatedAt = now
+
+	if _, err := ref.Create(ctx, session); err != nil {
+		return errs.NewDatabaseError("create", "failed to create AI session", err)
+	}
+	return nil
+}
+
+// ListSessions returns the most recently active sessions first.
+func (s *aiStore) ListSessions(ctx context.Context, uid string, limit int) ([]*models.AISession, error) {
+	query := s.sessionsCollection(uid).OrderBy("updatedAt", firestore.Desc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to list AI sessions", err)
+	}
+	sessions := make([]*models.AISession, 0, len(docs))
+	for _, d := range docs {
+		var session models.AISession
+		if err := d.DataTo(&session); err != nil {
+			return nil, errs.NewDatabaseError("read", "failed to parse AI session data", err)
+		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, nil
+}
+
+func (s *aiStore) GetSession(ctx context.Context, uid, sessionID string) (*models.AISession, error) {
+	doc, err := s.sessionsCollection(uid).Doc(sessionID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errs.NewNotFoundError("AI session not found")
+		}
+		return nil, errs.NewDatabaseError("read", "failed to get AI session", err)
+	}
+	var session models.AISession
+	if err := doc.DataTo(&session); err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to parse AI session data", err)
+	}
+	return &session, nil
+}
+
+func (s *aiStore) RenameSession(ctx context.Context, uid, sessionID, title string) error {
+	_, err := s.sessionsCollection(uid).Doc(sessionID).Update(ctx, []firestore.Update{
+		{Path: "title", Value: title},
+		{Path: "updatedAt", Value: time.Now()},
+	})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return errs.NewNotFoundError("AI session not found")
+		}
+		return errs.NewDatabaseError("update", "failed to rename AI session", err)
+	}
+	return nil
+}
+
+// TouchSession records the latest turn on a session, creating the session document
+// for conversations started before sessions were stored. An existing title is kept.
+func (s *aiStore) TouchSession(ctx context.Context, uid, sessionID string, activity dto.AISessionActivity) error {
+	ref := s.sessionsCollection(uid).Doc(sessionID)
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		session := models.AISession{SessionID: sessionID, CreatedAt: activity.At}
+		snap, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err := snap.DataTo(&session); err != nil {
+				return err
+			}
+		}
+		if session.Title == "" {
+			session.Title = activity.Title
+		}
+		session.LastMessage = activity.LastMessage
+		session.LastMessageAt = &activity.At
+		session.UpdatedAt = activity.At
+		session.ExpiresAt = activity.ExpiresAt
+		return tx.Set(ref, session)
+	})
+	if err != nil {
+		return errs.NewDatabaseError("update", "failed to update AI session", err)
+	}
+	return nil
+}
+
+// DeleteSession removes a session and its messages. Sessions that only exist as
+// messages (no session document) can be deleted too.
+func (s *aiStore) DeleteSession(ctx context.Context, uid, sessionID string) error {
+	ref := s.sessionsCollection(uid).Doc(sessionID)
+	_, err := ref.Get(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return errs.NewDatabaseError("read", "failed to get AI session", err)
+	}
+	exists := err == nil
+
+	docs, err := s.messagesCollection(uid, sessionID).Select().Documents(ctx).GetAll()
+	if err != nil {
+		return errs.NewDatabaseError("delete", "failed to query AI messages for deletion", err)
+	}
+	if !exists && len(docs) == 0 {
+		return errs.NewNotFoundError("AI session not found")
+	}
+
+	bw := s.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(docs)+1)
+	for _, d := range docs {
+		job, err := bw.Delete(d.Ref)
+		if err != nil {
+			bw.End()
+			return errs.NewDatabaseError("delete", "failed to delete AI message", err)
+		}
+		jobs = append(jobs, job)
+	}
+	job, err := bw.Delete(ref)
+	if err != nil {
+		bw.End()
+		return errs.NewDatabaseError("delete", "failed to delete AI session", err)
+	}
+	jobs = append(jobs, job)
+
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return errs.NewDatabaseError("delete", "failed to commit AI session deletion batch", err)
+		}
+	}
+	return nil
+}
+
 func reverseMessages(msgs []models.AIMessage) {
 	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
 		msgs[i], msgs[j] = msgs[j], msgs[i]
 	}
 }
+
+// DeleteExpiredMessages deletes AI messages across all users whose expiresAt is at or
+// before before. It returns how many were deleted and the sessions they belonged to.
+func (s *aiStore) DeleteExpiredMessages(ctx context.Context, before time.Time) (int, []dto.UserSession, error) {
+	iter := s.client.CollectionGroup("messages").Where("expiresAt", "<=", before).Select().Documents(ctx)
+	defer iter.Stop()
+	bw := s.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0)
+	seen := make(map[dto.UserSession]bool)
+	sessions := make([]dto.UserSession, 0)
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bw.End()
+			return 0, nil, errs.NewDatabaseError("read", "failed to query expired AI messages", err)
+		}
+
+		job, err := bw.Delete(doc.Ref)
+		if err != nil {
+			bw.End()
+			return 0, nil, errs.NewDatabaseError("delete", "failed to delete AI message", err)
+		}
+		jobs = append(jobs, job)
+
+		// users/{uid}/ai_sessions/{sessionId}/messages/{messageId}
+		sessionRef := doc.Ref.Parent.Parent
+		us := dto.UserSession{UID: sessionRef.Parent.Parent.ID, SessionID: sessionRef.ID}
+		if !seen[us] {
+			seen[us] = true
+			sessions = append(sessions, us)
+		}
+	}
+
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return 0, nil, errs.NewDatabaseError("delete", "failed to commit AI message deletion batch", err)
+		}
+	}
+	return len(jobs), sessions, nil
+}
+
+// ListExpiredSessions returns sessions across all users whose expiresAt is at or
+// before before.
+func (s *aiStore) ListExpiredSessions(ctx context.Context, before time.Time) ([]dto.UserSession, error) {
+	docs, err := s.client.CollectionGroup("ai_sessions").Where("expiresAt", "<=", before).Select().Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to query expired AI sessions", err)
+	}
+	sessions := make([]dto.UserSession, 0, len(docs))
+	for _, d := range docs {
+		// users/{uid}/ai_sessions/{sessionId}
+		sessions = append(sessions, dto.UserSession{UID: d.Ref.Parent.Parent.ID, SessionID: d.Ref.ID})
+	}
+	return sessions, nil
+}
+
+// DeleteSessionIfEmpty deletes the session document when it has no messages left,
+// reporting whether it did. The check and delete share a transaction so a message
+// written meanwhile keeps the session.
+func (s *aiStore) DeleteSessionIfEmpty(ctx context.Context, uid, sessionID string) (bool, error) {
+	ref := s.sessionsCollection(uid).Doc(sessionID)
+	deleted := false
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		deleted = false
+		docs, err := tx.Documents(s.messagesCollection(uid, sessionID).Select().Limit(1)).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(docs) > 0 {
+			return nil
+		}
+		if err := tx.Delete(ref); err != nil {
+			return err
+		}
+		deleted = true
+		return nil
+	})
+	if err != nil {
+		return false, errs.NewDatabaseError("delete", "failed to delete empty AI session", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+)
+
+type alertStore struct {
+	client *firestore.Client
+}
+
+func NewAlertStore(client *firestore.Client) *alertStore {
+	return &alertStore{client: client}
+}
+
+func (s *alertStore) rulesCollection(uid string) *firestore.CollectionRef {
+	return s.client.Collection("users").Doc(uid).Collection("alert_rules")
+}
+
+func (s *alertStore) alertsCollection(uid string) *firestore.CollectionRef {
+	return s.client.Collection("users").Doc(uid).Collection("alerts")
+}
+
+func (s *alertStore) CreateRule(ctx context.Context, uid string, rule *models.AlertRule) error {
+	now := time.Now()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	ref := s.rulesCollection(uid).NewDoc()
+	rule.RuleID = ref.ID
+	if _, err := ref.Set(ctx, rule); err != nil {
+		return errs.NewDatabaseError("create", "failed to create alert rule", err)
+	}
+	return nil
+}
+
+func (s *alertStore) ListRules(ctx context.Context, uid string) ([]*models.AlertRule, error) {
+	docs, err := s.rulesCollection(uid).OrderBy("createdAt", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to list alert rules", err)
+	}
+	rules := make([]*models.AlertRule, 0, len(docs))
+	for _, d := range docs {
+		var r models.AlertRule
+		if err := d.DataTo(&r); err != nil {
+			return nil, errs.NewDatabaseError("read", "failed to parse alert rule data", err)
+		}
+		rules = append(rules, &r)
+	}
+	return rules, nil
+}
+
+func (s *alertStore) DeleteRule(ctx context.Context, uid, ruleID string) error {
+	_, err := s.rulesCollection(uid).Doc(ruleID).Delete(ctx, firestore.Exists)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return errs.NewNotFoundError("alert rule not found")
+		}
+		return errs.NewDatabaseError("delete", "failed to delete alert rule", err)
+	}
+	return nil
+}
+
+// CreateAlerts stores alerts that don't exist yet and returns the ones that were new.
+// Alerts already stored under the same id, including acknowledged ones, are left alone.
+func (s *alertStore) CreateAlerts(ctx context.Context, uid string, alerts []models.Alert) ([]models.Alert, error) {
+	if len(alerts) == 0 {
+		return nil, nil
+	}
+
+	bw := s.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(alerts))
+	for _, a := range alerts {
+		job, err := bw.Create(s.alertsCollection(uid).Doc(a.AlertID), a)
+		if err != nil {
+			bw.End()
+			return nil, errs.NewDatabaseError("create", "failed to create alert", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	bw.End()
+	created := make([]models.Alert, 0, len(alerts))
+	for i, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			if status.Code(err) == codes.AlreadyExists {
+				continue
+			}
+			return created, errs.NewDatabaseError("create", "failed to commit alert batch", err)
+		}
+		created = append(created, alerts[i])
+	}
+	return created, nil
+}
+
+// ListAlerts returns the newest alerts first, optionally only those not yet acknowledged.
+func (s *alertStore) ListAlerts(ctx context.Context, uid string, unacknowledgedOnly bool, limit int) ([]*models.Alert, error) {
+	query := s.alertsCollection(uid).Query
+	if unacknowledgedOnly {
+		query = query.Where("acknowledged", "==", false)
+	}
+	query = query.OrderBy("createdAt", firestore.Desc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to list alerts", err)
+	}
+	alerts := make([]*models.Alert, 0, len(docs))
+	for _, d := range docs {
+		var a models.Alert
+		if err := d.DataTo(&a); err != nil {
+			return nil, errs.NewDatabaseError("read", "failed to parse alert data", err)
+		}
+		alerts = append(alerts, &a)
+	}
+	return alerts, nil
+}
+
+func (s *alertStore) Acknowledge(ctx context.Context, uid, alertID string, at time.Time) error {
+	_, err := s.alertsCollection(uid).Doc(alertID).Update(ctx, []firestore.Update{
+		{Path: "acknowledged", Value: true},
+		{Path: "acknowledgedAt", Value: at},
+	})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return errs.NewNotFoundError("alert not found")
+		}
+		return errs.NewDatabaseError("update", "failed to acknowledge alert", err)
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/GregMSThompson/finance-backend/internal/dto"
 	"github.com/GregMSThompson/finance-backend/internal/errs"
 	"github.com/GregMSThompson/finance-backend/internal/models"
 )
@@ -88,6 +89,76 @@ func (s *bankStore) Get(ctx context.Context, uid, bankID string) (*models.Bank,
 	return &b, nil
 }
 
+// FindOwner resolves the uid that owns a Plaid item, along with the bank, for
+// callers (like webhooks) that only know the item id. The access token is cleared
+// rather than decrypted.
+func (s *bankStore) FindOwner(ctx context.Context, bankID string) (dto.UserBank, error) {
+	docs, err := s.client.CollectionGroup("banks").Where("bankId", "==", bankID).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return dto.UserBank{}, errs.NewDatabaseError("read", "failed to find bank owner", err)
+	}
+	if len(docs) == 0 {
+		return dto.UserBank{}, errs.NewNotFoundError("bank not found")
+	}
+	var b models.Bank
+	if err := docs[0].DataTo(&b); err != nil {
+		return dto.UserBank{}, errs.NewDatabaseError("read", "failed to parse bank data", err)
+	}
+	b.PlaidPublicToken = ""
+	return dto.UserBank{UID: docs[0].Ref.Parent.Parent.ID, Bank: &b}, nil
+}
+
+func (s *bankStore) UpdateStatus(ctx context.Context, uid, bankID, bankStatus, errorCode string) error {
+	_, err := s.collection(uid).Doc(bankID).Update(ctx, []firestore.Update{
+		{Path: "status", Value: bankStatus},
+		{Path: "errorCode", Value: errorCode},
+		{Path: "updatedAt", Value: time.Now()},
+	})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return errs.NewNotFoundError("bank not found")
+		}
+		return errs.NewDatabaseError("update", "failed to update bank status", err)
+	}
+	return nil
+}
+
+// ListAll returns every linked bank across all users. Access tokens are
+// cleared rather than decrypted since callers only need to know which banks exist.
+func (s *bankStore) ListAll(ctx context.Context) ([]dto.UserBank, error) {
+	docs, err := s.client.CollectionGroup("banks").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to list banks", err)
+	}
+	banks := make([]dto.UserBank, 0, len(docs))
+	for _, d := range docs {
+		var b models.Bank
+		if err := d.DataTo(&b); err != nil {
+			return nil, errs.NewDatabaseError("read", "failed to parse bank data", err)
+		}
+		b.PlaidPublicToken = ""
+		banks = append(banks, dto.UserBank{UID: d.Ref.Parent.Parent.ID, Bank: &b})
+	}
+	return banks, nil
+}
+
+func (s *bankStore) UpdateSyncState(ctx context.Context, uid, bankID string, state dto.BankSyncState) error {
+	_, err := s.collection(uid).Doc(bankID).Update(ctx, []firestore.Update{
+		{Path: "lastSyncAt", Value: state.LastSyncAt},
+		{Path: "lastSyncError", Value: state.LastSyncError},
+		{Path: "syncFailures", Value: state.SyncFailures},
+		{Path: "nextSyncAt", Value: state.NextSyncAt},
+		{Path: "updatedAt", Value: time.Now()},
+	})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return errs.NewNotFoundError("bank not found")
+		}
+		return errs.NewDatabaseError("update", "failed to update bank sync state", err)
+	}
+	return nil
+}
+
 func (s *bankStore) Delete(ctx context.Context, uid, bankID string) error {
 	_, err := s.collection(uid).Doc(bankID).Delete(ctx)
 	if err != nil {
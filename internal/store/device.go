@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+)
+
+type deviceStore struct {
+	client *firestore.Client
+}
+
+func NewDeviceStore(client *firestore.Client) *deviceStore {
+	return &deviceStore{client: client}
+}
+
+func (s *deviceStore) collection(uid string) *firestore.CollectionRef {
+	return s.client.Collection("users").Doc(uid).Collection("devices")
+}
+
+// deviceDocID keys devices by a hash of the token, which keeps ids a fixed length
+// and makes registering the same token twice an update.
+func deviceDocID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Upsert registers a device, keeping the original creation time when the token is
+// already known.
+func (s *deviceStore) Upsert(ctx context.Context, uid string, device *models.Device) error {
+	now := time.Now()
+	device.UpdatedAt = now
+	ref := s.collection(uid).Doc(deviceDocID(device.Token))
+
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		device.CreatedAt = now
+		snap, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var existing models.Device
+			if err := snap.DataTo(&existing); err != nil {
+				return err
+			}
+			if !existing.CreatedAt.IsZero() {
+				device.CreatedAt = existing.CreatedAt
+			}
+		}
+		return tx.Set(ref, device)
+	})
+	if err != nil {
+		return errs.NewDatabaseError("update", "failed to register device", err)
+	}
+	return nil
+}
+
+func (s *deviceStore) List(ctx context.Context, uid string) ([]*models.Device, error) {
+	docs, err := s.collection(uid).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to list devices", err)
+	}
+	devices := make([]*models.Device, 0, len(docs))
+	for _, d := range docs {
+		var dev models.Device
+		if err := d.DataTo(&dev); err != nil {
+			return nil, errs.NewDatabaseError("read", "failed to parse device data", err)
+		}
+		devices = append(devices, &dev)
+	}
+	return devices, nil
+}
+
+// Delete forgets the given tokens. Unknown tokens are ignored.
+func (s *deviceStore) Delete(ctx context.Context, uid string, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	bw := s.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(tokens))
+	for _, token := range tokens {
+		job, err := bw.Delete(s.collection(uid).Doc(deviceDocID(token)))
+		if err != nil {
+			bw.End()
+			return errs.NewDatabaseError("delete", "failed to delete device", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return errs.NewDatabaseError("delete", "failed to commit device deletion batch", err)
+		}
+	}
+	return nil
+}
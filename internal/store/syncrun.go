@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+
+	"github.com/GregMSThompson/finance-backend/internal/errs"
+	"github.com/GregMSThompson/finance-backend/internal/models"
+)
+
+type syncRunStore struct {
+	client *firestore.Client
+}
+
+func NewSyncRunStore(client *firestore.Client) *syncRunStore {
+	return &syncRunStore{client: client}
+}
+
+func (s *syncRunStore) collection(uid, bankID string) *firestore.CollectionRef {
+	return s.client.Collection("users").Doc(uid).Collection("banks").Doc(bankID).Collection("sync_runs")
+}
+
+func (s *syncRunStore) Create(ctx context.Context, uid string, run *models.SyncRun) error {
+	ref := s.collection(uid, run.BankID).NewDoc()
+	run.RunID = ref.ID
+
+	if _, err := ref.Set(ctx, run); err != nil {
+		return errs.NewDatabaseError("create", "failed to record sync run", err)
+	}
+	return nil
+}
+
+// List returns the most recent runs for a bank, newest first.
+func (s *syncRunStore) List(ctx context.Context, uid, bankID string, limit int) ([]*models.SyncRun, error) {
+	docs, err := s.collection(uid, bankID).OrderBy("startedAt", firestore.Desc).Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to list sync runs", err)
+	}
+	runs := make([]*models.SyncRun, 0, len(docs))
+	for _, d := range docs {
+		var run models.SyncRun
+		if err := d.DataTo(&run); err != nil {
+			return nil, errs.NewDatabaseError("read", "failed to parse sync run data", err)
+		}
+		runs = append(runs, &run)
+	}
+	return runs, nil
+}
+
+// DeleteByBank removes the run history subcollection, which Firestore does not
+// delete along with the parent bank document.
+func (s *syncRunStore) DeleteByBank(ctx context.Context, uid, bankID string) error {
+	iter := s.collection(uid, bankID).Documents(ctx)
+	bw := s.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0)
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bw.End()
+			return errs.NewDatabaseError("delete", "failed to query sync runs for deletion", err)
+		}
+		job, err := bw.Delete(doc.Ref)
+		if err != nil {
+			bw.End()
+			return errs.NewDatabaseError("delete", "failed to delete sync run", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return errs.NewDatabaseError("delete", "failed to commit sync run deletion batch", err)
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"time"
 
@@ -82,17 +83,20 @@ func (s *transactionStore) query(ctx context.Context, uid string, q dto.Transact
 		query = query.Where("date", "<=", *q.DateTo)
 	}
 
-	orderField := q.OrderBy
-	if orderField == "" {
-		orderField = "date"
-	}
+	orderField := transactionOrderField(q.OrderBy)
 	dir := firestore.Asc
 	if q.Desc {
 		dir = firestore.Desc
 	}
-	query = query.OrderBy(orderField, dir)
+	// Ordering by document id as well keeps results stable for pagination.
+	query = query.OrderBy(orderField, dir).OrderBy(firestore.DocumentID, dir)
+	if q.StartAfter != nil {
+		query = query.StartAfter(q.StartAfter.OrderValue, q.StartAfter.TransactionID)
+	}
 
-	if q.Limit > 0 {
+	// The merchant filter is applied client-side, so the limit must be too.
+	merchant := strings.ToLower(helpers.Value(q.Merchant))
+	if q.Limit > 0 && merchant == "" {
 		query = query.Limit(q.Limit)
 	}
 
@@ -102,6 +106,7 @@ func (s *transactionStore) query(ctx context.Context, uid string, q dto.Transact
 		defer close(errCh)
 		defer iter.Stop()
 
+		sent := 0
 		for {
 			doc, err := iter.Next()
 			if err == iterator.Done {
@@ -117,8 +122,9 @@ func (s *transactionStore) query(ctx context.Context, uid string, q dto.Transact
 				errCh <- errs.NewDatabaseError("read", "failed to parse transaction data", err)
 				return
 			}
+			tx.NormalizeAmount()
 
-			if merchant := helpers.Value(q.Merchant); merchant != "" && !strings.Contains(strings.ToLower(tx.Name), strings.ToLower(merchant)) {
+			if merchant != "" && !strings.Contains(strings.ToLower(tx.Name), merchant) {
 				continue
 			}
 
@@ -128,32 +134,79 @@ func (s *transactionStore) query(ctx context.Context, uid string, q dto.Transact
 				errCh <- ctx.Err()
 				return
 			}
+
+			sent++
+			if q.Limit > 0 && sent >= q.Limit {
+				return
+			}
 		}
 	}()
 
 	return out, errCh
 }
 
-func (s *transactionStore) UpsertBatch(ctx context.Context, uid string, txs []models.Transaction) error {
+// transactionOrderField maps an API sort key to its firestore field.
+func transactionOrderField(orderBy string) string {
+	switch orderBy {
+	case "":
+		return "date"
+	case "amount":
+		return "amountMinor"
+	default:
+		return orderBy
+	}
+}
+
+// UpsertBatch writes new and changed transactions and reports how many documents
+// were created, modified, or skipped because they were already up to date.
+func (s *transactionStore) UpsertBatch(ctx context.Context, uid string, txs []models.Transaction) (dto.TransactionUpsertResult, error) {
+	result := dto.TransactionUpsertResult{}
 	if len(txs) == 0 {
-		return nil
+		return result, nil
+	}
+
+	refs := make([]*firestore.DocumentRef, 0, len(txs))
+	for _, t := range txs {
+		refs = append(refs, s.txCollection(uid).Doc(t.TransactionID))
+	}
+	// GetAll returns snapshots in the same order as refs, including missing docs.
+	snaps, err := s.client.GetAll(ctx, refs)
+	if err != nil {
+		return result, errs.NewDatabaseError("read", "failed to read existing transactions", err)
 	}
 
 	bw := s.client.BulkWriter(ctx)
 	jobs := make([]*firestore.BulkWriterJob, 0, len(txs))
 	now := time.Now()
 
-	for _, t := range txs {
+	for i, t := range txs {
 		t.UpdatedAt = now
-		if t.CreatedAt.IsZero() {
-			t.CreatedAt = now
+		t.CreatedAt = now
+
+		if snaps[i].Exists() {
+			var existing models.Transaction
+			if err := snaps[i].DataTo(&existing); err != nil {
+				bw.End()
+				return result, errs.NewDatabaseError("read", "failed to parse transaction data", err)
+			}
+			existing.NormalizeAmount()
+			if !transactionChanged(existing, t) {
+				result.Unchanged++
+				continue
+			}
+			if !existing.CreatedAt.IsZero() {
+				t.CreatedAt = existing.CreatedAt
+			}
+			result.Updated++
+		} else {
+			result.Inserted++
+			result.InsertedIDs = append(result.InsertedIDs, t.TransactionID)
 		}
 
-		doc := s.txCollection(uid).Doc(t.TransactionID)
-		job, err := bw.Set(doc, t)
+		job, err := bw.Set(refs[i], t)
 		if err != nil {
 			bw.End()
-			return errs.NewDatabaseError("create", "failed to upsert transaction", err)
+			return result, errs.NewDatabaseError("create", "failed to upsert transaction", err)
 		}
 		jobs = append(jobs, job)
 	}
@@ -162,32 +215,121 @@ func (s *transactionStore) UpsertBatch(ctx context.Context, uid string, txs []mo
 	bw.End()
 	for _, job := range jobs {
 		if _, err := job.Results(); err != nil {
-			return errs.NewDatabaseError("create", "failed to commit transaction batch", err)
+			return result, errs.NewDatabaseError("create", "failed to commit transaction batch", err)
+		}
+	}
+
+	return result, nil
+}
+
+// transactionChanged compares stored and incoming transactions, ignoring bookkeeping timestamps.
+func transactionChanged(existing, incoming models.Transaction) bool {
+	normalize := func(t models.Transaction) models.Transaction {
+		t.CreatedAt = time.Time{}
+		t.UpdatedAt = time.Time{}
+		if len(t.Categories) == 0 {
+			t.Categories = nil
+		}
+		return t
+	}
+	return !reflect.DeepEqual(normalize(existing), normalize(incoming))
+}
+
+// ListIDsByName returns up to limit ids of transactions whose name is exactly name.
+func (s *transactionStore) ListIDsByName(ctx context.Context, uid, name string, limit int) ([]string, error) {
+	query := s.txCollection(uid).Where("name", "==", name).Select()
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to query transactions by name", err)
+	}
+	ids := make([]string, 0, len(docs))
+	for _, d := range docs {
+		ids = append(ids, d.Ref.ID)
+	}
+	return ids, nil
+}
+
+// ListByName returns up to limit transactions whose name is exactly name, newest
+// first. Only the id, date, amount and pending fields are loaded.
+func (s *transactionStore) ListByName(ctx context.Context, uid, name string, limit int) ([]*models.Transaction, error) {
+	query := s.txCollection(uid).Where("name", "==", name).
+		Select("transactionId", "date", "amountMinor", "amount", "currency", "pending").
+		OrderBy("date", firestore.Desc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errs.NewDatabaseError("read", "failed to query transactions by name", err)
+	}
+	txs := make([]*models.Transaction, 0, len(docs))
+	for _, d := range docs {
+		var tx models.Transaction
+		if err := d.DataTo(&tx); err != nil {
+			return nil, errs.NewDatabaseError("read", "failed to parse transaction data", err)
+		}
+		tx.NormalizeAmount()
+		txs = append(txs, &tx)
+	}
+	return txs, nil
+}
+
+func (s *transactionStore) DeleteBatch(ctx context.Context, uid string, transactionIDs []string) error {
+	if len(transactionIDs) == 0 {
+		return nil
+	}
+
+	bw := s.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(transactionIDs))
+
+	for _, id := range transactionIDs {
+		job, err := bw.Delete(s.txCollection(uid).Doc(id))
+		if err != nil {
+			bw.End()
+			return errs.NewDatabaseError("delete", "failed to delete transaction", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	// Deleting a missing document is a no-op in Firestore, so replays of the same
+	// removed IDs are safe.
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return errs.NewDatabaseError("delete", "failed to commit transaction deletion batch", err)
 		}
 	}
 
 	return nil
 }
 
-func (s *transactionStore) GetCursor(ctx context.Context, uid, bankID string) (string, error) {
+func (s *transactionStore) GetCursor(ctx context.Context, uid, bankID string) (dto.PlaidSyncCursor, error) {
+	out := dto.PlaidSyncCursor{}
 	snap, err := s.cursorDoc(uid, bankID).Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			return "", nil
+			return out, nil
 		}
-		return "", errs.NewDatabaseError("read", "failed to get cursor", err)
+		return out, errs.NewDatabaseError("read", "failed to get cursor", err)
 	}
-	cursor, ok := snap.Data()["cursor"].(string)
-	if !ok {
-		return "", nil
+	data := snap.Data()
+	out.Cursor, _ = data["cursor"].(string)
+	if start, ok := data["paginationStart"].(string); ok {
+		out.PaginationStart = &start
 	}
-	return cursor, nil
+	return out, nil
 }
 
-func (s *transactionStore) SetCursor(ctx context.Context, uid, bankID, cursor string) error {
+// SetCursor stores the bank's cursor. paginationStart is the cursor the current
+// pagination loop began from, or nil once the loop has finished.
+func (s *transactionStore) SetCursor(ctx context.Context, uid, bankID, cursor string, paginationStart *string) error {
 	_, err := s.cursorDoc(uid, bankID).Set(ctx, map[string]interface{}{
-		"cursor":    cursor,
-		"updatedAt": time.Now(),
+		"cursor":          cursor,
+		"paginationStart": paginationStart,
+		"updatedAt":       time.Now(),
 	}, firestore.MergeAll)
 	if err != nil {
 		return errs.NewDatabaseError("update", "failed to set cursor", err)
@@ -234,3 +376,53 @@ func (s *transactionStore) DeleteCursor(ctx context.Context, uid, bankID string)
 	}
 	return nil
 }
+
+// MigrateLegacyAmounts rewrites transactions that still hold a float "amount" so they
+// store "amountMinor" instead, returning how many documents changed. Running it again
+// is a no-op.
+func (s *transactionStore) MigrateLegacyAmounts(ctx context.Context) (int, error) {
+	iter := s.client.CollectionGroup("transactions").Select("amount", "amountMinor", "currency").Documents(ctx)
+	defer iter.Stop()
+	bw := s.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0)
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bw.End()
+			return 0, errs.NewDatabaseError("read", "failed to query transactions for migration", err)
+		}
+
+		var tx models.Transaction
+		if err := doc.DataTo(&tx); err != nil {
+			bw.End()
+			return 0, errs.NewDatabaseError("read", "failed to parse transaction data", err)
+		}
+		if tx.LegacyAmount == nil {
+			continue
+		}
+		tx.NormalizeAmount()
+
+		job, err := bw.Update(doc.Ref, []firestore.Update{
+			{Path: "amountMinor", Value: tx.AmountMinor},
+			{Path: "amount", Value: firestore.Delete},
+		})
+		if err != nil {
+			bw.End()
+			return 0, errs.NewDatabaseError("update", "failed to migrate transaction amount", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return 0, errs.NewDatabaseError("update", "failed to commit transaction migration batch", err)
+		}
+	}
+
+	return len(jobs), nil
+}
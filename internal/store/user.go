@@ -2,8 +2,10 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -34,9 +36,22 @@ func (us *userStore) CreateUser(ctx context.Context, user *models.User) error {
 	return nil
 }
 
+// UpdateUser writes the editable profile fields of an existing user. Preferences are
+// replaced rather than merged so removed keys don't linger.
 func (us *userStore) UpdateUser(ctx context.Context, user *models.User) error {
-	_, err := us.Collection.Doc(user.UID).Set(ctx, user, firestore.MergeAll)
+	user.UpdatedAt = time.Now()
+	_, err := us.Collection.Doc(user.UID).Update(ctx, []firestore.Update{
+		{Path: "firstName", Value: user.FirstName},
+		{Path: "lastName", Value: user.LastName},
+		{Path: "timezone", Value: user.Timezone},
+		{Path: "baseCurrency", Value: user.BaseCurrency},
+		{Path: "preferences", Value: user.Preferences},
+		{Path: "updatedAt", Value: user.UpdatedAt},
+	})
 	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return errs.NewNotFoundError("user not found")
+		}
 		return errs.NewDatabaseError("update", "failed to update user", err)
 	}
 	return nil
@@ -58,3 +73,55 @@ func (us *userStore) GetUser(ctx context.Context, uid string) (*models.User, err
 
 	return &user, nil
 }
+
+// DeleteUser removes the user document and everything stored beneath it. Firestore
+// doesn't cascade deletes, so every subcollection is walked, including ones under
+// documents that only exist as parents (e.g. AI sessions holding messages).
+func (us *userStore) DeleteUser(ctx context.Context, uid string) error {
+	bw := us.Client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0)
+
+	if err := us.deleteTree(ctx, bw, us.Collection.Doc(uid), &jobs); err != nil {
+		bw.End()
+		return err
+	}
+
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return errs.NewDatabaseError("delete", "failed to commit user deletion batch", err)
+		}
+	}
+	return nil
+}
+
+// deleteTree queues deletes for ref and every document in its subcollections.
+func (us *userStore) deleteTree(ctx context.Context, bw *firestore.BulkWriter, ref *firestore.DocumentRef, jobs *[]*firestore.BulkWriterJob) error {
+	cols := ref.Collections(ctx)
+	for {
+		col, err := cols.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return errs.NewDatabaseError("delete", "failed to list user collections", err)
+		}
+		// DocumentRefs includes missing documents that only have subcollections.
+		docs, err := col.DocumentRefs(ctx).GetAll()
+		if err != nil {
+			return errs.NewDatabaseError("delete", "failed to list user documents", err)
+		}
+		for _, doc := range docs {
+			if err := us.deleteTree(ctx, bw, doc, jobs); err != nil {
+				return err
+			}
+		}
+	}
+
+	job, err := bw.Delete(ref)
+	if err != nil {
+		return errs.NewDatabaseError("delete", "failed to delete user document", err)
+	}
+	*jobs = append(*jobs, job)
+	return nil
+}